@@ -0,0 +1,99 @@
+// Command etilangmock menjalankan API e-tilang tiruan untuk development dan pengujian
+// provider http, dengan data dari provider dummy.
+//
+// Penggunaan:
+//
+//	go run ./cmd/etilangmock                          # http://localhost:8090, tanpa auth
+//	go run ./cmd/etilangmock -addr :9000 -key rahasia # wajib "Authorization: Bearer rahasia"
+//	go run ./cmd/etilangmock -key rahasia -header X-API-Key
+//
+// Lalu jalankan backend dengan:
+//
+//	ETILANG_PROVIDER=http ETILANG_API_URL=http://localhost:8090 ETILANG_API_KEY=rahasia
+//
+// Endpoint:
+//
+//	GET /vehicles/{plate}  200 data kendaraan, 404 nomor polisi tidak terdaftar
+//	GET /health
+//
+// Nomor polisi dengan huruf belakang ERR (misal B 500 ERR) selalu membalas 500 untuk
+// menguji retry dan circuit breaker.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"police-assistant-backend/services"
+	"sort"
+	"strings"
+)
+
+// Huruf belakang plat yang selalu dibalas 500
+const failSuffix = "ERR"
+
+func main() {
+	addr := flag.String("addr", ":8090", "listen address")
+	key := flag.String("key", "", "required API key (empty = no auth)")
+	header := flag.String("header", "Authorization", "header carrying the API key; Authorization expects a Bearer token")
+	flag.Parse()
+
+	provider := services.NewDummyETilangProvider()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("GET /vehicles/{plate}", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, *header, *key) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid API key"})
+			return
+		}
+
+		plate, ok := services.ParsePlateNumber(r.PathValue("plate"))
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid plate number"})
+			return
+		}
+		if plate.Suffix == failSuffix {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "simulated failure"})
+			return
+		}
+
+		info, err := provider.Lookup(r.Context(), plate.Normalized())
+		if errors.Is(err, services.ErrPlateNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "vehicle not found"})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, info)
+	})
+
+	plates := provider.Plates()
+	sort.Strings(plates)
+	log.Printf("🚓 E-Tilang mock API listening on %s (auth: %v)", *addr, *key != "")
+	log.Printf("📋 Known plates: %s", strings.Join(plates, ", "))
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+func authorized(r *http.Request, header string, key string) bool {
+	if key == "" {
+		return true
+	}
+	value := r.Header.Get(header)
+	if strings.EqualFold(header, "Authorization") {
+		value = strings.TrimPrefix(value, "Bearer ")
+	}
+	return value == key
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,117 @@
+// Command flowlint memvalidasi file definisi flow dan mengekspor graph-nya.
+//
+// Penggunaan:
+//
+//	go run ./cmd/flowlint                           # validasi semua flow di ./flows
+//	go run ./cmd/flowlint -strict flows/x.json      # warning juga dianggap gagal
+//	go run ./cmd/flowlint -format dot flows/x.json  # export Graphviz DOT
+//	go run ./cmd/flowlint -format mermaid flows     # export Mermaid flowchart
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"police-assistant-backend/services"
+	"sort"
+)
+
+func main() {
+	format := flag.String("format", "text", "output format: text, dot, mermaid")
+	strict := flag.Bool("strict", false, "exit with failure on warnings too")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: flowlint [-format text|dot|mermaid] [-strict] [file.json|dir ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *format != "text" && *format != "dot" && *format != "mermaid" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	targets := flag.Args()
+	if len(targets) == 0 {
+		targets = []string{"flows"}
+	}
+
+	paths, err := collectFlowFiles(targets)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "flowlint: %v\n", err)
+		os.Exit(2)
+	}
+	if len(paths) == 0 {
+		fmt.Fprintf(os.Stderr, "flowlint: no flow files found in %v\n", targets)
+		os.Exit(2)
+	}
+
+	failed := false
+	errorCount, warningCount := 0, 0
+
+	for _, path := range paths {
+		flow, err := services.LoadFlowFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: error: %v\n", path, err)
+			failed = true
+			errorCount++
+			continue
+		}
+
+		switch *format {
+		case "dot":
+			fmt.Print(services.ExportDOT(flow))
+			continue
+		case "mermaid":
+			fmt.Print(services.ExportMermaid(flow))
+			continue
+		}
+
+		issues := services.LintFlow(flow)
+		for _, issue := range issues {
+			fmt.Printf("%s: %s\n", path, issue)
+			if issue.Severity == services.LintError {
+				errorCount++
+				failed = true
+			} else {
+				warningCount++
+				if *strict {
+					failed = true
+				}
+			}
+		}
+		if len(issues) == 0 {
+			fmt.Printf("%s: ok (%s, %d nodes)\n", path, flow.FlowID, len(flow.Nodes))
+		}
+	}
+
+	if *format == "text" {
+		fmt.Printf("\n%d file(s), %d error(s), %d warning(s)\n", len(paths), errorCount, warningCount)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// collectFlowFiles mengumpulkan file *.json dari argumen file atau direktori
+func collectFlowFiles(targets []string) ([]string, error) {
+	var paths []string
+	for _, target := range targets {
+		info, err := os.Stat(target)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			paths = append(paths, target)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(target, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
@@ -0,0 +1,110 @@
+// Command paysim mensimulasikan payment provider: mengambil tagihan lalu mengirim callback
+// bertanda tangan ke webhook backend, seolah-olah virtual account sudah dibayar.
+//
+// Tagihan dibaca lewat endpoint session pembuatnya, jadi -session dan -token (atau -admin-key) wajib.
+//
+// Penggunaan:
+//
+//	PAYMENT_WEBHOOK_SECRET=rahasia go run ./cmd/paysim -session SID -token TOKEN PAY-1A2B3C4D5E6F7A8B
+//	go run ./cmd/paysim -secret rahasia -session SID -admin-key KEY -status expired PAY-1A2B3C4D5E6F7A8B
+//	go run ./cmd/paysim -secret rahasia -session SID -token TOKEN -amount 1000 PAY-...   # nominal salah, harus ditolak
+//	go run ./cmd/paysim -secret salah -session SID -token TOKEN PAY-...                  # tanda tangan salah, harus 401
+//
+// Exit code 1 jika backend menolak callback.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"police-assistant-backend/handlers"
+	"police-assistant-backend/models"
+	"police-assistant-backend/services"
+	"strings"
+	"time"
+)
+
+func main() {
+	base := flag.String("base", "http://localhost:8080", "backend base URL")
+	secret := flag.String("secret", os.Getenv("PAYMENT_WEBHOOK_SECRET"), "webhook secret (default $PAYMENT_WEBHOOK_SECRET)")
+	status := flag.String("status", services.PaymentStatusPaid, "callback status: paid, expired or failed")
+	amount := flag.Int("amount", -1, "paid amount (default: the payment amount)")
+	sessionID := flag.String("session", "", "session that created the payment")
+	token := flag.String("token", os.Getenv("SESSION_TOKEN"), "session token (default $SESSION_TOKEN)")
+	adminKey := flag.String("admin-key", os.Getenv("ADMIN_API_KEY"), "admin key instead of the session token (default $ADMIN_API_KEY)")
+	flag.Parse()
+
+	if flag.NArg() != 1 || *secret == "" || *sessionID == "" || (*token == "" && *adminKey == "") {
+		fmt.Fprintln(os.Stderr, "usage: paysim [-base url] [-secret s] -session id (-token t | -admin-key k) [-status paid|expired|failed] [-amount n] <payment_id>")
+		os.Exit(2)
+	}
+	baseURL := strings.TrimRight(*base, "/")
+
+	// Reading the payment needs the same access as the chat client that created it
+	auth := map[string]string{handlers.HeaderSessionToken: *token, handlers.HeaderAdminKey: *adminKey}
+	var current models.PaymentResponse
+	if code := call(http.MethodGet, baseURL+"/api/v1/session/"+*sessionID+"/payments/"+flag.Arg(0), nil, auth, &current); code != http.StatusOK {
+		log.Fatalf("❌ GET payment: %d %s", code, current.Error)
+	}
+	payment := current.Payment
+	log.Printf("💳 %s: %s Rp %d, VA %s %s", payment.ID, payment.Status, payment.Amount, payment.Bank, payment.VirtualAccount)
+
+	callback := models.PaymentCallback{
+		PaymentID:      payment.ID,
+		VirtualAccount: payment.VirtualAccount,
+		Status:         *status,
+		Amount:         payment.Amount,
+		Reference:      fmt.Sprintf("SIM-%d", time.Now().Unix()),
+	}
+	if *amount >= 0 {
+		callback.Amount = *amount
+	}
+	if callback.Status == services.PaymentStatusPaid {
+		callback.PaidAt = time.Now()
+	}
+	body, err := json.Marshal(callback)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var result models.PaymentResponse
+	code := call(http.MethodPost, baseURL+"/api/v1/payments/webhook", body, map[string]string{services.PaymentSignatureHeader: services.SignPaymentCallback(*secret, body)}, &result)
+	if code != http.StatusOK {
+		log.Printf("❌ Callback rejected: %d %s", code, result.Error)
+		os.Exit(1)
+	}
+	log.Printf("✅ Callback accepted: %s is now %s", result.Payment.ID, result.Payment.Status)
+}
+
+func call(method string, url string, body []byte, headers map[string]string, out *models.PaymentResponse) int {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		if value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Fatalf("❌ %s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		log.Fatalf("❌ %s %s: %d %s", method, url, resp.StatusCode, data)
+	}
+	return resp.StatusCode
+}
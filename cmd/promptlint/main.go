@@ -0,0 +1,98 @@
+// Command promptlint merender setiap template prompt terhadap setiap fixture data.
+//
+// Penggunaan:
+//
+//	go run ./cmd/promptlint                                 # prompts/ dengan fixture prompts/fixtures/*.json
+//	go run ./cmd/promptlint -fixtures path/to/fixtures prompts
+//	go run ./cmd/promptlint -print system.tmpl prompts      # tampilkan hasil render template
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"police-assistant-backend/services"
+	"sort"
+)
+
+func main() {
+	fixturesDir := flag.String("fixtures", "", "fixture directory (default: <prompts dir>/fixtures)")
+	printName := flag.String("print", "", "print the rendered output of this template for every fixture")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: promptlint [-fixtures dir] [-print name.tmpl] [prompts dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dir := "prompts"
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	if *fixturesDir == "" {
+		*fixturesDir = filepath.Join(dir, services.PromptFixturesDir)
+	}
+
+	prompts, err := services.LoadPromptSet(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: error: %v\n", dir, err)
+		os.Exit(1)
+	}
+
+	fixtures, err := filepath.Glob(filepath.Join(*fixturesDir, "*.json"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "promptlint: %v\n", err)
+		os.Exit(2)
+	}
+	if len(fixtures) == 0 {
+		fmt.Fprintf(os.Stderr, "promptlint: no fixtures found in %s\n", *fixturesDir)
+		os.Exit(2)
+	}
+	sort.Strings(fixtures)
+
+	names := prompts.Names()
+	failed := false
+	errorCount := 0
+
+	for _, path := range fixtures {
+		data, err := services.LoadPromptFixture(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: error: %v\n", path, err)
+			failed = true
+			errorCount++
+			continue
+		}
+
+		fixtureOK := true
+		for _, name := range names {
+			output, err := prompts.Render(name, data)
+			if err == nil {
+				err = services.CheckPromptOutput(name, output)
+			}
+			if err != nil {
+				fmt.Printf("%s: %s: error: %v\n", path, name, err)
+				fixtureOK = false
+				errorCount++
+				continue
+			}
+			if name == *printName {
+				fmt.Printf("===== %s (%s) =====\n%s\n", name, filepath.Base(path), output)
+			}
+		}
+
+		if fixtureOK {
+			fmt.Printf("%s: ok (%d templates)\n", path, len(names))
+		} else {
+			failed = true
+		}
+	}
+
+	fmt.Printf("\n%s, %d fixture(s), %d error(s)\n", prompts.Version, len(fixtures), errorCount)
+	if failed {
+		os.Exit(1)
+	}
+}
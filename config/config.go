@@ -3,6 +3,10 @@ package config
 import (
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,7 +15,59 @@ type Config struct {
 	Port         string
 	OpenAIAPIKey string
 	ORSAPIKey    string // OpenRouteService API Key
-	OpenAIModel  string
+	OpenAIModel  string // Nama model, juga dipakai untuk provider openai_compatible
+
+	LLMProvider string // openai (default), openai_compatible, atau stub
+	LLMBaseURL  string // Base URL server OpenAI-compatible (Ollama, vLLM, Azure OpenAI)
+	LLMAPIKey   string // API key untuk server OpenAI-compatible (opsional, misal Ollama)
+	LLMStubFile string // File balasan skrip untuk provider stub
+	LLMTools    bool   // Tool calling (e-tilang, pelayanan, rute, lalu lintas); false = routing kata kunci
+
+	ETilangProvider   string // dummy (default) atau http
+	ETilangAPIURL     string // Base URL API e-tilang/ETLE untuk provider http
+	ETilangAPIKey     string // API key e-tilang (opsional)
+	ETilangAuthHeader string // Header tempat API key dikirim; Authorization = Bearer token
+
+	LLMTimeout             time.Duration // Timeout per percobaan panggilan LLM (termasuk seluruh stream)
+	LLMMaxRetries          int           // Retry untuk 429/5xx/timeout LLM
+	ORSTimeout             time.Duration // Timeout per percobaan panggilan OpenRouteService
+	ORSMaxRetries          int           // Retry untuk 429/5xx/timeout OpenRouteService
+	ETilangTimeout         time.Duration // Timeout per percobaan panggilan API e-tilang
+	ETilangMaxRetries      int           // Retry untuk 429/5xx/timeout API e-tilang
+	CircuitBreakerFailures int           // Kegagalan berturut-turut sebelum circuit breaker terbuka
+	CircuitBreakerCooldown time.Duration // Lama circuit terbuka sebelum panggilan percobaan
+
+	PaymentProvider      string        // fake; kosong = endpoint bayar denda tidak didaftarkan
+	PaymentDBPath        string        // File database tagihan (BoltDB)
+	PaymentWebhookSecret string        // Secret HMAC callback pembayaran; kosong = webhook ditolak
+	PaymentExpiry        time.Duration // Masa berlaku virtual account
+	PaymentVACompanyCode string        // Kode perusahaan BRIVA untuk provider fake
+
+	StorageDir     string        // Direktori penyimpanan file upload & berkas hasil generate
+	AuditLogPath   string        // File audit log akses data pribadi (JSON Lines)
+	PublicBaseURL  string        // Base URL publik untuk link download file
+	FileURLSecret  string        // Secret HMAC link download file; kosong = dibuat acak saat start
+	FileURLExpiry  time.Duration // Masa berlaku link download file
+	ProxyHeader    string        // Header IP client dari reverse proxy (misal X-Real-IP); kosong = IP koneksi
+	TrustedProxies []string      // IP/CIDR reverse proxy yang boleh mengisi ProxyHeader; kosong = header diabaikan
+	MaxUploadSize  int64         // Batas ukuran file upload (bytes)
+	UploadURLHosts []string      // Host yang boleh dipakai untuk upload lewat url; kosong = upload url dinonaktifkan
+	FlowsDir       string        // Direktori file definisi flow (*.json)
+	FlowReplyMode  string        // deterministic (default) atau llm
+
+	PromptsDir       string // Direktori template system prompt (*.tmpl)
+	PromptsHotReload bool   // Muat ulang template saat file berubah (untuk development)
+
+	ChoiceMinConfidence float64 // Ambang confidence classifier pilihan flow (0.0 - 1.0)
+
+	SessionBackend string // memory (default) atau bolt; keduanya hanya untuk satu replica
+	SessionDBPath  string // Lokasi file database session untuk backend bolt
+
+	SessionTurnTimeout time.Duration // Batas tunggu giliran jika session masih memproses pesan lain
+
+	HistoryTokenBudget int // Budget token history per request (0 = otomatis dari context window model)
+
+	AdminAPIKey string // Key untuk akses session hanya dengan ID (header X-Admin-Key); kosong = nonaktif
 }
 
 var AppConfig *Config
@@ -30,16 +86,135 @@ func LoadConfig() {
 		OpenAIModel:  getEnv("OPENAI_MODEL", "gpt-5.1"),
 	}
 
-	// Validate required keys
-	if AppConfig.OpenAIAPIKey == "" {
-		log.Fatal("❌ OPENAI_API_KEY is required in .env file")
+	AppConfig.LLMProvider = strings.ToLower(getEnv("LLM_PROVIDER", "openai"))
+	AppConfig.LLMBaseURL = getEnv("LLM_BASE_URL", "")
+	AppConfig.LLMAPIKey = getEnv("LLM_API_KEY", "")
+	AppConfig.LLMStubFile = getEnv("LLM_STUB_FILE", "llm-stub.json")
+	// A misconfigured production server must not silently answer with scripted replies;
+	// the stub is only used when chosen explicitly
+	switch AppConfig.LLMProvider {
+	case "openai":
+		if AppConfig.OpenAIAPIKey == "" {
+			log.Fatal("❌ OPENAI_API_KEY is required for LLM_PROVIDER=openai (set LLM_PROVIDER=stub for offline development)")
+		}
+	case "openai_compatible":
+		if AppConfig.LLMBaseURL == "" {
+			log.Fatal("❌ LLM_BASE_URL is required for LLM_PROVIDER=openai_compatible")
+		}
+	case "stub":
+		log.Println("⚠️  LLM_PROVIDER=stub, replies are scripted and no LLM is called")
+	default:
+		log.Fatalf("❌ Invalid LLM_PROVIDER %q (use openai, openai_compatible, or stub)", AppConfig.LLMProvider)
+	}
+
+	AppConfig.ETilangProvider = strings.ToLower(getEnv("ETILANG_PROVIDER", "dummy"))
+	AppConfig.ETilangAPIURL = getEnv("ETILANG_API_URL", "")
+	AppConfig.ETilangAPIKey = getEnv("ETILANG_API_KEY", "")
+	AppConfig.ETilangAuthHeader = getEnv("ETILANG_AUTH_HEADER", "Authorization")
+	switch AppConfig.ETilangProvider {
+	case "http":
+		if AppConfig.ETilangAPIURL == "" {
+			log.Println("⚠️  ETILANG_API_URL is required for http, using dummy e-tilang data")
+			AppConfig.ETilangProvider = "dummy"
+		}
+	case "dummy":
+	default:
+		log.Printf("⚠️  Invalid ETILANG_PROVIDER %q, using dummy", AppConfig.ETilangProvider)
+		AppConfig.ETilangProvider = "dummy"
+	}
+
+	llmTools, err := strconv.ParseBool(getEnv("LLM_TOOLS", "true"))
+	if err != nil {
+		log.Printf("⚠️  Invalid LLM_TOOLS, using default true")
+		llmTools = true
+	}
+	// The stub never calls tools, so data lookups fall back to keyword routing
+	AppConfig.LLMTools = llmTools && AppConfig.LLMProvider != "stub"
+
+	AppConfig.LLMTimeout = time.Duration(getEnvInt("LLM_TIMEOUT_SECONDS", 60, 1)) * time.Second
+	AppConfig.LLMMaxRetries = getEnvInt("LLM_MAX_RETRIES", 2, 0)
+	AppConfig.ORSTimeout = time.Duration(getEnvInt("ORS_TIMEOUT_SECONDS", 10, 1)) * time.Second
+	AppConfig.ORSMaxRetries = getEnvInt("ORS_MAX_RETRIES", 2, 0)
+	AppConfig.ETilangTimeout = time.Duration(getEnvInt("ETILANG_TIMEOUT_SECONDS", 10, 1)) * time.Second
+	AppConfig.ETilangMaxRetries = getEnvInt("ETILANG_MAX_RETRIES", 2, 0)
+	AppConfig.CircuitBreakerFailures = getEnvInt("CIRCUIT_BREAKER_FAILURES", 5, 1)
+	AppConfig.CircuitBreakerCooldown = time.Duration(getEnvInt("CIRCUIT_BREAKER_COOLDOWN_SECONDS", 30, 1)) * time.Second
+
+	AppConfig.StorageDir = getEnv("STORAGE_DIR", "storage")
+	AppConfig.AuditLogPath = getEnv("AUDIT_LOG_PATH", filepath.Join(AppConfig.StorageDir, "audit.jsonl"))
+	AppConfig.PaymentProvider = strings.ToLower(getEnv("PAYMENT_PROVIDER", ""))
+	switch AppConfig.PaymentProvider {
+	case "":
+		log.Println("⚠️  PAYMENT_PROVIDER is not set, payment endpoints are disabled")
+	case "fake":
+		log.Println("⚠️  PAYMENT_PROVIDER=fake: virtual accounts are simulated, do not use in production")
+	default:
+		log.Fatalf("❌ Invalid PAYMENT_PROVIDER %q (supported: fake)", AppConfig.PaymentProvider)
+	}
+	AppConfig.PaymentDBPath = getEnv("PAYMENT_DB_PATH", filepath.Join(AppConfig.StorageDir, "payments.db"))
+	AppConfig.PaymentWebhookSecret = getEnv("PAYMENT_WEBHOOK_SECRET", "")
+	AppConfig.PaymentExpiry = time.Duration(getEnvInt("PAYMENT_EXPIRY_HOURS", 24, 1)) * time.Hour
+	AppConfig.PaymentVACompanyCode = getEnv("PAYMENT_VA_COMPANY_CODE", "77777")
+	AppConfig.FlowsDir = getEnv("FLOWS_DIR", "flows")
+	AppConfig.FlowReplyMode = strings.ToLower(getEnv("FLOW_REPLY_MODE", "deterministic"))
+	if AppConfig.FlowReplyMode != "deterministic" && AppConfig.FlowReplyMode != "llm" {
+		log.Printf("⚠️  Invalid FLOW_REPLY_MODE %q, using deterministic", AppConfig.FlowReplyMode)
+		AppConfig.FlowReplyMode = "deterministic"
+	}
+	AppConfig.PromptsDir = getEnv("PROMPTS_DIR", "prompts")
+	promptsHotReload, err := strconv.ParseBool(getEnv("PROMPTS_HOT_RELOAD", "false"))
+	if err != nil {
+		log.Printf("⚠️  Invalid PROMPTS_HOT_RELOAD, using default false")
+	}
+	AppConfig.PromptsHotReload = promptsHotReload
+	AppConfig.PublicBaseURL = strings.TrimRight(getEnv("PUBLIC_BASE_URL", "http://localhost:"+AppConfig.Port), "/")
+	AppConfig.ProxyHeader = getEnv("PROXY_HEADER", "")
+	for _, proxy := range strings.Split(getEnv("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			AppConfig.TrustedProxies = append(AppConfig.TrustedProxies, proxy)
+		}
+	}
+	if AppConfig.ProxyHeader != "" && len(AppConfig.TrustedProxies) == 0 {
+		log.Println("⚠️  PROXY_HEADER is set but TRUSTED_PROXIES is empty, the header is ignored and the connection IP is used")
+	}
+	AppConfig.FileURLSecret = getEnv("FILE_URL_SECRET", "")
+	if AppConfig.FileURLSecret == "" {
+		log.Println("⚠️  FILE_URL_SECRET is not set, download links stop working after a restart")
+	}
+	AppConfig.FileURLExpiry = time.Duration(getEnvInt("FILE_URL_EXPIRY_HOURS", 24, 1)) * time.Hour
+
+	AppConfig.SessionBackend = strings.ToLower(getEnv("SESSION_BACKEND", "memory"))
+	if AppConfig.SessionBackend != "memory" && AppConfig.SessionBackend != "bolt" {
+		log.Printf("⚠️  Invalid SESSION_BACKEND %q, using memory", AppConfig.SessionBackend)
+		AppConfig.SessionBackend = "memory"
 	}
+	AppConfig.AdminAPIKey = getEnv("ADMIN_API_KEY", "")
+	AppConfig.SessionDBPath = getEnv("SESSION_DB_PATH", filepath.Join(AppConfig.StorageDir, "sessions.db"))
+
+	AppConfig.SessionTurnTimeout = time.Duration(getEnvInt("SESSION_TURN_TIMEOUT_SECONDS", 20, 1)) * time.Second
+	AppConfig.HistoryTokenBudget = getEnvInt("HISTORY_TOKEN_BUDGET", 0, 0) // 0 = automatic budget
+
+	minConfidence, err := strconv.ParseFloat(getEnv("CHOICE_MIN_CONFIDENCE", "0.6"), 64)
+	if err != nil || minConfidence < 0 || minConfidence > 1 {
+		log.Printf("⚠️  Invalid CHOICE_MIN_CONFIDENCE, using default 0.6")
+		minConfidence = 0.6
+	}
+	AppConfig.ChoiceMinConfidence = minConfidence
+
+	AppConfig.MaxUploadSize = int64(getEnvInt("MAX_UPLOAD_SIZE_MB", 5, 1)) * 1024 * 1024
+	for _, host := range strings.Split(getEnv("UPLOAD_URL_HOSTS", ""), ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			AppConfig.UploadURLHosts = append(AppConfig.UploadURLHosts, host)
+		}
+	}
+
+	// Routes and traffic need OpenRouteService; everything else still works without it
 	if AppConfig.ORSAPIKey == "" {
-		log.Fatal("❌ OPENROUTESERVICE_API_KEY is required in .env file")
+		log.Println("⚠️  OPENROUTESERVICE_API_KEY is not set, route and traffic requests will fail")
 	}
 
 	log.Println("✅ Configuration loaded successfully")
-	log.Printf("📝 Using OpenAI Model: %s", AppConfig.OpenAIModel)
+	log.Printf("📝 Using LLM provider: %s", AppConfig.LLMProvider)
 	log.Println("🗺️  Using OpenRouteService (Free Maps API)")
 }
 
@@ -49,3 +224,13 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvInt membaca angka bulat >= minValue; nilai tidak valid diganti default
+func getEnvInt(key string, defaultValue int, minValue int) int {
+	value, err := strconv.Atoi(getEnv(key, strconv.Itoa(defaultValue)))
+	if err != nil || value < minValue {
+		log.Printf("⚠️  Invalid %s, using default %d", key, defaultValue)
+		return defaultValue
+	}
+	return value
+}
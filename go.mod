@@ -7,7 +7,9 @@ toolchain go1.24.11
 require (
 	github.com/go-resty/resty/v2 v2.17.0
 	github.com/google/uuid v1.6.0
+	github.com/jung-kurt/gofpdf v1.16.2
 	github.com/openai/openai-go v1.12.0
+	go.etcd.io/bbolt v1.3.11
 )
 
 require (
@@ -1,47 +1,125 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"log"
+	"police-assistant-backend/config"
 	"police-assistant-backend/models"
 	"police-assistant-backend/services"
+	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// Batas nomor polisi yang dicek per pesan pada keyword routing
+const maxPlateLookups = 3
+
 type ChatHandler struct {
 	openaiService    *services.OpenAIService
 	orsService       *services.ORSService
 	etilangService   *services.ETilangService
 	pelayananService *services.PelayananService
-	simFlowService   *services.SIMFlowService
+	flowService      *services.FlowService
+	historyService   *services.HistoryService
 }
 
-func NewChatHandler(openaiService *services.OpenAIService, orsService *services.ORSService, etilangService *services.ETilangService, pelayananService *services.PelayananService, simFlowService *services.SIMFlowService) *ChatHandler {
+func NewChatHandler(openaiService *services.OpenAIService, orsService *services.ORSService, etilangService *services.ETilangService, pelayananService *services.PelayananService, flowService *services.FlowService, historyService *services.HistoryService) *ChatHandler {
 	return &ChatHandler{
 		openaiService:    openaiService,
 		orsService:       orsService,
 		etilangService:   etilangService,
 		pelayananService: pelayananService,
-		simFlowService:   simFlowService,
+		flowService:      flowService,
+		historyService:   historyService,
 	}
 }
 
+// chatTurn adalah satu giliran chat yang sudah lolos validasi, otorisasi, turn lock, dan flow engine
+type chatTurn struct {
+	req             models.ChatRequest
+	newSessionToken string              // Hanya terisi jika session dibuat pada giliran ini
+	release         func()              // Melepas turn lock session; wajib dipanggil setelah balasan disimpan
+	deterministic   bool                // Balasan dirender dari node flow tanpa LLM
+	replyMeta       *models.MessageMeta // Metadata balasan untuk history/transcript
+}
+
 func (h *ChatHandler) HandleChat(c *fiber.Ctx) error {
+	turn, err := h.beginTurn(c)
+	if turn == nil {
+		return err
+	}
+	defer turn.release()
+
+	var response string
+	if turn.deterministic {
+		// The flow engine resolved this turn: reply with the node text itself, no LLM call
+		response = h.flowService.RenderReply(turn.req.Context.FlowInfo)
+		log.Printf("🧩 Flow reply rendered without LLM (node: %s)", turn.req.Context.FlowInfo.CurrentNode)
+	} else {
+		history := h.prepareReply(c.UserContext(), &turn.req)
+		response, err = h.openaiService.Chat(c.UserContext(), turn.req.Message, &turn.req.Context, history)
+		if errors.Is(err, services.ErrUpstreamUnavailable) {
+			// Fail fast with a friendly reply; the turn is not stored so the user can simply resend it
+			log.Printf("🔴 LLM unavailable, sending fallback reply: %v", err)
+			setUpstreamRetryAfter(c)
+			return c.Status(fiber.StatusServiceUnavailable).JSON(models.ChatResponse{
+				Success:      false,
+				Response:     services.LLMFallbackReply,
+				SessionID:    turn.req.SessionID,
+				SessionToken: turn.newSessionToken,
+				Error:        "AI service temporarily unavailable: " + err.Error(),
+			})
+		}
+		if err != nil {
+			log.Printf("❌ OpenAI error: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(models.ChatResponse{
+				Success:      false,
+				SessionID:    turn.req.SessionID,
+				SessionToken: turn.newSessionToken,
+				Error:        "Failed to get AI response: " + err.Error(),
+			})
+		}
+	}
+
+	h.finishTurn(turn, response)
+
+	req := &turn.req
+	return c.JSON(models.ChatResponse{
+		Success:         true,
+		Response:        response,
+		SessionID:       req.SessionID, // Return session ID ke frontend
+		SessionToken:    turn.newSessionToken,
+		ETilangInfo:     req.Context.ETilangInfo,
+		PelayananInfo:   req.Context.PelayananInfo,
+		FlowInfo:        req.Context.FlowInfo,
+		UploadRejection: req.Context.UploadRejection,
+		Routes:          req.Context.Routes,
+		TrafficInfo:     req.Context.TrafficInfo,
+		Regulations:     req.Context.Regulations,
+		PromptVersion:   req.Context.PromptVersion,
+	})
+}
+
+// beginTurn memvalidasi request, mengambil turn lock session, memperkaya context
+// (nama, serta pelayanan dan e-tilang jika tool calling nonaktif), dan menjalankan flow engine. Jika turn nil, response
+// error sudah dikirim dan error yang dikembalikan adalah hasil pengirimannya.
+func (h *ChatHandler) beginTurn(c *fiber.Ctx) (*chatTurn, error) {
 	var req models.ChatRequest
 
 	// Parse request body
 	if err := c.BodyParser(&req); err != nil {
 		log.Printf("❌ Failed to parse request: %v", err)
-		return c.Status(fiber.StatusBadRequest).JSON(models.ChatResponse{
+		return nil, c.Status(fiber.StatusBadRequest).JSON(models.ChatResponse{
 			Success: false,
 			Error:   "Invalid request body",
 		})
 	}
 
-	// Validate message
-	if req.Message == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(models.ChatResponse{
+	// Validate message (a flow button press may send only choice_id)
+	if req.Message == "" && req.ChoiceID == "" {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(models.ChatResponse{
 			Success: false,
 			Error:   "Message is required",
 		})
@@ -50,19 +128,54 @@ func (h *ChatHandler) HandleChat(c *fiber.Ctx) error {
 	// Get session store
 	sessionStore := services.GetSessionStore()
 
-	// Jika tidak ada session_id, buat session baru
+	// Jika tidak ada session_id, buat session baru; session yang sudah ada hanya bisa dipakai pemiliknya
+	newSessionToken := ""
 	if req.SessionID == "" {
-		req.SessionID = sessionStore.CreateSession()
+		sessionID, token, err := sessionStore.CreateSession()
+		if err != nil {
+			log.Printf("❌ %v", err)
+			return nil, c.Status(fiber.StatusInternalServerError).JSON(models.ChatResponse{
+				Success: false,
+				Error:   "Failed to create session",
+			})
+		}
+		req.SessionID, newSessionToken = sessionID, token
 		log.Printf("🆕 Created new session: %s", req.SessionID)
 	} else {
+		token := c.Get(HeaderSessionToken)
+		if token == "" {
+			token = req.SessionToken
+		}
+		if status, err := authorizeSession(c, sessionStore, req.SessionID, token); err != nil {
+			log.Printf("🔒 Chat rejected for session %s: %v", req.SessionID, err)
+			return nil, c.Status(status).JSON(models.ChatResponse{
+				Success:   false,
+				SessionID: req.SessionID,
+				Error:     err.Error(),
+			})
+		}
 		log.Printf("📝 Using existing session: %s", req.SessionID)
 	}
 
+	// One turn per session at a time: a second message waits until the first one is answered
+	release, err := services.GetTurnLocker().Acquire(req.SessionID)
+	if err != nil {
+		log.Printf("⏳ Session %s busy: %v", req.SessionID, err)
+		c.Set(fiber.HeaderRetryAfter, "2")
+		return nil, c.Status(fiber.StatusTooManyRequests).JSON(models.ChatResponse{
+			Success:      false,
+			SessionID:    req.SessionID,
+			SessionToken: newSessionToken,
+			Error:        err.Error(),
+		})
+	}
+
 	// Set nama user ke context jika diberikan
 	if req.Name != "" {
 		req.Context.Name = req.Name
 		// Simpan nama di session untuk digunakan di request berikutnya
 		sessionStore.SetData(req.SessionID, "user_name", req.Name)
+		sessionStore.PinFact(req.SessionID, services.PinnedName, req.Name)
 		log.Printf("👤 User name: %s", req.Name)
 	} else {
 		// Coba ambil nama dari session jika ada
@@ -73,6 +186,8 @@ func (h *ChatHandler) HandleChat(c *fiber.Ctx) error {
 		}
 	}
 
+	req.Context.SessionID = req.SessionID
+	req.Context.ClientIP = c.IP()
 	log.Printf("💬 Chat request: %s", req.Message)
 	log.Printf("📍 Context: Location=%s, Speed=%.1f km/h, Traffic=%s",
 		req.Context.Location, req.Context.Speed, req.Context.Traffic)
@@ -89,7 +204,68 @@ func (h *ChatHandler) HandleChat(c *fiber.Ctx) error {
 		}
 	}
 
-	// Check if user is asking about pelayanan (services)
+	// With tool calling the model looks up services and e-tilang itself; keyword routing
+	// is the fallback for providers without tools (stub, LLM_TOOLS=false)
+	keywordRouting := !h.openaiService.ToolsEnabled()
+	if keywordRouting {
+		h.lookupPelayanan(&req)
+		h.lookupRegulations(&req)
+	}
+
+	// Continue the active service flow, or start one if the message matches a flow trigger
+	flowResolved, err := h.processFlow(c.UserContext(), &req)
+	if err != nil {
+		release()
+		status := fiber.StatusConflict
+		if errors.Is(err, services.ErrUnknownChoice) {
+			status = fiber.StatusBadRequest
+		}
+		return nil, c.Status(status).JSON(models.ChatResponse{
+			Success:      false,
+			SessionID:    req.SessionID,
+			SessionToken: newSessionToken,
+			FlowInfo:     req.Context.FlowInfo,
+			Error:        err.Error(),
+		})
+	}
+	if req.Context.FlowInfo != nil && req.Context.FlowInfo.Title != "" {
+		sessionStore.PinFact(req.SessionID, services.PinnedService, req.Context.FlowInfo.Title)
+	}
+
+	// Ownership evidence first, so a lookup in the same message already sees the verified data
+	verified := h.verifyOwnership(c.UserContext(), &req, keywordRouting)
+	if keywordRouting && !verified {
+		h.lookupETilang(c.UserContext(), &req)
+	}
+
+	replyMeta := &models.MessageMeta{
+		ReplySource:   services.FlowReplyLLM,
+		ETilangInfo:   req.Context.ETilangInfo,
+		PelayananInfo: pelayananSnapshot(req.Context.PelayananInfo),
+	}
+	if req.Context.FlowInfo != nil {
+		replyMeta.FlowID = req.Context.FlowInfo.FlowID
+		replyMeta.FlowNode = req.Context.FlowInfo.CurrentNode
+	}
+
+	deterministic := flowResolved && req.Context.FlowInfo != nil && req.Context.ETilangInfo == nil && config.AppConfig.FlowReplyMode == services.FlowReplyDeterministic
+	if deterministic {
+		replyMeta.ReplySource = services.FlowReplyDeterministic
+	} else {
+		replyMeta.Model = h.openaiService.ModelName()
+	}
+
+	return &chatTurn{
+		req:             req,
+		newSessionToken: newSessionToken,
+		release:         release,
+		deterministic:   deterministic,
+		replyMeta:       replyMeta,
+	}, nil
+}
+
+// lookupPelayanan melampirkan info pelayanan jika pesan mengandung kata kunci layanan
+func (h *ChatHandler) lookupPelayanan(req *models.ChatRequest) {
 	messageLower := strings.ToLower(req.Message)
 	pelayananKeywords := []string{
 		"pelayanan", "layanan", "sim", "stnk", "pajak", "balik nama", "mutasi",
@@ -111,79 +287,169 @@ func (h *ChatHandler) HandleChat(c *fiber.Ctx) error {
 		if pelayananInfo.Found {
 			req.Context.PelayananInfo = pelayananInfo
 			log.Printf("✅ Pelayanan info attached: %s", pelayananInfo.Flow.Title)
+			services.GetSessionStore().PinFact(req.SessionID, services.PinnedService, pelayananInfo.Flow.Title)
 		}
 	}
+}
 
-	// Check if user is talking about SIM renewal (perpanjangan/pembuatan SIM)
-	if h.simFlowService.DetectSIMIntent(req.Message) {
-		log.Printf("🪪 SIM flow detected")
-
-		// Get current node from session (if exists)
-		currentNodeID := sessionStore.GetData(req.SessionID, "sim_flow_current_node")
-		if currentNodeID == "" {
-			// First time, start from entry node
-			currentNodeID = "entry_node"
-			sessionStore.SetData(req.SessionID, "sim_flow_current_node", currentNodeID)
-			log.Printf("🆕 Starting SIM flow from entry_node")
-		} else {
-			log.Printf("📍 Continuing SIM flow from node: %s", currentNodeID)
-		}
-
-		// Get current node
-		currentNode := h.simFlowService.GetCurrentNode(currentNodeID)
-		if currentNode != nil {
-			// Process user choice to get next node
-			nextNodeID, nextNode := h.simFlowService.ProcessUserChoice(currentNodeID, req.Message)
-
-			if nextNode != nil {
-				// Update session to next node
-				sessionStore.SetData(req.SessionID, "sim_flow_current_node", nextNodeID)
-				log.Printf("➡️  Moving to next node: %s (type: %s)", nextNodeID, nextNode.Type)
-
-				// Get flow info for this node
-				flowInfo := h.simFlowService.GetSIMFlowInfo(nextNodeID)
-				req.Context.SIMFlowInfo = flowInfo
-			} else {
-				// No transition matched, stay on current node
-				log.Printf("⏸️  No transition matched, staying on node: %s", currentNodeID)
-				flowInfo := h.simFlowService.GetSIMFlowInfo(currentNodeID)
-				req.Context.SIMFlowInfo = flowInfo
+// lookupRegulations melampirkan pasal UU 22/2009 jika pesan menanyakan denda, sanksi, atau pasal
+func (h *ChatHandler) lookupRegulations(req *models.ChatRequest) {
+	messageLower := strings.ToLower(req.Message)
+	regulationKeywords := []string{"denda", "sanksi", "hukuman", "pasal", "kurungan", "undang-undang", "uu 22", "uu llaj"}
+
+	for _, keyword := range regulationKeywords {
+		if strings.Contains(messageLower, keyword) {
+			req.Context.Regulations = services.SearchRegulations(req.Message, services.RegulationChatLimit)
+			if len(req.Context.Regulations) > 0 {
+				log.Printf("⚖️  Regulations attached: %d article(s), first %s", len(req.Context.Regulations), req.Context.Regulations[0].Article)
 			}
+			return
 		}
 	}
+}
 
-	// Check if user is asking about e-tilang
-	messageLower = strings.ToLower(req.Message)
+// lookupETilang melampirkan data e-tilang jika pesan menanyakan tilang dan menyebut nomor polisi
+func (h *ChatHandler) lookupETilang(requestCtx context.Context, req *models.ChatRequest) {
+	messageLower := strings.ToLower(req.Message)
 	if strings.Contains(messageLower, "tilang") || strings.Contains(messageLower, "pelanggaran") ||
 		strings.Contains(messageLower, "denda") || strings.Contains(messageLower, "cek") && (strings.Contains(messageLower, "polisi") || strings.Contains(messageLower, "nopol")) {
 
-		// Try to extract plate number
-		plateNumber := h.etilangService.ExtractPlateNumber(req.Message)
-		if plateNumber != "" {
-			log.Printf("🚗 E-Tilang check requested for plate: %s", plateNumber)
+		plates := services.FindPlateNumbers(req.Message)
+		if len(plates) > maxPlateLookups {
+			log.Printf("🚗 %d plates in message, checking the first %d", len(plates), maxPlateLookups)
+			plates = plates[:maxPlateLookups]
+		}
+
+		// The first registered plate is attached; if none is registered, the first not-found result
+		requester := services.OwnershipRequester{SessionID: req.SessionID, ClientIP: req.Context.ClientIP}
+		for _, plate := range plates {
+			log.Printf("🚗 E-Tilang check requested for plate: %s", plate)
+
+			// Get e-tilang info; without it the model answers from the conversation alone
+			etilangInfo, err := h.etilangService.CheckETilang(requestCtx, requester, plate.String(), services.AuditSourceKeyword)
+			if err != nil {
+				log.Printf("⚠️  E-Tilang lookup failed for %s: %v", plate, err)
+				continue
+			}
+			if req.Context.ETilangInfo == nil || etilangInfo.Found {
+				req.Context.ETilangInfo = etilangInfo
+			}
+			if etilangInfo.Found {
+				services.GetSessionStore().PinFact(req.SessionID, services.PinnedPlate, etilangInfo.PlateNumber)
+				break
+			}
+		}
+
+		if info := req.Context.ETilangInfo; info != nil {
+			log.Printf("📋 E-Tilang info attached: Plate=%s, Found=%v, HasViolation=%v, TotalFine=%d",
+				info.PlateNumber, info.Found, info.HasViolation, info.TotalFine)
+		}
+	}
+}
+
+// verifyOwnership mencocokkan karakter terakhir nomor rangka/mesin yang disebut di pesan dengan
+// nomor polisi di session (keyword routing); dengan tool calling model memanggil
+// verify_vehicle_ownership sendiri. Mengembalikan true jika hasil verifikasi sudah dilampirkan ke context.
+func (h *ChatHandler) verifyOwnership(requestCtx context.Context, req *models.ChatRequest, keywordRouting bool) bool {
+	plateNumber := services.GetSessionStore().GetPinnedFacts(req.SessionID)[services.PinnedPlate]
+
+	if !keywordRouting || plateNumber == "" || h.etilangService.IsOwnershipVerified(req.SessionID, plateNumber) {
+		return false
+	}
+	digits := services.FindOwnershipDigits(req.Message)
+	if len(digits) == 0 {
+		return false
+	}
+	// Numbers appear in many messages; only a bare answer or one naming the chassis/engine counts as an attempt
+	messageLower := strings.ToLower(req.Message)
+	if strings.TrimSpace(strings.ToUpper(req.Message)) != digits[0] &&
+		!strings.Contains(messageLower, "rangka") && !strings.Contains(messageLower, "mesin") && !strings.Contains(messageLower, "verifikasi") {
+		return false
+	}
+
+	requester := services.OwnershipRequester{SessionID: req.SessionID, ClientIP: req.Context.ClientIP}
+	info, err := h.etilangService.VerifyOwnership(requestCtx, requester, plateNumber, services.OwnershipEvidence{Digits: digits[0]}, services.AuditSourceKeyword)
+	return h.attachVerification(requestCtx, req, plateNumber, info, err, services.AuditSourceKeyword)
+}
+
+// attachVerification melampirkan hasil verifikasi; jika gagal, data tersamar beserta alasannya
+func (h *ChatHandler) attachVerification(requestCtx context.Context, req *models.ChatRequest, plateNumber string, info *models.ETilangInfo, err error, source string) bool {
+	if info == nil && (errors.Is(err, services.ErrOwnershipLocked) || errors.Is(err, services.ErrOwnershipInput)) {
+		// Rejected before the provider was asked; fetch the masked record so the reply can explain
+		var lookupErr error
+		requester := services.OwnershipRequester{SessionID: req.SessionID, ClientIP: req.Context.ClientIP}
+		info, lookupErr = h.etilangService.CheckETilang(requestCtx, requester, plateNumber, source)
+		if lookupErr == nil {
+			info.VerificationMessage = err.Error()
+		}
+	}
+	if info == nil {
+		log.Printf("⚠️  Ownership verification for %s failed: %v", plateNumber, err)
+		return false
+	}
 
-			// Get e-tilang info
-			etilangInfo := h.etilangService.CheckETilang(plateNumber)
-			req.Context.ETilangInfo = etilangInfo
+	req.Context.ETilangInfo = info
+	log.Printf("🔐 Ownership verification attached: Plate=%s, Verified=%v", info.PlateNumber, info.Verified)
+	return true
+}
 
-			log.Printf("📋 E-Tilang info attached: HasViolation=%v, TotalFine=%d",
-				etilangInfo.HasViolation, etilangInfo.TotalFine)
+// pelayananSnapshot menyalin info pelayanan untuk metadata pesan tanpa script dataset
+func pelayananSnapshot(info *models.PelayananInfo) *models.PelayananInfo {
+	if info == nil {
+		return nil
+	}
+	// The dataset script is static and large; the snapshot only needs to identify the service
+	snapshot := *info
+	snapshot.Flow.Script = nil
+	return &snapshot
+}
+
+// finishTurn menyimpan pesan user dan balasan ke session history
+func (h *ChatHandler) finishTurn(turn *chatTurn, response string) {
+	var userMeta *models.MessageMeta
+	if turn.req.ChoiceID != "" {
+		userMeta = &models.MessageMeta{ChoiceID: turn.req.ChoiceID}
+	}
+
+	sessionStore := services.GetSessionStore()
+	turn.replyMeta.PromptVersion = turn.req.Context.PromptVersion
+
+	// Data fetched by the model through tool calls belongs to this reply and the session facts
+	if toolContext := &turn.req.Context; len(toolContext.ToolsUsed) > 0 {
+		turn.replyMeta.ToolCalls = toolContext.ToolsUsed
+		turn.replyMeta.ETilangInfo = toolContext.ETilangInfo
+		turn.replyMeta.PelayananInfo = pelayananSnapshot(toolContext.PelayananInfo)
+		if toolContext.ETilangInfo != nil && toolContext.ETilangInfo.Found {
+			sessionStore.PinFact(turn.req.SessionID, services.PinnedPlate, toolContext.ETilangInfo.PlateNumber)
+		}
+		if toolContext.PelayananInfo != nil && toolContext.PelayananInfo.Found {
+			sessionStore.PinFact(turn.req.SessionID, services.PinnedService, toolContext.PelayananInfo.Flow.Title)
 		}
 	}
 
+	sessionStore.AddMessage(turn.req.SessionID, "user", turn.req.Message, userMeta)
+	sessionStore.AddMessage(turn.req.SessionID, "assistant", response, turn.replyMeta)
+
+	log.Printf("✅ Chat response generated successfully (session: %s)", turn.req.SessionID)
+}
+
+// prepareReply melengkapi context untuk LLM (reverse geocoding) dan mengambil history session
+func (h *ChatHandler) prepareReply(requestCtx context.Context, req *models.ChatRequest) []models.OpenAIMessage {
 	// If location is empty but coordinates are provided, do reverse geocoding
 	if req.Context.Location == "" && req.Context.Latitude != 0 && req.Context.Longitude != 0 {
-		address, err := h.orsService.ReverseGeocode(req.Context.Latitude, req.Context.Longitude)
+		address, err := h.orsService.ReverseGeocode(requestCtx, req.Context.Latitude, req.Context.Longitude)
 		if err == nil {
 			req.Context.Location = address
 			log.Printf("🗺️  Reverse geocoded location: %s", address)
 		}
 	}
 
-	// Ambil history dari session (prioritas: backend storage > request body)
+	// Ambil history dari session (prioritas: backend storage > request body).
+	// History yang melebihi budget token dipadatkan menjadi ringkasan.
 	var history []models.OpenAIMessage
 	if req.SessionID != "" {
-		history = sessionStore.GetHistory(req.SessionID)
+		history, req.Context.ConversationSummary = h.historyService.Load(requestCtx, req.SessionID)
+		req.Context.PinnedFacts = services.GetSessionStore().GetPinnedFacts(req.SessionID)
 		if len(history) > 0 {
 			log.Printf("📚 Using %d messages from session history", len(history))
 		}
@@ -193,28 +459,163 @@ func (h *ChatHandler) HandleChat(c *fiber.Ctx) error {
 		log.Printf("📚 Using %d messages from request history", len(history))
 	}
 
-	// Call OpenAI API with history
-	response, err := h.openaiService.Chat(req.Message, req.Context, history)
+	return history
+}
+
+// classifyChoice memetakan jawaban bebas ke pilihan node lewat classifier LLM
+func (h *ChatHandler) classifyChoice(requestCtx context.Context, req *models.ChatRequest, flowID string, node *services.FlowNode) (string, *services.FlowNode) {
+	question := ""
+	if info := h.flowService.GetFlowInfo(req.SessionID, flowID, node.ID); info != nil {
+		question = info.NodeText
+	}
+
+	result, err := h.openaiService.ClassifyChoice(requestCtx, req.Message, question, node.Choices)
 	if err != nil {
-		log.Printf("❌ OpenAI error: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ChatResponse{
-			Success: false,
-			Error:   "Failed to get AI response: " + err.Error(),
-		})
+		log.Printf("⚠️  Choice classification failed: %v", err)
+		return "", nil
+	}
+
+	if result.ChoiceID == services.ChoiceNone {
+		return "", nil
+	}
+	if result.Confidence < config.AppConfig.ChoiceMinConfidence {
+		log.Printf("🤔 Low-confidence choice ignored on node %s: %q -> %s (confidence: %.2f < %.2f, reason: %s)",
+			node.ID, req.Message, result.ChoiceID, result.Confidence, config.AppConfig.ChoiceMinConfidence, result.Reason)
+		return "", nil
 	}
 
-	// Simpan pesan user dan response ke session history
-	sessionStore.AddMessage(req.SessionID, "user", req.Message)
-	sessionStore.AddMessage(req.SessionID, "assistant", response)
+	nextNodeID, nextNode, err := h.flowService.ProcessChoiceID(req.SessionID, flowID, node.ID, result.ChoiceID)
+	if err != nil {
+		log.Printf("⚠️  Classified choice %s rejected: %v", result.ChoiceID, err)
+		return "", nil
+	}
+	return nextNodeID, nextNode
+}
 
-	log.Printf("✅ Chat response generated successfully (session: %s)", req.SessionID)
+// processFlow menjalankan satu langkah flow layanan (SIM, STNK, ...) untuk request ini.
+// Nilai true berarti input sudah ditangani flow (pindah node, perintah navigasi, atau
+// flow baru dimulai) sehingga balasan bisa dirender langsung dari node.
+// Error dikembalikan jika choice_id/node_id dari frontend tidak sesuai posisi flow.
+func (h *ChatHandler) processFlow(requestCtx context.Context, req *models.ChatRequest) (bool, error) {
+	sessionStore := services.GetSessionStore()
+	structured := req.ChoiceID != "" || req.NodeID != ""
 
-	return c.JSON(models.ChatResponse{
-		Success:       true,
-		Response:      response,
-		SessionID:     req.SessionID, // Return session ID ke frontend
-		ETilangInfo:   req.Context.ETilangInfo,
-		PelayananInfo: req.Context.PelayananInfo,
-		SIMFlowInfo:   req.Context.SIMFlowInfo,
-	})
+	// Navigation commands (kembali, ulang dari awal, batal, lanjutkan)
+	if !structured {
+		activeFlowID, activeNodeID := sessionStore.GetActiveFlow(req.SessionID)
+		if command := h.flowService.DetectCommand(req.Message, h.flowService.GetNode(activeFlowID, activeNodeID)); command != "" {
+			flowInfo, err := h.flowService.ExecuteCommand(req.SessionID, command)
+			if err == nil {
+				req.Context.FlowInfo = flowInfo
+				return true, nil
+			}
+			log.Printf("⚠️  Flow command %s ignored: %v", command, err)
+		}
+	}
+
+	flowID, currentNodeID := sessionStore.GetActiveFlow(req.SessionID)
+
+	// Optimistic concurrency: the frontend must be looking at the current node
+	if req.NodeID != "" && req.NodeID != currentNodeID {
+		log.Printf("⚠️  Stale flow node: request %s, current %q", req.NodeID, currentNodeID)
+		if flowID != "" {
+			req.Context.FlowInfo = h.flowService.GetFlowInfo(req.SessionID, flowID, currentNodeID)
+		}
+		return false, services.ErrStaleFlowNode
+	}
+
+	started := false
+	if flowID == "" {
+		if structured {
+			return false, services.ErrNoActiveFlow
+		}
+		flow := h.flowService.DetectIntent(req.Message)
+		if flow == nil {
+			return false, nil
+		}
+		flowID = flow.FlowID
+		currentNodeID = h.flowService.StartFlow(req.SessionID, flowID)
+		started = true
+	} else {
+		log.Printf("📍 Continuing flow %s from node: %s", flowID, currentNodeID)
+	}
+
+	currentNode := h.flowService.GetNode(flowID, currentNodeID)
+	if currentNode == nil {
+		log.Printf("⚠️  Node %s not found in flow %s, ending flow", currentNodeID, flowID)
+		sessionStore.EndFlow(req.SessionID)
+		return false, nil
+	}
+
+	var nextNodeID string
+	var nextNode *services.FlowNode
+
+	if req.ChoiceID != "" {
+		// Button press: advance deterministically by choice ID
+		var err error
+		nextNodeID, nextNode, err = h.flowService.ProcessChoiceID(req.SessionID, flowID, currentNodeID, req.ChoiceID)
+		if err != nil {
+			req.Context.FlowInfo = h.flowService.GetFlowInfo(req.SessionID, flowID, currentNodeID)
+			return false, err
+		}
+		if req.Message == "" {
+			req.Message = currentNode.GetChoice(req.ChoiceID).Label
+		}
+		log.Printf("🔘 Choice %s selected on node %s", req.ChoiceID, currentNodeID)
+	} else if currentNode.Type == "collect" && len(req.Documents) > 0 {
+		// Bind uploaded document to the collect key
+		var rejection *models.UploadRejection
+		nextNodeID, nextNode, rejection = h.flowService.ProcessUpload(req.SessionID, flowID, currentNodeID, req.Documents)
+		req.Context.UploadRejection = rejection
+	} else if !started {
+		// The message that triggered the flow is not an answer to the entry question
+		// ("perpanjang SIM C" must still ask which SIM)
+		nextNodeID, nextNode = h.flowService.ProcessUserChoice(req.SessionID, flowID, currentNodeID, req.Message)
+
+		// Free-text answer that matched no choice: let the LLM classifier try
+		if nextNode == nil && len(currentNode.Choices) > 0 && h.flowService.MatchChoice(currentNode, req.Message) == nil {
+			nextNodeID, nextNode = h.classifyChoice(requestCtx, req, flowID, currentNode)
+		}
+	}
+
+	if nextNode == nil {
+		// No transition matched, stay on current node
+		log.Printf("⏸️  No transition matched, staying on node: %s", currentNodeID)
+		req.Context.FlowInfo = h.flowService.GetFlowInfo(req.SessionID, flowID, currentNodeID)
+		return started, nil
+	}
+
+	log.Printf("➡️  Moving to next node: %s (type: %s)", nextNodeID, nextNode.Type)
+
+	// Run action nodes (generate_zip, handoff, ...) and pass informational message nodes
+	// until a node needs user input
+	resolvedNodeID, actionResult, messages := h.flowService.RunActions(req.SessionID, flowID, nextNodeID)
+	sessionStore.SetFlowNode(req.SessionID, flowID, resolvedNodeID)
+
+	flowInfo := h.flowService.GetFlowInfo(req.SessionID, flowID, resolvedNodeID)
+	if flowInfo != nil {
+		flowInfo.Messages = messages
+	}
+	if flowInfo != nil && actionResult != nil {
+		flowInfo.Action = &models.FlowActionInfo{
+			Type:    actionResult.Type,
+			OK:      actionResult.OK,
+			Output:  actionResult.Output,
+			Message: actionResult.Message,
+		}
+	}
+	req.Context.FlowInfo = flowInfo
+
+	// Flow reached a node without transitions: release the session from the flow
+	if flowInfo != nil && !flowInfo.Active {
+		log.Printf("🏁 Flow %s finished at node: %s", flowID, resolvedNodeID)
+		sessionStore.EndFlow(req.SessionID)
+	}
+
+	return true, nil
+}
+
+// setUpstreamRetryAfter memberi tahu client kapan dependency yang gangguan dicoba lagi (cooldown circuit breaker)
+func setUpstreamRetryAfter(c *fiber.Ctx) {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(config.AppConfig.CircuitBreakerCooldown.Seconds())))
 }
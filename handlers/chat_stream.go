@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"police-assistant-backend/models"
+	"police-assistant-backend/services"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Nama event SSE untuk chat stream
+const (
+	streamEventMetadata = "metadata"
+	streamEventDelta    = "delta"
+	streamEventDone     = "done"
+)
+
+// streamKeepAliveInterval: komentar SSE dikirim saat menunggu token pertama agar koneksi
+// yang sudah ditutup client terdeteksi tanpa menunggu balasan LLM selesai
+const streamKeepAliveInterval = 5 * time.Second
+
+var errClientGone = errors.New("client closed the stream")
+
+// HandleChatStream handles POST /api/v1/chat/stream
+// Request sama dengan /chat; balasan dikirim sebagai Server-Sent Events:
+// satu event metadata, event delta per potongan teks, lalu event done.
+func (h *ChatHandler) HandleChatStream(c *fiber.Ctx) error {
+	turn, err := h.beginTurn(c)
+	if turn == nil {
+		return err
+	}
+
+	var history []models.OpenAIMessage
+	if !turn.deterministic {
+		history = h.prepareReply(c.UserContext(), &turn.req)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // Disable proxy buffering (nginx) so deltas arrive immediately
+
+	// The body writer runs after this handler returns, so the turn lock is released there.
+	// Done is taken now because c must not be used once the handler has returned.
+	serverDone := c.Context().Done()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer turn.release()
+		h.streamTurn(w, turn, history, serverDone)
+	})
+	return nil
+}
+
+// streamTurn mengirim event SSE untuk satu giliran dan menyimpan balasan ke history,
+// termasuk teks parsial jika client menutup koneksi di tengah stream. Permintaan ke LLM
+// dibatalkan begitu koneksi terputus (gagal menulis) atau server berhenti.
+func (h *ChatHandler) streamTurn(w *bufio.Writer, turn *chatTurn, history []models.OpenAIMessage, serverDone <-chan struct{}) {
+	req := &turn.req
+
+	// The keep-alive goroutine must stop before w is handed back to the server
+	var keepAlive sync.WaitGroup
+	streamCtx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		keepAlive.Wait()
+	}()
+
+	// Writes come from the reply callback and the keep-alive goroutine
+	var writeMu sync.Mutex
+	clientGone := false
+	write := func(chunk string) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if clientGone {
+			return errClientGone
+		}
+		fmt.Fprint(w, chunk)
+		if err := w.Flush(); err != nil {
+			clientGone = true
+			cancel()
+			return err
+		}
+		return nil
+	}
+	send := func(event string, payload interface{}) error {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		return write(fmt.Sprintf("event: %s\ndata: %s\n\n", event, data))
+	}
+
+	keepAlive.Add(1)
+	go func() {
+		defer keepAlive.Done()
+		ticker := time.NewTicker(streamKeepAliveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-streamCtx.Done():
+				return
+			case <-serverDone:
+				cancel()
+				return
+			case <-ticker.C:
+				if write(": keep-alive\n\n") != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	response := ""
+	completed := false
+	var replyErr error
+
+	if err := send(streamEventMetadata, models.ChatStreamMetadata{
+		SessionID:       req.SessionID,
+		SessionToken:    turn.newSessionToken,
+		ETilangInfo:     req.Context.ETilangInfo,
+		PelayananInfo:   req.Context.PelayananInfo,
+		FlowInfo:        req.Context.FlowInfo,
+		UploadRejection: req.Context.UploadRejection,
+	}); err == nil {
+		if turn.deterministic {
+			// Flow replies are rendered in full up front; send them as a single delta
+			response = h.flowService.RenderReply(req.Context.FlowInfo)
+			completed = send(streamEventDelta, models.ChatStreamDelta{Text: response}) == nil
+		} else {
+			response, replyErr = h.openaiService.ChatStream(streamCtx, req.Message, &req.Context, history, func(delta string) error {
+				return send(streamEventDelta, models.ChatStreamDelta{Text: delta})
+			})
+			completed = replyErr == nil
+
+			if response == "" && errors.Is(replyErr, services.ErrUpstreamUnavailable) {
+				// Nothing reached the client yet, so the fallback can still be shown as the reply
+				response = services.LLMFallbackReply
+				send(streamEventDelta, models.ChatStreamDelta{Text: response})
+			}
+		}
+	}
+
+	writeMu.Lock()
+	gone := clientGone
+	writeMu.Unlock()
+
+	if gone {
+		log.Printf("🔌 Client closed chat stream (session: %s, %d chars sent)", req.SessionID, len(response))
+	} else if replyErr != nil {
+		log.Printf("❌ OpenAI stream error: %v", replyErr)
+	}
+
+	// Nothing was generated (OpenAI failed before the first token or the client left
+	// before the turn started): keep history unchanged, as /chat does on errors.
+	// The fallback reply is not part of the conversation either.
+	unavailable := errors.Is(replyErr, services.ErrUpstreamUnavailable) && response == services.LLMFallbackReply
+	if response != "" && !unavailable {
+		turn.replyMeta.Aborted = !completed
+		h.finishTurn(turn, response)
+	}
+
+	if gone {
+		return
+	}
+
+	done := models.ChatStreamDone{
+		SessionID:     req.SessionID,
+		Response:      response,
+		ReplySource:   turn.replyMeta.ReplySource,
+		PromptVersion: req.Context.PromptVersion,
+		Regulations:   req.Context.Regulations, // From keyword routing or search_regulations
+	}
+	if replyErr != nil {
+		done.Error = "Failed to get AI response: " + replyErr.Error()
+	}
+	if len(req.Context.ToolsUsed) > 0 {
+		done.ETilangInfo = req.Context.ETilangInfo
+		done.PelayananInfo = req.Context.PelayananInfo
+		done.Routes = req.Context.Routes
+		done.TrafficInfo = req.Context.TrafficInfo
+	}
+	send(streamEventDone, done)
+}
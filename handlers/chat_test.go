@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"police-assistant-backend/config"
+	"police-assistant-backend/models"
+	"police-assistant-backend/services"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestMain menjalankan test dari root repo agar rules, flows, dan prompts terbaca seperti
+// saat server jalan, dengan LLM stub dan storage sementara
+func TestMain(m *testing.M) {
+	storageDir, err := os.MkdirTemp("", "handlers-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.Chdir(".."); err != nil {
+		log.Fatal(err)
+	}
+	os.Setenv("LLM_PROVIDER", "stub")
+	os.Setenv("LLM_STUB_FILE", "llm-stub.json")
+	os.Setenv("SESSION_BACKEND", "memory")
+	os.Setenv("STORAGE_DIR", storageDir)
+	os.Setenv("PAYMENT_PROVIDER", "fake")
+	config.LoadConfig()
+
+	if testApp, err = newTestApp(); err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(storageDir)
+	os.Exit(code)
+}
+
+// testApp dipakai bersama semua test; database payment bolt hanya bisa dibuka sekali
+var testApp *fiber.App
+
+// newTestApp merakit service serta route chat dan payment sama seperti main.go
+func newTestApp() (*fiber.App, error) {
+	rulesService := services.NewRulesService()
+	orsService := services.NewORSService()
+	auditLog := services.NewAuditLog(config.AppConfig.AuditLogPath)
+	paymentStore, err := services.NewPaymentStore(config.AppConfig.PaymentDBPath)
+	if err != nil {
+		return nil, err
+	}
+	etilangService := services.NewETilangService(services.NewETilangProvider(), auditLog, paymentStore)
+	promptService, err := services.NewPromptService(config.AppConfig.PromptsDir, false)
+	if err != nil {
+		return nil, err
+	}
+	openaiService := services.NewOpenAIService(rulesService, promptService, services.NewChatModel(), nil)
+	fileStore := services.NewFileStore()
+	flowService := services.NewFlowService(services.NewActionRegistry(fileStore), services.NewUploadService(fileStore))
+	chatHandler := NewChatHandler(openaiService, orsService, etilangService, services.NewPelayananService(), flowService, services.NewHistoryService(openaiService))
+
+	paymentProvider, err := services.NewPaymentProvider()
+	if err != nil {
+		return nil, err
+	}
+	paymentHandler := NewPaymentHandler(services.NewPaymentService(etilangService, paymentStore, paymentProvider, auditLog))
+
+	app := fiber.New()
+	app.Post("/api/v1/chat", chatHandler.HandleChat)
+	app.Post("/api/v1/etilang/:plate/payments", paymentHandler.CreatePayment)
+	app.Get("/api/v1/session/:session_id/payments/:payment_id", RequireSessionAccess(), paymentHandler.GetPayment)
+	return app, nil
+}
+
+func postChat(app *fiber.App, token string, req models.ChatRequest) (int, models.ChatResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return 0, models.ChatResponse{}, err
+	}
+	httpReq := httptest.NewRequest("POST", "/api/v1/chat", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		httpReq.Header.Set(HeaderSessionToken, token)
+	}
+
+	// No test timeout: turns queue on the session lock instead of failing fast
+	resp, err := app.Test(httpReq, -1)
+	if err != nil {
+		return 0, models.ChatResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, models.ChatResponse{}, err
+	}
+	var chatResp models.ChatResponse
+	if err := json.Unmarshal(raw, &chatResp); err != nil {
+		return resp.StatusCode, chatResp, fmt.Errorf("invalid response %q: %w", raw, err)
+	}
+	return resp.StatusCode, chatResp, nil
+}
+
+// TestHandleChatConcurrentTurns mengirim banyak giliran paralel ke satu session. Jalankan
+// dengan go test -race: setiap giliran harus tersimpan utuh sebagai pasangan user/assistant
+// tanpa disela giliran lain.
+func TestHandleChatConcurrentTurns(t *testing.T) {
+	app := testApp
+	store := services.GetSessionStore()
+
+	sessionID, token, err := store.CreateSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const workers, turns = 8, 5
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*turns)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for turn := 0; turn < turns; turn++ {
+				status, resp, err := postChat(app, token, models.ChatRequest{
+					SessionID: sessionID,
+					Message:   fmt.Sprintf("halo dari client %d giliran %d", worker, turn),
+				})
+				switch {
+				case err != nil:
+					errs <- err
+				case status != fiber.StatusOK || !resp.Success:
+					errs <- fmt.Errorf("worker %d turn %d: status %d, error %q", worker, turn, status, resp.Error)
+				case resp.SessionID != sessionID:
+					errs <- fmt.Errorf("worker %d turn %d: session %q, want %q", worker, turn, resp.SessionID, sessionID)
+				}
+			}
+		}(w)
+	}
+
+	// Readers outside the turn lock, like the session info and flow endpoints
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				if session, ok := store.GetSession(sessionID); ok {
+					session.History = nil // Must only touch the copy
+				}
+				store.GetHistory(sessionID)
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(done)
+	readers.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	history := store.GetHistory(sessionID)
+	if len(history) != 2*workers*turns {
+		t.Fatalf("history has %d messages, want %d", len(history), 2*workers*turns)
+	}
+	seen := make(map[string]bool, workers*turns)
+	for i := 0; i < len(history); i += 2 {
+		user, assistant := history[i], history[i+1]
+		if user.Role != "user" || assistant.Role != "assistant" {
+			t.Fatalf("messages %d-%d are %s/%s, want an uninterrupted user/assistant turn", i, i+1, user.Role, assistant.Role)
+		}
+		if seen[user.Content] {
+			t.Fatalf("turn %q stored twice", user.Content)
+		}
+		seen[user.Content] = true
+		if assistant.Content == "" {
+			t.Fatalf("empty reply stored for %q", user.Content)
+		}
+	}
+}
+
+// TestHandleChatRejectsWrongToken memastikan session hanya bisa dipakai pemilik token
+func TestHandleChatRejectsWrongToken(t *testing.T) {
+	app := testApp
+
+	sessionID, token, err := services.GetSessionStore().CreateSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, _, err := postChat(app, token+"x", models.ChatRequest{SessionID: sessionID, Message: "halo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status == fiber.StatusOK {
+		t.Fatalf("chat with a wrong token returned %d", status)
+	}
+	if history := services.GetSessionStore().GetHistory(sessionID); len(history) != 0 {
+		t.Fatalf("rejected turn stored %d messages", len(history))
+	}
+}
+
+// TestTriggerMessageIsNotAnAnswer: pesan pemicu flow tidak boleh langsung memilih jenis SIM
+func TestTriggerMessageIsNotAnAnswer(t *testing.T) {
+	sessionID, token, err := services.GetSessionStore().CreateSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, resp, err := postChat(testApp, token, models.ChatRequest{SessionID: sessionID, Message: "saya mau perpanjang SIM C"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != fiber.StatusOK || resp.FlowInfo == nil {
+		t.Fatalf("status %d, flow info %v", status, resp.FlowInfo)
+	}
+	if resp.FlowInfo.CurrentNode != "start" || resp.FlowInfo.Context["sim_type"] != nil {
+		t.Fatalf("flow at %s with context %v, want the entry node with nothing chosen", resp.FlowInfo.CurrentNode, resp.FlowInfo.Context)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package handlers
+
+import "net"
+
+// connClosed: tanpa MSG_PEEK, putusnya koneksi baru ketahuan saat response ditulis
+func connClosed(conn net.Conn) bool {
+	return false
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package handlers
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// connClosed mengintip socket tanpa mengambil data: EOF atau reset berarti client sudah pergi.
+// Koneksi yang bukan socket (TLS, app.Test) dianggap masih terbuka.
+func connClosed(conn net.Conn) bool {
+	sysConn, ok := conn.(syscall.Conn)
+	if !ok {
+		return false
+	}
+	rawConn, err := sysConn.SyscallConn()
+	if err != nil {
+		return false
+	}
+
+	closed := false
+	buf := make([]byte, 1)
+	rawConn.Read(func(fd uintptr) bool {
+		n, _, err := syscall.Recvfrom(int(fd), buf, syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		closed = (n == 0 && err == nil) || errors.Is(err, syscall.ECONNRESET)
+		return true // Never wait for data
+	})
+	return closed
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"police-assistant-backend/services"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type FileHandler struct {
+	fileStore *services.FileStore
+}
+
+func NewFileHandler(fileStore *services.FileStore) *FileHandler {
+	return &FileHandler{
+		fileStore: fileStore,
+	}
+}
+
+// DownloadFile handles GET /api/v1/files/:file_id?expires=...&signature=...
+// Mengirim file yang tersimpan (misal berkas ZIP hasil flow SIM). Link bertanda tangan dibuat
+// FileStore.URL untuk session pemilik file dan bisa dibuka langsung di browser; tanpa tanda
+// tangan yang valid hanya X-Admin-Key yang bisa mengunduh.
+func (h *FileHandler) DownloadFile(c *fiber.Ctx) error {
+	fileID := c.Params("file_id")
+
+	file, data, err := h.fileStore.Get(fileID)
+	if err != nil {
+		log.Printf("❌ File not found: %s (%v)", fileID, err)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "File not found",
+		})
+	}
+
+	// Files stored before session binding have no owner; only admins can still fetch them
+	if file.SessionID == "" && !isAdmin(c) {
+		log.Printf("🔒 File %s has no owning session, download denied", fileID)
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error":   "File not found",
+		})
+	}
+	if file.SessionID != "" && !isAdmin(c) {
+		if err := h.fileStore.VerifyURL(file, c.Query("expires"), c.Query("signature"), time.Now()); err != nil {
+			log.Printf("🔒 File %s download denied: %v", fileID, err)
+			if errors.Is(err, services.ErrFileURLExpired) {
+				return c.Status(fiber.StatusGone).JSON(fiber.Map{
+					"success": false,
+					"error":   "Download link has expired",
+				})
+			}
+			// Do not reveal that the file exists without a valid link
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"success": false,
+				"error":   "File not found",
+			})
+		}
+	}
+
+	log.Printf("📥 Downloading file: %s (%s)", file.FileName, fileID)
+
+	c.Set(fiber.HeaderContentType, file.ContentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", file.FileName))
+	return c.Send(data)
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"police-assistant-backend/models"
+	"police-assistant-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type FlowHandler struct {
+	flowService  *services.FlowService
+	sessionStore services.SessionStore
+}
+
+func NewFlowHandler(flowService *services.FlowService) *FlowHandler {
+	return &FlowHandler{
+		flowService:  flowService,
+		sessionStore: services.GetSessionStore(),
+	}
+}
+
+// GetFlowState handles GET /api/v1/session/:session_id/flow
+// Menampilkan posisi flow yang sedang aktif di session
+func (h *FlowHandler) GetFlowState(c *fiber.Ctx) error {
+	sessionID := c.Params("session_id")
+
+	if _, exists := h.sessionStore.GetSession(sessionID); !exists {
+		return c.Status(fiber.StatusNotFound).JSON(models.FlowStateResponse{
+			Success:   false,
+			SessionID: sessionID,
+			Error:     "Session not found",
+		})
+	}
+
+	flowID, nodeID := h.sessionStore.GetActiveFlow(sessionID)
+	if flowID == "" {
+		return c.JSON(models.FlowStateResponse{
+			Success:   true,
+			SessionID: sessionID,
+			Message:   "No active flow",
+		})
+	}
+
+	return c.JSON(models.FlowStateResponse{
+		Success:   true,
+		SessionID: sessionID,
+		FlowInfo:  h.flowService.GetFlowInfo(sessionID, flowID, nodeID),
+	})
+}
+
+// BackFlow handles POST /api/v1/session/:session_id/flow/back
+func (h *FlowHandler) BackFlow(c *fiber.Ctx) error {
+	return h.runCommand(c, services.FlowCommandBack)
+}
+
+// RestartFlow handles POST /api/v1/session/:session_id/flow/restart
+func (h *FlowHandler) RestartFlow(c *fiber.Ctx) error {
+	return h.runCommand(c, services.FlowCommandRestart)
+}
+
+// CancelFlow handles POST /api/v1/session/:session_id/flow/cancel
+func (h *FlowHandler) CancelFlow(c *fiber.Ctx) error {
+	return h.runCommand(c, services.FlowCommandCancel)
+}
+
+// ResumeFlow handles POST /api/v1/session/:session_id/flow/resume
+func (h *FlowHandler) ResumeFlow(c *fiber.Ctx) error {
+	return h.runCommand(c, services.FlowCommandResume)
+}
+
+// runCommand menjalankan perintah navigasi flow dan memetakan error ke status HTTP
+func (h *FlowHandler) runCommand(c *fiber.Ctx, command string) error {
+	sessionID := c.Params("session_id")
+
+	if _, exists := h.sessionStore.GetSession(sessionID); !exists {
+		return c.Status(fiber.StatusNotFound).JSON(models.FlowStateResponse{
+			Success:   false,
+			SessionID: sessionID,
+			Error:     "Session not found",
+		})
+	}
+
+	release, err := services.GetTurnLocker().Acquire(sessionID)
+	if err != nil {
+		c.Set(fiber.HeaderRetryAfter, "2")
+		return c.Status(fiber.StatusTooManyRequests).JSON(models.FlowStateResponse{
+			Success:   false,
+			SessionID: sessionID,
+			Error:     err.Error(),
+		})
+	}
+	defer release()
+
+	flowInfo, err := h.flowService.ExecuteCommand(sessionID, command)
+	if err != nil {
+		log.Printf("⚠️  Flow command %s rejected for session %s: %v", command, sessionID, err)
+
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, services.ErrNoActiveFlow) || errors.Is(err, services.ErrNoPreviousNode) || errors.Is(err, services.ErrNoPausedFlow) {
+			status = fiber.StatusConflict
+		}
+		return c.Status(status).JSON(models.FlowStateResponse{
+			Success:   false,
+			SessionID: sessionID,
+			Error:     err.Error(),
+		})
+	}
+
+	log.Printf("🧭 Flow command %s applied (session: %s)", command, sessionID)
+
+	return c.JSON(models.FlowStateResponse{
+		Success:   true,
+		SessionID: sessionID,
+		FlowInfo:  flowInfo,
+		Message:   "Flow " + command + " applied",
+	})
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"police-assistant-backend/models"
+	"police-assistant-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PaymentHandler struct {
+	paymentService *services.PaymentService
+	sessionStore   services.SessionStore
+}
+
+func NewPaymentHandler(paymentService *services.PaymentService) *PaymentHandler {
+	return &PaymentHandler{
+		paymentService: paymentService,
+		sessionStore:   services.GetSessionStore(),
+	}
+}
+
+// CreatePayment handles POST /api/v1/etilang/:plate/payments
+// Body: { "session_id": "...", "violation_ids": ["ETLE-B1234SV-01"] }; violation_ids kosong = semua
+// pelanggaran yang belum dibayar. Wajib header X-Session-Token dan session sudah memverifikasi
+// kepemilikan kendaraan. Tagihan pending yang sama dikembalikan dengan status 200.
+func (h *PaymentHandler) CreatePayment(c *fiber.Ctx) error {
+	var req models.PaymentRequest
+	if err := c.BodyParser(&req); err != nil {
+		log.Printf("❌ Failed to parse request: %v", err)
+		return c.Status(fiber.StatusBadRequest).JSON(models.PaymentResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+	}
+	if req.SessionID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.PaymentResponse{
+			Success: false,
+			Error:   "session_id is required",
+		})
+	}
+
+	if status, err := authorizeSession(c, h.sessionStore, req.SessionID, c.Get(HeaderSessionToken)); err != nil {
+		log.Printf("🔒 Payment for %s denied (session: %s): %v", c.Params("plate"), req.SessionID, err)
+		return c.Status(status).JSON(models.PaymentResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	payment, created, err := h.paymentService.CreatePayment(c.UserContext(), req.SessionID, c.Params("plate"), req.ViolationIDs)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		message := err.Error()
+		switch {
+		case errors.Is(err, services.ErrInvalidPlateNumber):
+			status = fiber.StatusBadRequest
+		case errors.Is(err, services.ErrOwnershipRequired):
+			status = fiber.StatusForbidden
+		case errors.Is(err, services.ErrPlateNotFound):
+			status = fiber.StatusNotFound
+		case errors.Is(err, services.ErrViolationNotPayable), errors.Is(err, services.ErrNothingToPay):
+			status = fiber.StatusConflict
+		case errors.Is(err, services.ErrUpstreamUnavailable):
+			status = fiber.StatusServiceUnavailable
+			message = "Layanan e-tilang sedang mengalami gangguan, silakan coba lagi beberapa saat lagi"
+			setUpstreamRetryAfter(c)
+		}
+		log.Printf("❌ Failed to create payment for %s: %v", c.Params("plate"), err)
+		return c.Status(status).JSON(models.PaymentResponse{
+			Success: false,
+			Error:   message,
+		})
+	}
+
+	status := fiber.StatusOK
+	if created {
+		status = fiber.StatusCreated
+	}
+	return c.Status(status).JSON(models.PaymentResponse{
+		Success: true,
+		Payment: payment,
+	})
+}
+
+// GetPayment handles GET /api/v1/session/:session_id/payments/:payment_id
+// Dipasang di belakang RequireSessionAccess; tagihan session lain dibalas 404 agar nomor VA
+// dan nomor polisi tidak bocor ke pemegang ID tagihan.
+func (h *PaymentHandler) GetPayment(c *fiber.Ctx) error {
+	payment, err := h.paymentService.GetPayment(c.Params("payment_id"))
+	if err == nil && payment.SessionID != c.Params("session_id") {
+		log.Printf("🔒 Payment %s requested by another session (%s)", c.Params("payment_id"), c.Params("session_id"))
+		err = services.ErrPaymentNotFound
+	}
+	if errors.Is(err, services.ErrPaymentNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(models.PaymentResponse{
+			Success: false,
+			Error:   "Payment not found",
+		})
+	}
+	if err != nil {
+		log.Printf("❌ Failed to get payment %s: %v", c.Params("payment_id"), err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.PaymentResponse{
+			Success: false,
+			Error:   "Failed to get payment",
+		})
+	}
+
+	return c.JSON(models.PaymentResponse{
+		Success: true,
+		Payment: payment,
+	})
+}
+
+// HandleWebhook handles POST /api/v1/payments/webhook
+// Callback status pembayaran dari provider; body ditandatangani di header X-Callback-Signature
+func (h *PaymentHandler) HandleWebhook(c *fiber.Ctx) error {
+	payment, err := h.paymentService.HandleCallback(c.Body(), c.Get(services.PaymentSignatureHeader))
+	if err != nil {
+		status := fiber.StatusBadRequest
+		switch {
+		case errors.Is(err, services.ErrInvalidSignature):
+			status = fiber.StatusUnauthorized
+		case errors.Is(err, services.ErrWebhookNotConfigured):
+			status = fiber.StatusServiceUnavailable
+		case errors.Is(err, services.ErrPaymentNotFound):
+			status = fiber.StatusNotFound
+		}
+		log.Printf("❌ Payment callback rejected: %v", err)
+		return c.Status(status).JSON(models.PaymentResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(models.PaymentResponse{
+		Success: true,
+		Payment: payment,
+	})
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"police-assistant-backend/models"
+	"police-assistant-backend/services"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func doPaymentRequest(t *testing.T, method string, path string, token string, body interface{}) (int, models.PaymentResponse) {
+	t.Helper()
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	httpReq := httptest.NewRequest(method, path, bytes.NewReader(data))
+	httpReq.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		httpReq.Header.Set(HeaderSessionToken, token)
+	}
+
+	resp, err := testApp.Test(httpReq, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var paymentResp models.PaymentResponse
+	if err := json.NewDecoder(resp.Body).Decode(&paymentResp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, paymentResp
+}
+
+// TestGetPaymentRequiresOwnerSession: status tagihan (nomor VA, nomor polisi) hanya untuk session pembuatnya
+func TestGetPaymentRequiresOwnerSession(t *testing.T) {
+	store := services.GetSessionStore()
+	sessionID, token, err := store.CreateSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, message := range []string{"cek tilang B 1234 SV", "nomor rangka 23456"} {
+		if status, resp, err := postChat(testApp, token, models.ChatRequest{SessionID: sessionID, Message: message}); err != nil || status != fiber.StatusOK {
+			t.Fatalf("chat %q: status %d, error %v %q", message, status, err, resp.Error)
+		}
+	}
+
+	status, created := doPaymentRequest(t, "POST", "/api/v1/etilang/B1234SV/payments", token, models.PaymentRequest{SessionID: sessionID})
+	if status != fiber.StatusCreated && status != fiber.StatusOK {
+		t.Fatalf("create payment: status %d, error %q", status, created.Error)
+	}
+	paymentID := created.Payment.ID
+
+	if status, resp := doPaymentRequest(t, "GET", "/api/v1/session/"+sessionID+"/payments/"+paymentID, token, nil); status != fiber.StatusOK || resp.Payment.ID != paymentID {
+		t.Fatalf("owner: status %d, payment %+v", status, resp.Payment)
+	}
+
+	otherSession, otherToken, err := store.CreateSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		session string
+		token   string
+		want    int
+	}{
+		{"no token", sessionID, "", fiber.StatusUnauthorized},
+		{"token of another session", sessionID, otherToken, fiber.StatusForbidden},
+		{"another session's own token", otherSession, otherToken, fiber.StatusNotFound},
+	}
+	for _, tt := range tests {
+		status, resp := doPaymentRequest(t, "GET", "/api/v1/session/"+tt.session+"/payments/"+paymentID, tt.token, nil)
+		if status != tt.want || resp.Payment != nil {
+			t.Errorf("%s: status %d (payment %v), want %d without the payment", tt.name, status, resp.Payment != nil, tt.want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"log"
+	"police-assistant-backend/models"
+	"police-assistant-backend/services"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type RegulationHandler struct{}
+
+func NewRegulationHandler() *RegulationHandler {
+	return &RegulationHandler{}
+}
+
+// SearchRegulations handles GET /api/v1/regulations?q=
+// Tanpa q mengembalikan seluruh katalog pasal; dengan q ("berapa denda tidak pakai helm",
+// "pasal 287") mengembalikan pasal yang paling relevan
+func (h *RegulationHandler) SearchRegulations(c *fiber.Ctx) error {
+	query := strings.TrimSpace(c.Query("q"))
+	limit := c.QueryInt("limit", 0)
+	if limit < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(models.RegulationResponse{
+			Success:     false,
+			Query:       query,
+			Regulations: []models.Regulation{},
+			Error:       "limit must not be negative",
+		})
+	}
+
+	regulations := services.SearchRegulations(query, limit)
+	if query != "" {
+		log.Printf("⚖️  Regulation search %q: %d result(s)", query, len(regulations))
+	}
+
+	return c.JSON(models.RegulationResponse{
+		Success:     true,
+		Query:       query,
+		Regulations: regulations,
+	})
+}
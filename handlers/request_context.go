@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Seberapa sering koneksi client dicek selama request berjalan
+const connCheckInterval = 250 * time.Millisecond
+
+// RequestContext memberi setiap request context (c.UserContext) yang dibatalkan saat client
+// menutup koneksi atau server berhenti, sehingga panggilan LLM, ORS, dan e-tilang yang sedang
+// berjalan ikut berhenti alih-alih menghabiskan kuota untuk jawaban yang tidak dibaca siapa pun.
+func RequestContext() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestCtx, cancel := context.WithCancel(context.Background())
+		c.SetUserContext(requestCtx)
+
+		// The watcher must stop before the handler returns, because c is reused afterwards
+		conn := c.Context().Conn()
+		serverDone := c.Context().Done()
+		stop := make(chan struct{})
+		var watcher sync.WaitGroup
+		watcher.Add(1)
+		go func() {
+			defer watcher.Done()
+			watchRequest(conn, serverDone, stop, cancel)
+		}()
+		defer func() {
+			close(stop)
+			watcher.Wait()
+			cancel()
+		}()
+
+		return c.Next()
+	}
+}
+
+// watchRequest membatalkan request saat server berhenti atau koneksi client tertutup
+func watchRequest(conn net.Conn, serverDone <-chan struct{}, stop <-chan struct{}, cancel context.CancelFunc) {
+	ticker := time.NewTicker(connCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-serverDone:
+			cancel()
+			return
+		case <-ticker.C:
+			if connClosed(conn) {
+				cancel()
+				return
+			}
+		}
+	}
+}
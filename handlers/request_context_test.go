@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// newContextTestServer menjalankan app dengan RequestContext di socket sungguhan; handler /wait
+// melapor saat mulai lalu menunggu sampai context request dibatalkan
+func newContextTestServer(t *testing.T) (*fiber.App, string, chan struct{}, chan error) {
+	t.Helper()
+	started := make(chan struct{}, 1)
+	cancelled := make(chan error, 1)
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(RequestContext())
+	app.Get("/wait", func(c *fiber.Ctx) error {
+		started <- struct{}{}
+		select {
+		case <-c.UserContext().Done():
+			cancelled <- c.UserContext().Err()
+		case <-time.After(5 * time.Second):
+			cancelled <- nil
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(ln)
+	t.Cleanup(func() { app.Shutdown() })
+	return app, ln.Addr().String(), started, cancelled
+}
+
+func startWaitRequest(t *testing.T, addr string, started chan struct{}) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write([]byte("GET /wait HTTP/1.1\r\nHost: test\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler did not start")
+	}
+	return conn
+}
+
+func expectCancelled(t *testing.T, cancelled chan error) {
+	t.Helper()
+	select {
+	case err := <-cancelled:
+		if err == nil {
+			t.Fatal("request context was not cancelled")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("request context was not cancelled")
+	}
+}
+
+func TestRequestContextCancelledOnDisconnect(t *testing.T) {
+	_, addr, started, cancelled := newContextTestServer(t)
+
+	conn := startWaitRequest(t, addr, started)
+	conn.Close()
+	expectCancelled(t, cancelled)
+}
+
+func TestRequestContextCancelledOnShutdown(t *testing.T) {
+	app, addr, started, cancelled := newContextTestServer(t)
+
+	conn := startWaitRequest(t, addr, started)
+	defer conn.Close()
+	go app.ShutdownWithTimeout(3 * time.Second)
+	expectCancelled(t, cancelled)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"police-assistant-backend/models"
 	"police-assistant-backend/services"
@@ -43,7 +44,15 @@ func (h *RouteHandler) GetRoutes(c *fiber.Ctx) error {
 	log.Printf("🗺️  Finding routes from '%s' to '%s'", req.Origin, req.Destination)
 
 	// Get alternative routes with traffic from OpenRouteService
-	routes, err := h.orsService.GetAlternativeRoutes(req.Origin, req.Destination)
+	routes, err := h.orsService.GetAlternativeRoutes(c.UserContext(), req.Origin, req.Destination)
+	if errors.Is(err, services.ErrUpstreamUnavailable) {
+		log.Printf("🔴 ORS unavailable: %v", err)
+		setUpstreamRetryAfter(c)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(models.RouteResponse{
+			Success: false,
+			Error:   services.MapsFallbackReply,
+		})
+	}
 	if err != nil {
 		log.Printf("❌ Failed to get routes: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(models.RouteResponse{
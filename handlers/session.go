@@ -4,12 +4,13 @@ import (
 	"log"
 	"police-assistant-backend/models"
 	"police-assistant-backend/services"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type SessionHandler struct {
-	sessionStore *services.SessionStore
+	sessionStore services.SessionStore
 }
 
 func NewSessionHandler() *SessionHandler {
@@ -20,13 +21,21 @@ func NewSessionHandler() *SessionHandler {
 
 // CreateSession membuat session baru
 func (h *SessionHandler) CreateSession(c *fiber.Ctx) error {
-	sessionID := h.sessionStore.CreateSession()
+	sessionID, token, err := h.sessionStore.CreateSession()
+	if err != nil {
+		log.Printf("❌ %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(models.SessionResponse{
+			Success: false,
+			Error:   "Failed to create session",
+		})
+	}
 	log.Printf("🆕 New session created: %s", sessionID)
 
 	return c.JSON(models.SessionResponse{
-		Success:   true,
-		SessionID: sessionID,
-		Message:   "Session created successfully",
+		Success:      true,
+		SessionID:    sessionID,
+		SessionToken: token,
+		Message:      "Session created successfully",
 	})
 }
 
@@ -98,3 +107,53 @@ func (h *SessionHandler) GetSessionInfo(c *fiber.Ctx) error {
 		"updated_at":    session.UpdatedAt,
 	})
 }
+
+// GetTranscript handles GET /api/v1/session/:session_id/transcript?format=json|md|pdf
+// Ekspor percakapan lengkap beserta metadata untuk tim support
+func (h *SessionHandler) GetTranscript(c *fiber.Ctx) error {
+	sessionID := c.Params("session_id")
+	format := strings.ToLower(c.Query("format", services.TranscriptJSON))
+
+	session, exists := h.sessionStore.GetSession(sessionID)
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(models.TranscriptResponse{
+			Success: false,
+			Error:   "Session not found",
+		})
+	}
+
+	transcript := services.BuildTranscript(session)
+	filename := "transcript-" + sessionID
+
+	switch format {
+	case services.TranscriptJSON:
+		return c.JSON(models.TranscriptResponse{
+			Success:    true,
+			Transcript: transcript,
+		})
+
+	case services.TranscriptMarkdown:
+		c.Set(fiber.HeaderContentType, "text/markdown; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`.md"`)
+		return c.SendString(services.RenderTranscriptMarkdown(transcript))
+
+	case services.TranscriptPDF:
+		data, err := services.RenderTranscriptPDF(transcript)
+		if err != nil {
+			log.Printf("❌ Failed to export transcript %s: %v", sessionID, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(models.TranscriptResponse{
+				Success: false,
+				Error:   "Failed to render transcript",
+			})
+		}
+		c.Set(fiber.HeaderContentType, "application/pdf")
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`.pdf"`)
+		return c.Send(data)
+
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(models.TranscriptResponse{
+			Success: false,
+			Error:   "Unsupported format, use json, md, or pdf",
+		})
+	}
+}
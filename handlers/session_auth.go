@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"police-assistant-backend/config"
+	"police-assistant-backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Header otorisasi session
+const (
+	HeaderSessionToken = "X-Session-Token" // Token pemilik session dari CreateSession
+	HeaderAdminKey     = "X-Admin-Key"     // ADMIN_API_KEY untuk akses session hanya dengan ID
+)
+
+var (
+	errSessionTokenRequired = errors.New("session token is required")
+	errInvalidSessionToken  = errors.New("invalid session token")
+)
+
+// isAdmin mengecek apakah request membawa ADMIN_API_KEY yang valid; admin nonaktif jika key kosong
+func isAdmin(c *fiber.Ctx) bool {
+	adminKey := config.AppConfig.AdminAPIKey
+	provided := c.Get(HeaderAdminKey)
+	if adminKey == "" || provided == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(adminKey), []byte(provided)) == 1
+}
+
+// authorizeSession memastikan pemanggil adalah pemilik session (token cocok) atau admin.
+// Mengembalikan status HTTP dan error jika akses ditolak.
+func authorizeSession(c *fiber.Ctx, sessionStore services.SessionStore, sessionID string, token string) (int, error) {
+	if isAdmin(c) {
+		if _, exists := sessionStore.GetSession(sessionID); !exists {
+			return fiber.StatusNotFound, services.ErrSessionNotFound
+		}
+		log.Printf("🛡️  Admin access to session %s (%s %s)", sessionID, c.Method(), c.Path())
+		return 0, nil
+	}
+
+	if token == "" {
+		return fiber.StatusUnauthorized, errSessionTokenRequired
+	}
+	if !sessionStore.VerifyToken(sessionID, token) {
+		if _, exists := sessionStore.GetSession(sessionID); !exists {
+			return fiber.StatusNotFound, services.ErrSessionNotFound
+		}
+		return fiber.StatusForbidden, errInvalidSessionToken
+	}
+	return 0, nil
+}
+
+// RequireSessionAccess adalah middleware untuk route dengan parameter :session_id
+func RequireSessionAccess() fiber.Handler {
+	sessionStore := services.GetSessionStore()
+
+	return func(c *fiber.Ctx) error {
+		sessionID := c.Params("session_id")
+
+		status, err := authorizeSession(c, sessionStore, sessionID, c.Get(HeaderSessionToken))
+		if err != nil {
+			log.Printf("🔒 Session access denied (session: %s, %s %s): %v", sessionID, c.Method(), c.Path(), err)
+			return c.Status(status).JSON(fiber.Map{
+				"success":    false,
+				"session_id": sessionID,
+				"error":      err.Error(),
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"police-assistant-backend/models"
 	"police-assistant-backend/services"
@@ -43,7 +44,15 @@ func (h *TrafficHandler) GetTraffic(c *fiber.Ctx) error {
 	log.Printf("🚦 Getting traffic info for: %.6f, %.6f", req.Latitude, req.Longitude)
 
 	// Get traffic information from Google Maps
-	traffic, err := h.mapsService.GetTrafficInfo(req.Latitude, req.Longitude)
+	traffic, err := h.mapsService.GetTrafficInfo(c.UserContext(), req.Latitude, req.Longitude)
+	if errors.Is(err, services.ErrUpstreamUnavailable) {
+		log.Printf("🔴 ORS unavailable: %v", err)
+		setUpstreamRetryAfter(c)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(models.TrafficResponse{
+			Success: false,
+			Error:   services.MapsFallbackReply,
+		})
+	}
 	if err != nil {
 		log.Printf("❌ Failed to get traffic: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(models.TrafficResponse{
@@ -2,9 +2,13 @@ package main
 
 import (
 	"log"
+	"os"
+	"os/signal"
 	"police-assistant-backend/config"
 	"police-assistant-backend/handlers"
 	"police-assistant-backend/services"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -29,23 +33,56 @@ func main() {
 	// Initialize services
 	log.Println("🔧 Initializing services...")
 	rulesService := services.NewRulesService()
-	openaiService := services.NewOpenAIService(rulesService)
 	orsService := services.NewORSService()
-	etilangService := services.NewETilangService()
+	auditLog := services.NewAuditLog(config.AppConfig.AuditLogPath)
+	paymentStore, err := services.NewPaymentStore(config.AppConfig.PaymentDBPath)
+	if err != nil {
+		log.Fatalf("❌ Failed to open payment database %s: %v", config.AppConfig.PaymentDBPath, err)
+	}
+	etilangService := services.NewETilangService(services.NewETilangProvider(), auditLog, paymentStore)
+	var paymentService *services.PaymentService
+	if config.AppConfig.PaymentProvider != "" {
+		paymentProvider, err := services.NewPaymentProvider()
+		if err != nil {
+			log.Fatalf("❌ Failed to create payment provider: %v", err)
+		}
+		paymentService = services.NewPaymentService(etilangService, paymentStore, paymentProvider, auditLog)
+	}
 	pelayananService := services.NewPelayananService()
-	simFlowService := services.NewSIMFlowService()
+	var chatTools *services.ChatTools
+	if config.AppConfig.LLMTools {
+		chatTools = services.NewChatTools(etilangService, pelayananService, orsService)
+	}
+	promptService, err := services.NewPromptService(config.AppConfig.PromptsDir, config.AppConfig.PromptsHotReload)
+	if err != nil {
+		log.Fatalf("❌ Failed to load prompt templates from %s: %v", config.AppConfig.PromptsDir, err)
+	}
+	openaiService := services.NewOpenAIService(rulesService, promptService, services.NewChatModel(), chatTools)
+	fileStore := services.NewFileStore()
+	actionRegistry := services.NewActionRegistry(fileStore)
+	uploadService := services.NewUploadService(fileStore)
+	flowService := services.NewFlowService(actionRegistry, uploadService)
+	historyService := services.NewHistoryService(openaiService)
 
 	// Initialize handlers
-	chatHandler := handlers.NewChatHandler(openaiService, orsService, etilangService, pelayananService, simFlowService)
+	chatHandler := handlers.NewChatHandler(openaiService, orsService, etilangService, pelayananService, flowService, historyService)
 	trafficHandler := handlers.NewTrafficHandler(orsService)
 	routeHandler := handlers.NewRouteHandler(orsService)
 	sessionHandler := handlers.NewSessionHandler()
+	fileHandler := handlers.NewFileHandler(fileStore)
+	flowHandler := handlers.NewFlowHandler(flowService)
+	regulationHandler := handlers.NewRegulationHandler()
 
 	// Create Fiber app with config
 	app := fiber.New(fiber.Config{
 		AppName:      "Police Assistant API v1.0",
 		ServerHeader: "Fiber",
 		ErrorHandler: customErrorHandler,
+		ProxyHeader:  config.AppConfig.ProxyHeader, // Real client IP behind nginx/traefik, used by ownership lockout
+		// The proxy header is only honoured from TRUSTED_PROXIES, otherwise any client could pick its own IP
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          config.AppConfig.TrustedProxies,
+		EnableIPValidation:      true,
 	})
 
 	// Middleware
@@ -57,31 +94,42 @@ func main() {
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization, " + handlers.HeaderSessionToken + ", " + handlers.HeaderAdminKey,
 	}))
+	app.Use(handlers.RequestContext()) // Batalkan panggilan upstream saat client putus atau server berhenti
 
 	// Root endpoint
+	endpoints := fiber.Map{
+		"health":      "/health",
+		"chat":        "/api/v1/chat",
+		"chat_stream": "/api/v1/chat/stream",
+		"session":     "/api/v1/session",
+		"traffic":     "/api/v1/traffic",
+		"routes":      "/api/v1/routes",
+		"files":       "/api/v1/files/:file_id",
+		"flow":        "/api/v1/session/:session_id/flow",
+		"transcript":  "/api/v1/session/:session_id/transcript",
+		"regulations": "/api/v1/regulations?q=",
+	}
+	if paymentService != nil {
+		endpoints["payments"] = "/api/v1/etilang/:plate/payments"
+	}
 	app.Get("/", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
-			"status":  "ok",
-			"message": "🚓 AI Police Assistant API is running",
-			"version": "1.0.0",
-			"endpoints": fiber.Map{
-				"health":  "/health",
-				"chat":    "/api/v1/chat",
-				"session": "/api/v1/session",
-				"traffic": "/api/v1/traffic",
-				"routes":  "/api/v1/routes",
-			},
+			"status":    "ok",
+			"message":   "🚓 AI Police Assistant API is running",
+			"version":   "1.0.0",
+			"endpoints": endpoints,
 		})
 	})
 
 	// Health check endpoint
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
-			"status":  "healthy",
-			"service": "police-assistant-api",
-			"uptime":  "running",
+			"status":       "healthy",
+			"service":      "police-assistant-api",
+			"uptime":       "running",
+			"dependencies": services.UpstreamStates(), // Status circuit breaker: closed, open, half_open
 		})
 	})
 
@@ -90,12 +138,22 @@ func main() {
 
 	// Chat endpoints
 	api.Post("/chat", chatHandler.HandleChat)
-
-	// Session management endpoints
-	api.Post("/session", sessionHandler.CreateSession)                  // Buat session baru
-	api.Delete("/session/:session_id", sessionHandler.DeleteSession)    // Hapus session
-	api.Post("/session/:session_id/clear", sessionHandler.ClearSession) // Clear history
-	api.Get("/session/:session_id", sessionHandler.GetSessionInfo)      // Info session (debug)
+	api.Post("/chat/stream", chatHandler.HandleChatStream) // Balasan bertahap via Server-Sent Events
+
+	// Session management endpoints; selain create, wajib header X-Session-Token (atau X-Admin-Key)
+	sessionAccess := handlers.RequireSessionAccess()
+	api.Post("/session", sessionHandler.CreateSession)                                      // Buat session baru + token
+	api.Delete("/session/:session_id", sessionAccess, sessionHandler.DeleteSession)         // Hapus session
+	api.Post("/session/:session_id/clear", sessionAccess, sessionHandler.ClearSession)      // Clear history
+	api.Get("/session/:session_id", sessionAccess, sessionHandler.GetSessionInfo)           // Info session (debug)
+	api.Get("/session/:session_id/transcript", sessionAccess, sessionHandler.GetTranscript) // Ekspor percakapan (json, md, pdf)
+
+	// Flow navigation endpoints (tombol kembali/batal di frontend)
+	api.Get("/session/:session_id/flow", sessionAccess, flowHandler.GetFlowState)         // Posisi flow aktif
+	api.Post("/session/:session_id/flow/back", sessionAccess, flowHandler.BackFlow)       // Kembali ke node sebelumnya
+	api.Post("/session/:session_id/flow/restart", sessionAccess, flowHandler.RestartFlow) // Ulang dari entry_node
+	api.Post("/session/:session_id/flow/cancel", sessionAccess, flowHandler.CancelFlow)   // Batalkan flow
+	api.Post("/session/:session_id/flow/resume", sessionAccess, flowHandler.ResumeFlow)   // Lanjutkan flow yang dibatalkan
 
 	// Traffic endpoints
 	api.Get("/traffic", trafficHandler.GetTraffic)
@@ -103,6 +161,20 @@ func main() {
 	// Route endpoints
 	api.Post("/routes", routeHandler.GetRoutes)
 
+	// File endpoints (download berkas hasil flow); link bertanda tangan dan berbatas waktu dari flow
+	api.Get("/files/:file_id", fileHandler.DownloadFile)
+
+	// Regulation endpoints (katalog pasal UU 22/2009 beserta denda maksimal)
+	api.Get("/regulations", regulationHandler.SearchRegulations)
+
+	// Payment endpoints (bayar denda e-tilang via virtual account); hanya jika PAYMENT_PROVIDER diset
+	if paymentService != nil {
+		paymentHandler := handlers.NewPaymentHandler(paymentService)
+		api.Post("/etilang/:plate/payments", paymentHandler.CreatePayment)                             // Buat tagihan; wajib X-Session-Token + verifikasi kepemilikan
+		api.Post("/payments/webhook", paymentHandler.HandleWebhook)                                    // Callback provider, wajib header X-Callback-Signature
+		api.Get("/session/:session_id/payments/:payment_id", sessionAccess, paymentHandler.GetPayment) // Status tagihan milik session
+	}
+
 	// 404 handler
 	app.Use(func(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -118,11 +190,25 @@ func main() {
 	log.Printf("💚 Health Check: http://localhost%s/health", port)
 	log.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
+	// Graceful shutdown: SIGTERM cancels in-flight request contexts and waits for them to finish
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+		<-quit
+		log.Println("🛑 Shutting down server...")
+		if err := app.ShutdownWithTimeout(shutdownTimeout); err != nil {
+			log.Printf("⚠️  Shutdown did not finish cleanly: %v", err)
+		}
+	}()
+
 	if err := app.Listen(port); err != nil {
 		log.Fatalf("❌ Failed to start server: %v", err)
 	}
 }
 
+// Batas waktu menunggu request yang sedang berjalan saat server berhenti
+const shutdownTimeout = 10 * time.Second
+
 // Custom error handler
 func customErrorHandler(c *fiber.Ctx, err error) error {
 	code := fiber.StatusInternalServerError
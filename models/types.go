@@ -1,45 +1,153 @@
 package models
 
+import "time"
+
 // Request & Response structures for Chat
 type ChatRequest struct {
-	Message   string             `json:"message" validate:"required"`
-	Name      string             `json:"name,omitempty"` // Nama user (opsional)
-	Context   Context            `json:"context"`
-	SessionID string             `json:"session_id,omitempty"` // Session ID untuk backend-managed history
-	History   []OpenAIMessage    `json:"history,omitempty"`    // Optional: untuk backward compatibility
-	Documents []UploadedDocument `json:"documents,omitempty"`  // Dokumen yang diupload (base64 atau URL)
+	Message      string             `json:"message" validate:"required"`
+	Name         string             `json:"name,omitempty"` // Nama user (opsional)
+	Context      Context            `json:"context"`
+	SessionID    string             `json:"session_id,omitempty"`    // Session ID untuk backend-managed history
+	SessionToken string             `json:"session_token,omitempty"` // Token pemilik session (alternatif header X-Session-Token)
+	History      []OpenAIMessage    `json:"history,omitempty"`       // Optional: untuk backward compatibility
+	Documents    []UploadedDocument `json:"documents,omitempty"`     // Dokumen yang diupload (base64 atau URL)
+	ChoiceID     string             `json:"choice_id,omitempty"`     // Pilihan flow dari tombol (tanpa pencocokan teks)
+	NodeID       string             `json:"node_id,omitempty"`       // Node flow yang dilihat frontend, untuk deteksi konflik
 }
 
 type Context struct {
-	Name                  string         `json:"name,omitempty"` // Nama user
-	Location              string         `json:"location"`
-	Speed                 float64        `json:"speed"`
-	Traffic               string         `json:"traffic"`
-	Latitude              float64        `json:"latitude"`
-	Longitude             float64        `json:"longitude"`
-	ETilangInfo           *ETilangInfo   `json:"e_tilang_info,omitempty"`  // Info tilang jika dicek
-	PelayananInfo         *PelayananInfo `json:"pelayanan_info,omitempty"` // Info pelayanan jika ditanyakan
-	SIMFlowInfo           *SIMFlowInfo   `json:"sim_flow_info,omitempty"`  // Info flow SIM jika aktif
-	HasUploadedDocuments  bool           `json:"has_uploaded_documents"`   // Flag jika user upload dokumen
-	UploadedDocumentCount int            `json:"uploaded_document_count"`  // Jumlah dokumen yang diupload
+	Name                  string            `json:"name,omitempty"` // Nama user
+	Location              string            `json:"location"`
+	Speed                 float64           `json:"speed"`
+	Traffic               string            `json:"traffic"`
+	Latitude              float64           `json:"latitude"`
+	Longitude             float64           `json:"longitude"`
+	ETilangInfo           *ETilangInfo      `json:"e_tilang_info,omitempty"`    // Info tilang jika dicek
+	PelayananInfo         *PelayananInfo    `json:"pelayanan_info,omitempty"`   // Info pelayanan jika ditanyakan
+	FlowInfo              *FlowInfo         `json:"flow_info,omitempty"`        // Info flow layanan jika aktif
+	HasUploadedDocuments  bool              `json:"has_uploaded_documents"`     // Flag jika user upload dokumen
+	UploadedDocumentCount int               `json:"uploaded_document_count"`    // Jumlah dokumen yang diupload
+	UploadRejection       *UploadRejection  `json:"upload_rejection,omitempty"` // Alasan jika dokumen ditolak
+	ConversationSummary   string            `json:"-"`                          // Ringkasan percakapan lama yang sudah dipadatkan
+	PinnedFacts           map[string]string `json:"-"`                          // Fakta penting session (nama, nomor polisi, layanan)
+	SessionID             string            `json:"-"`                          // Session yang sedang dilayani (untuk tool dan audit)
+	ClientIP              string            `json:"-"`                          // IP pengirim request (batas percobaan verifikasi kepemilikan)
+
+	// Hasil tool calling pada giliran ini (diisi OpenAIService, bukan dari request)
+	Routes      []map[string]interface{} `json:"-"` // Rute alternatif dari get_alternative_routes
+	TrafficInfo map[string]interface{}   `json:"-"` // Kondisi lalu lintas dari get_traffic_info
+	ToolsUsed   []string                 `json:"-"` // Nama tool yang dipanggil model, berurutan
+	Regulations []Regulation             `json:"-"` // Pasal UU LLAJ yang ditanyakan (search_regulations atau kata kunci)
+
+	PromptVersion string `json:"-"` // Versi template prompt yang dipakai LLM (diisi OpenAIService)
 }
 
 type ChatResponse struct {
-	Success       bool           `json:"success"`
-	Response      string         `json:"response"`
-	SessionID     string         `json:"session_id,omitempty"`     // Return session ID ke frontend
-	ETilangInfo   *ETilangInfo   `json:"e_tilang_info,omitempty"`  // Info tilang jika dicek
-	PelayananInfo *PelayananInfo `json:"pelayanan_info,omitempty"` // Info pelayanan jika ditanyakan
-	SIMFlowInfo   *SIMFlowInfo   `json:"sim_flow_info,omitempty"`  // Info flow SIM jika aktif
-	Error         string         `json:"error,omitempty"`
+	Success         bool                     `json:"success"`
+	Response        string                   `json:"response"`
+	SessionID       string                   `json:"session_id,omitempty"`       // Return session ID ke frontend
+	SessionToken    string                   `json:"session_token,omitempty"`    // Token pemilik, hanya dikirim saat session baru dibuat
+	ETilangInfo     *ETilangInfo             `json:"e_tilang_info,omitempty"`    // Info tilang jika dicek
+	PelayananInfo   *PelayananInfo           `json:"pelayanan_info,omitempty"`   // Info pelayanan jika ditanyakan
+	FlowInfo        *FlowInfo                `json:"flow_info,omitempty"`        // Info flow layanan jika aktif
+	UploadRejection *UploadRejection         `json:"upload_rejection,omitempty"` // Alasan jika dokumen ditolak
+	Routes          []map[string]interface{} `json:"routes,omitempty"`           // Rute alternatif jika model memanggil tool rute
+	TrafficInfo     map[string]interface{}   `json:"traffic_info,omitempty"`     // Kondisi lalu lintas jika model memanggil tool traffic
+	Regulations     []Regulation             `json:"regulations,omitempty"`      // Pasal UU LLAJ jika user menanyakan denda/sanksi
+	PromptVersion   string                   `json:"prompt_version,omitempty"`   // Versi template prompt, kosong untuk balasan flow deterministik
+	Error           string                   `json:"error,omitempty"`
+}
+
+// Event Server-Sent Events untuk POST /api/v1/chat/stream (metadata → delta... → done)
+type ChatStreamMetadata struct {
+	SessionID       string           `json:"session_id"`
+	SessionToken    string           `json:"session_token,omitempty"` // Hanya saat session baru dibuat
+	ETilangInfo     *ETilangInfo     `json:"e_tilang_info,omitempty"`
+	PelayananInfo   *PelayananInfo   `json:"pelayanan_info,omitempty"`
+	FlowInfo        *FlowInfo        `json:"flow_info,omitempty"`
+	UploadRejection *UploadRejection `json:"upload_rejection,omitempty"`
+}
+
+type ChatStreamDelta struct {
+	Text string `json:"text"`
+}
+
+type ChatStreamDone struct {
+	SessionID     string `json:"session_id"`
+	Response      string `json:"response"`     // Teks lengkap yang disimpan ke history
+	ReplySource   string `json:"reply_source"` // deterministic atau llm
+	PromptVersion string `json:"prompt_version,omitempty"`
+	Error         string `json:"error,omitempty"`
+
+	// Data yang diambil model lewat tool calling selama stream (metadata dikirim sebelum tool dipanggil)
+	ETilangInfo   *ETilangInfo             `json:"e_tilang_info,omitempty"`
+	PelayananInfo *PelayananInfo           `json:"pelayanan_info,omitempty"`
+	Routes        []map[string]interface{} `json:"routes,omitempty"`
+	TrafficInfo   map[string]interface{}   `json:"traffic_info,omitempty"`
+	Regulations   []Regulation             `json:"regulations,omitempty"`
 }
 
 // Session structures
 type SessionResponse struct {
-	Success   bool   `json:"success"`
-	SessionID string `json:"session_id"`
-	Message   string `json:"message,omitempty"`
-	Error     string `json:"error,omitempty"`
+	Success      bool   `json:"success"`
+	SessionID    string `json:"session_id"`
+	SessionToken string `json:"session_token,omitempty"` // Simpan di frontend, wajib dikirim lewat header X-Session-Token
+	Message      string `json:"message,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// SessionMessage adalah satu pesan di history session beserta waktu dan metadata giliran
+type SessionMessage struct {
+	Role      string       `json:"role"`
+	Content   string       `json:"content"`
+	Timestamp time.Time    `json:"timestamp"`
+	Meta      *MessageMeta `json:"meta,omitempty"`
+}
+
+// MessageMeta mencatat apa yang dilihat asisten saat menjawab, untuk transcript/audit
+type MessageMeta struct {
+	Model         string         `json:"model,omitempty"`          // Model LLM yang menjawab (kosong untuk balasan flow deterministik)
+	PromptVersion string         `json:"prompt_version,omitempty"` // Versi template prompt yang dipakai model
+	ReplySource   string         `json:"reply_source,omitempty"`   // deterministic atau llm
+	FlowID        string         `json:"flow_id,omitempty"`
+	FlowNode      string         `json:"flow_node,omitempty"`  // Node flow setelah giliran ini
+	ChoiceID      string         `json:"choice_id,omitempty"`  // Pilihan tombol yang dikirim user
+	Aborted       bool           `json:"aborted,omitempty"`    // Stream terputus sebelum balasan selesai; Content berisi teks parsial
+	ToolCalls     []string       `json:"tool_calls,omitempty"` // Tool yang dipanggil model untuk balasan ini
+	ETilangInfo   *ETilangInfo   `json:"e_tilang_info,omitempty"`
+	PelayananInfo *PelayananInfo `json:"pelayanan_info,omitempty"`
+}
+
+// Transcript adalah ekspor percakapan satu session untuk tim support
+type Transcript struct {
+	SessionID  string               `json:"session_id"`
+	CreatedAt  time.Time            `json:"created_at"`
+	UpdatedAt  time.Time            `json:"updated_at"`
+	ExportedAt time.Time            `json:"exported_at"`
+	Models     []string             `json:"models"`            // Model LLM yang dipakai selama percakapan
+	Summary    string               `json:"summary,omitempty"` // Ringkasan berjalan dari HistoryService
+	FlowPath   []TranscriptFlowStep `json:"flow_path"`         // Node flow yang dikunjungi, berurutan
+	Messages   []SessionMessage     `json:"messages"`
+}
+
+type TranscriptFlowStep struct {
+	FlowID    string    `json:"flow_id"`
+	NodeID    string    `json:"node_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type TranscriptResponse struct {
+	Success    bool        `json:"success"`
+	Transcript *Transcript `json:"transcript,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+type FlowStateResponse struct {
+	Success   bool      `json:"success"`
+	SessionID string    `json:"session_id"`
+	FlowInfo  *FlowInfo `json:"flow_info,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Error     string    `json:"error,omitempty"`
 }
 
 // Traffic structures
@@ -68,8 +176,17 @@ type RouteResponse struct {
 
 // OpenAI API structures
 type OpenAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // Permintaan tool dari asisten
+	ToolCallID string     `json:"tool_call_id,omitempty"` // Untuk role "tool": ID tool call yang dijawab
+}
+
+// ToolCall adalah satu pemanggilan tool yang diminta model
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // Argumen dalam JSON
 }
 
 type OpenAIRequest struct {
@@ -101,22 +218,91 @@ type OpenAIResponse struct {
 
 // E-Tilang structures
 type ETilangViolation struct {
+	ID          string `json:"id"` // Nomor tilang, dipakai untuk memilih pelanggaran yang dibayar
 	Date        string `json:"date"`
 	Violation   string `json:"violation"`
 	Location    string `json:"location"`
 	Fine        int    `json:"fine"`
 	OfficerName string `json:"officer_name"`
-	Status      string `json:"status"` // "unpaid", "paid", "processed"
+	Status      string `json:"status"` // "unpaid", "paid", "processed" (menunggu pembayaran virtual account)
+
+	ArticleCode string      `json:"article_code,omitempty"` // Kode pasal UU 22/2009, misal "287(2)"
+	Article     *Regulation `json:"article,omitempty"`      // Detail pasal dari katalog regulasi
 }
 
 type ETilangInfo struct {
 	PlateNumber   string             `json:"plate_number"`
+	Found         bool               `json:"found"`            // false = nomor polisi tidak terdaftar, bukan berarti bebas pelanggaran
+	Region        string             `json:"region,omitempty"` // Wilayah registrasi dari kode wilayah plat
+	Polda         string             `json:"polda,omitempty"`  // Polda wilayah registrasi
 	ChassisNumber string             `json:"chassis_number"`
+	EngineNumber  string             `json:"engine_number,omitempty"`
 	OwnerName     string             `json:"owner_name"`
 	VehicleType   string             `json:"vehicle_type"`
 	HasViolation  bool               `json:"has_violation"`
 	Violations    []ETilangViolation `json:"violations,omitempty"`
-	TotalFine     int                `json:"total_fine"`
+	TotalFine     int                `json:"total_fine"` // Total denda yang belum dibayar
+
+	// Data pemilik (nama, nomor rangka, nomor mesin, jenis kendaraan) disamarkan sampai
+	// user membuktikan kepemilikan di session ini
+	Verified            bool   `json:"verified"`
+	VerificationMessage string `json:"verification_message,omitempty"` // Hasil verifikasi yang gagal, untuk disampaikan ke user
+}
+
+// Regulation adalah satu pasal ketentuan pidana UU 22/2009 tentang Lalu Lintas dan Angkutan Jalan
+type Regulation struct {
+	Code         string `json:"code"`          // Nomor pasal dan ayat, misal "287(2)"
+	Law          string `json:"law"`           // "UU 22/2009"
+	Article      string `json:"article"`       // "Pasal 287 ayat (2)"
+	Title        string `json:"title"`         // Pelanggaran singkat, misal "Menerobos lampu merah"
+	Description  string `json:"description"`   // Bunyi ketentuan yang diringkas
+	MaxDetention string `json:"max_detention"` // Pidana kurungan maksimal, misal "2 bulan"
+	MaxFine      int    `json:"max_fine"`      // Denda maksimal (Rupiah)
+}
+
+type RegulationResponse struct {
+	Success     bool         `json:"success"`
+	Query       string       `json:"query,omitempty"`
+	Regulations []Regulation `json:"regulations"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// Payment adalah tagihan denda e-tilang yang dibayar lewat virtual account
+type Payment struct {
+	ID             string     `json:"id"`
+	SessionID      string     `json:"session_id,omitempty"` // Session yang membuat tagihan (sudah verifikasi kepemilikan)
+	PlateNumber    string     `json:"plate_number"`
+	ViolationIDs   []string   `json:"violation_ids"`
+	Amount         int        `json:"amount"`
+	Status         string     `json:"status"` // "pending", "paid", "expired", "failed", "refund_required"
+	Provider       string     `json:"provider"`
+	Bank           string     `json:"bank,omitempty"`
+	VirtualAccount string     `json:"virtual_account,omitempty"` // Nomor VA (format BRIVA: kode perusahaan + nomor pelanggan)
+	ProviderRef    string     `json:"provider_ref,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	PaidAt         *time.Time `json:"paid_at,omitempty"`
+}
+
+type PaymentRequest struct {
+	SessionID    string   `json:"session_id"`    // Session yang sudah verifikasi kepemilikan kendaraan; token di header X-Session-Token
+	ViolationIDs []string `json:"violation_ids"` // Kosong = semua pelanggaran yang belum dibayar
+}
+
+type PaymentResponse struct {
+	Success bool     `json:"success"`
+	Payment *Payment `json:"payment,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// PaymentCallback adalah notifikasi dari payment provider ke webhook, ditandatangani HMAC-SHA256
+type PaymentCallback struct {
+	PaymentID      string    `json:"payment_id"`
+	VirtualAccount string    `json:"virtual_account"`
+	Status         string    `json:"status"` // "paid", "expired", "failed"
+	Amount         int       `json:"amount"`
+	PaidAt         time.Time `json:"paid_at"`
+	Reference      string    `json:"reference"`
 }
 
 // Pelayanan structures (NEW FORMAT with flows)
@@ -158,16 +344,39 @@ type UploadedDocument struct {
 	Description string `json:"description"` // Optional: "KTP", "Surat Kehilangan", etc.
 }
 
-// SIM Flow structures
-type SIMFlowInfo struct {
-	Active      bool            `json:"active"`
-	CurrentNode string          `json:"current_node"`
-	NodeType    string          `json:"node_type"`
-	NodeText    string          `json:"node_text"`
-	Choices     []SIMFlowChoice `json:"choices,omitempty"`
+// UploadRejection menjelaskan kenapa dokumen yang diupload ditolak
+type UploadRejection struct {
+	FileName     string   `json:"file_name"`
+	Code         string   `json:"code"`   // "mime_not_allowed", "file_too_large", "invalid_base64", ...
+	Reason       string   `json:"reason"` // Pesan untuk user
+	AllowedTypes []string `json:"allowed_types,omitempty"`
+	MaxSizeBytes int64    `json:"max_size_bytes,omitempty"`
+}
+
+// Flow structures (generic untuk semua alur layanan: SIM, STNK, balik nama, mutasi, ...)
+type FlowInfo struct {
+	FlowID      string                 `json:"flow_id"`
+	Title       string                 `json:"title,omitempty"`
+	Active      bool                   `json:"active"`            // false jika flow sudah sampai node akhir atau dibatalkan
+	CanGoBack   bool                   `json:"can_go_back"`       // true jika ada node sebelumnya untuk perintah "kembali"
+	Command     string                 `json:"command,omitempty"` // Perintah navigasi yang baru dijalankan (back, restart, cancel, resume)
+	CurrentNode string                 `json:"current_node"`
+	NodeType    string                 `json:"node_type"`
+	NodeText    string                 `json:"node_text"`
+	Messages    []string               `json:"messages,omitempty"` // Teks message node yang dilewati otomatis sebelum node ini, berurutan
+	Choices     []FlowChoiceInfo       `json:"choices,omitempty"`
+	Action      *FlowActionInfo        `json:"action,omitempty"`  // Hasil action node yang baru dijalankan
+	Context     map[string]interface{} `json:"context,omitempty"` // Context flow (sim_type, ever_had_sim, uploads, ...)
+}
+
+type FlowActionInfo struct {
+	Type    string `json:"type"`
+	OK      bool   `json:"ok"`
+	Output  string `json:"output,omitempty"` // Misal URL berkas ZIP atau target handoff
+	Message string `json:"message,omitempty"`
 }
 
-type SIMFlowChoice struct {
+type FlowChoiceInfo struct {
 	ID    string `json:"id"`
 	Label string `json:"label"`
 }
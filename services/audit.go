@@ -0,0 +1,69 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Sumber akses data e-tilang yang dicatat di audit log
+const (
+	AuditSourceTool    = "tool"    // Tool calling oleh model
+	AuditSourceKeyword = "keyword" // Deteksi kata kunci di pesan user
+)
+
+// AuditEntry adalah satu baris audit log (JSON Lines)
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"` // etilang_lookup, ownership_verification, payment_*
+	SessionID string    `json:"session_id,omitempty"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+	Source    string    `json:"source,omitempty"`
+	Subject   string    `json:"subject"` // Nomor polisi yang diakses
+	Result    string    `json:"result"`
+	Detail    string    `json:"detail,omitempty"`
+}
+
+// AuditLog menambahkan catatan akses data pribadi ke file JSON Lines. File hanya
+// ditambah, tidak pernah ditulis ulang.
+type AuditLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewAuditLog(path string) *AuditLog {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Printf("⚠️  Failed to create audit log directory for %s: %v", path, err)
+	}
+	log.Printf("✅ Audit log: %s", path)
+	return &AuditLog{path: path}
+}
+
+// Record menulis satu entri. Kegagalan menulis dicatat di log server tanpa menggagalkan request.
+func (a *AuditLog) Record(entry AuditEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("❌ Failed to encode audit entry: %v", err)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Printf("❌ Failed to open audit log %s: %v", a.path, err)
+		return
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		log.Printf("❌ Failed to write audit log %s: %v", a.path, err)
+	}
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	ctx "context"
+	"fmt"
+	"log"
+	"police-assistant-backend/config"
+	"police-assistant-backend/models"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/openai/openai-go/shared"
+)
+
+// Provider LLM yang didukung (LLM_PROVIDER)
+const (
+	LLMProviderOpenAI     = "openai"            // OpenAI API resmi (OPENAI_API_KEY)
+	LLMProviderCompatible = "openai_compatible" // Server OpenAI-compatible di LLM_BASE_URL (Ollama, vLLM, Azure)
+	LLMProviderStub       = "stub"              // Balasan skrip dari LLM_STUB_FILE, tanpa jaringan
+)
+
+// Tujuan completion; dipakai stub untuk memilih balasan skrip
+const (
+	PurposeChat      = "chat"
+	PurposeClassify  = "classify_choice"
+	PurposeSummarize = "summarize"
+)
+
+// CompletionRequest adalah satu permintaan completion yang sudah berisi prompt lengkap
+type CompletionRequest struct {
+	Purpose      string
+	Messages     []models.OpenAIMessage // Role: system, user, assistant, tool
+	Temperature  float64
+	MaxTokens    int64
+	JSONResponse bool             // Minta balasan berupa objek JSON
+	Tools        []ToolDefinition // Tool yang boleh dipanggil model (kosong = tanpa tool calling)
+
+	FlowNode string // Node flow aktif ("flow_id/node_id"), kosong jika tidak ada flow
+	Rule     string // Jenis pelayanan dari response rules yang dipakai di prompt
+}
+
+// Completion adalah balasan model: teks, atau permintaan pemanggilan tool
+type Completion struct {
+	Content   string
+	ToolCalls []models.ToolCall
+}
+
+// ChatModel adalah backend LLM. OpenAIService menyusun prompt, ChatModel menjalankannya.
+type ChatModel interface {
+	// Name mengembalikan nama model yang dicatat di metadata pesan dan transcript
+	Name() string
+	// Complete menjalankan completion dan mengembalikan seluruh balasan
+	Complete(requestCtx ctx.Context, req CompletionRequest) (Completion, error)
+	// Stream mengirim setiap potongan teks balasan ke onDelta. Teks yang sudah diterima tetap
+	// dikembalikan jika stream terputus atau onDelta mengembalikan error.
+	Stream(requestCtx ctx.Context, req CompletionRequest, onDelta func(delta string) error) (Completion, error)
+}
+
+// NewChatModel membuat ChatModel sesuai LLM_PROVIDER
+func NewChatModel() ChatModel {
+	cfg := config.AppConfig
+
+	switch cfg.LLMProvider {
+	case LLMProviderStub:
+		return NewStubChatModel(cfg.LLMStubFile)
+	case LLMProviderCompatible:
+		opts := []option.RequestOption{option.WithBaseURL(cfg.LLMBaseURL)}
+		if cfg.LLMAPIKey != "" {
+			// Azure OpenAI reads the key from api-key; other servers use the bearer token
+			opts = append(opts, option.WithAPIKey(cfg.LLMAPIKey), option.WithHeader("api-key", cfg.LLMAPIKey))
+		}
+		log.Printf("✅ LLM provider: OpenAI-compatible at %s (model: %s)", cfg.LLMBaseURL, cfg.OpenAIModel)
+		return newOpenAIChatModel("llm", cfg.OpenAIModel, opts...)
+	default:
+		log.Printf("✅ LLM provider: OpenAI (model: %s)", cfg.OpenAIModel)
+		return newOpenAIChatModel("openai", cfg.OpenAIModel, option.WithAPIKey(cfg.OpenAIAPIKey))
+	}
+}
+
+// openAIChatModel menjalankan completion lewat OpenAI SDK, baik ke OpenAI maupun ke
+// server lain yang mengikuti Chat Completions API
+type openAIChatModel struct {
+	client   *openai.Client
+	model    string
+	upstream *Upstream
+}
+
+func newOpenAIChatModel(name string, model string, opts ...option.RequestOption) *openAIChatModel {
+	// Retries are handled by Upstream so that the circuit breaker sees every failure
+	client := openai.NewClient(append(opts, option.WithMaxRetries(0))...)
+	return &openAIChatModel{
+		client: &client,
+		model:  model,
+		upstream: NewUpstream(name, RetryPolicy{
+			Timeout:    config.AppConfig.LLMTimeout,
+			MaxRetries: config.AppConfig.LLMMaxRetries,
+		}),
+	}
+}
+
+func (m *openAIChatModel) Name() string {
+	return m.model
+}
+
+func (m *openAIChatModel) Complete(requestCtx ctx.Context, req CompletionRequest) (Completion, error) {
+	params := m.buildParams(req)
+
+	var completion Completion
+	err := m.upstream.Do(requestCtx, func(attemptCtx ctx.Context) error {
+		response, err := m.client.Chat.Completions.New(attemptCtx, params)
+		if err != nil {
+			return err
+		}
+		if len(response.Choices) > 0 {
+			completion = toCompletion(response.Choices[0].Message)
+		}
+		log.Printf("✅ LLM response received (%s, tokens: %d)", req.Purpose, response.Usage.TotalTokens)
+		return nil
+	})
+	if err != nil {
+		return Completion{}, fmt.Errorf("failed to call LLM API: %w", err)
+	}
+	if completion.Content == "" && len(completion.ToolCalls) == 0 {
+		return Completion{}, fmt.Errorf("empty response from LLM")
+	}
+
+	return completion, nil
+}
+
+func (m *openAIChatModel) Stream(requestCtx ctx.Context, req CompletionRequest, onDelta func(delta string) error) (Completion, error) {
+	params := m.buildParams(req)
+
+	var completion Completion
+	err := m.upstream.Do(requestCtx, func(attemptCtx ctx.Context) error {
+		stream := m.client.Chat.Completions.NewStreaming(attemptCtx, params)
+		defer stream.Close()
+
+		// The accumulator reassembles tool calls, whose arguments arrive in fragments
+		var acc openai.ChatCompletionAccumulator
+		var full strings.Builder
+		for stream.Next() {
+			chunk := stream.Current()
+			acc.AddChunk(chunk)
+			if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+				continue
+			}
+
+			delta := chunk.Choices[0].Delta.Content
+			full.WriteString(delta)
+			completion.Content = full.String()
+			if err := onDelta(delta); err != nil {
+				return noRetry(err)
+			}
+		}
+		if err := stream.Err(); err != nil {
+			// Text already sent to the client cannot be taken back, so only a clean failure is retried
+			if full.Len() > 0 {
+				return noRetry(err)
+			}
+			return err
+		}
+
+		if len(acc.Choices) > 0 {
+			completion.ToolCalls = toCompletion(acc.Choices[0].Message).ToolCalls
+		}
+		return nil
+	})
+	if err != nil {
+		return completion, fmt.Errorf("failed to stream from LLM API: %w", err)
+	}
+	if completion.Content == "" && len(completion.ToolCalls) == 0 {
+		return Completion{}, fmt.Errorf("empty response from LLM")
+	}
+
+	return completion, nil
+}
+
+func toCompletion(message openai.ChatCompletionMessage) Completion {
+	completion := Completion{Content: message.Content}
+	for _, call := range message.ToolCalls {
+		completion.ToolCalls = append(completion.ToolCalls, models.ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+	return completion
+}
+
+// buildParams mengubah CompletionRequest ke format OpenAI SDK
+func (m *openAIChatModel) buildParams(req CompletionRequest) openai.ChatCompletionNewParams {
+	messages := make([]openai.ChatCompletionMessageParamUnion, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case "system":
+			messages = append(messages, openai.SystemMessage(msg.Content))
+		case "user":
+			messages = append(messages, openai.UserMessage(msg.Content))
+		case "assistant":
+			if len(msg.ToolCalls) == 0 {
+				messages = append(messages, openai.AssistantMessage(msg.Content))
+				continue
+			}
+			assistant := openai.ChatCompletionAssistantMessageParam{}
+			if msg.Content != "" {
+				assistant.Content.OfString = openai.String(msg.Content)
+			}
+			for _, call := range msg.ToolCalls {
+				assistant.ToolCalls = append(assistant.ToolCalls, openai.ChatCompletionMessageToolCallParam{
+					ID: call.ID,
+					Function: openai.ChatCompletionMessageToolCallFunctionParam{
+						Name:      call.Name,
+						Arguments: call.Arguments,
+					},
+				})
+			}
+			messages = append(messages, openai.ChatCompletionMessageParamUnion{OfAssistant: &assistant})
+		case "tool":
+			messages = append(messages, openai.ToolMessage(msg.Content, msg.ToolCallID))
+		}
+	}
+
+	params := openai.ChatCompletionNewParams{
+		Model:               openai.ChatModel(m.model),
+		Messages:            messages,
+		Temperature:         openai.Float(req.Temperature),
+		MaxCompletionTokens: openai.Int(req.MaxTokens),
+	}
+	for _, tool := range req.Tools {
+		params.Tools = append(params.Tools, openai.ChatCompletionToolParam{
+			Function: shared.FunctionDefinitionParam{
+				Name:        tool.Name,
+				Description: openai.String(tool.Description),
+				Parameters:  shared.FunctionParameters(tool.Parameters),
+			},
+		})
+	}
+	if req.JSONResponse {
+		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONObject: &shared.ResponseFormatJSONObjectParam{},
+		}
+	}
+	return params
+}
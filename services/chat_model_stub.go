@@ -0,0 +1,143 @@
+package services
+
+import (
+	ctx "context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// StubModelName adalah nama model yang dicatat untuk balasan dari provider stub
+const StubModelName = "stub"
+
+// Balasan bawaan jika file skrip tidak ada atau tidak punya entri yang cocok
+const (
+	stubDefaultReply     = "Halo Sobat Lantas! Ini balasan uji dari asisten (LLM stub). Ada yang bisa saya bantu?"
+	stubClassifyReply    = `{"choice_id": "none", "confidence": 0, "reason": "stub"}`
+	stubSummarizeReply   = "- Ringkasan percakapan sebelumnya (LLM stub)"
+	stubStreamChunkWords = 3
+)
+
+// StubReplies adalah isi file skrip balasan provider stub (LLM_STUB_FILE)
+type StubReplies struct {
+	Default   string            `json:"default"`
+	FlowNodes map[string]string `json:"flow_nodes"` // Key "flow_id/node_id", atau "flow_id" untuk semua node di flow
+	Rules     map[string]string `json:"rules"`      // Key jenis pelayanan dari response rules (cocok sebagian)
+	Purposes  map[string]string `json:"purposes"`   // Key classify_choice atau summarize
+}
+
+// StubChatModel mengembalikan balasan skrip berdasarkan node flow, rule, atau tujuan
+// completion. Dipakai untuk menjalankan service offline dan di CI tanpa API key.
+// Stub tidak pernah memanggil tool.
+type StubChatModel struct {
+	replies StubReplies
+}
+
+// NewStubChatModel memuat file skrip balasan; balasan bawaan dipakai jika file tidak ada
+func NewStubChatModel(path string) *StubChatModel {
+	model := &StubChatModel{}
+
+	if path != "" {
+		file, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("⚠️  WARNING: Failed to load LLM stub file %s: %v", path, err)
+		} else if err := json.Unmarshal(file, &model.replies); err != nil {
+			log.Printf("⚠️  WARNING: Invalid LLM stub file %s: %v", path, err)
+			model.replies = StubReplies{}
+		}
+	}
+
+	log.Printf("✅ LLM provider: stub (%d flow node replies, %d rule replies)", len(model.replies.FlowNodes), len(model.replies.Rules))
+	return model
+}
+
+func (m *StubChatModel) Name() string {
+	return StubModelName
+}
+
+func (m *StubChatModel) Complete(requestCtx ctx.Context, req CompletionRequest) (Completion, error) {
+	if err := requestCtx.Err(); err != nil {
+		return Completion{}, fmt.Errorf("failed to call LLM API: %w", err)
+	}
+	return Completion{Content: m.reply(req)}, nil
+}
+
+// Stream mengirim balasan skrip per beberapa kata agar client bisa menguji SSE
+func (m *StubChatModel) Stream(requestCtx ctx.Context, req CompletionRequest, onDelta func(delta string) error) (Completion, error) {
+	words := strings.SplitAfter(m.reply(req), " ")
+
+	var full strings.Builder
+	for i := 0; i < len(words); i += stubStreamChunkWords {
+		if err := requestCtx.Err(); err != nil {
+			return Completion{Content: full.String()}, fmt.Errorf("failed to stream from LLM API: %w", err)
+		}
+
+		end := min(i+stubStreamChunkWords, len(words))
+		delta := strings.Join(words[i:end], "")
+		full.WriteString(delta)
+		if err := onDelta(delta); err != nil {
+			return Completion{Content: full.String()}, err
+		}
+	}
+
+	return Completion{Content: full.String()}, nil
+}
+
+// reply memilih balasan: node flow, lalu flow, lalu rule, lalu default
+func (m *StubChatModel) reply(req CompletionRequest) string {
+	switch req.Purpose {
+	case PurposeClassify:
+		return m.purposeReply(PurposeClassify, stubClassifyReply)
+	case PurposeSummarize:
+		return m.purposeReply(PurposeSummarize, stubSummarizeReply)
+	}
+
+	if req.FlowNode != "" {
+		if reply, ok := m.replies.FlowNodes[req.FlowNode]; ok {
+			return reply
+		}
+		flowID, _, _ := strings.Cut(req.FlowNode, "/")
+		if reply, ok := m.replies.FlowNodes[flowID]; ok {
+			return reply
+		}
+	}
+
+	if req.Rule != "" {
+		if reply, ok := m.ruleReply(req.Rule); ok {
+			return reply
+		}
+	}
+
+	if m.replies.Default != "" {
+		return m.replies.Default
+	}
+	return stubDefaultReply
+}
+
+// ruleReply memilih key Rules terpanjang yang cocok sebagian dengan rule, agar hasilnya
+// tidak bergantung urutan map (misal "SIM" vs "perpanjangan SIM"). Panjang sama diurutkan abjad.
+func (m *StubChatModel) ruleReply(rule string) (string, bool) {
+	ruleLower := strings.ToLower(rule)
+	bestKey := ""
+	found := false
+	for key := range m.replies.Rules {
+		keyLower := strings.ToLower(key)
+		if !strings.Contains(ruleLower, keyLower) && !strings.Contains(keyLower, ruleLower) {
+			continue
+		}
+		if !found || len(key) > len(bestKey) || len(key) == len(bestKey) && key < bestKey {
+			bestKey = key
+			found = true
+		}
+	}
+	return m.replies.Rules[bestKey], found
+}
+
+func (m *StubChatModel) purposeReply(purpose string, fallback string) string {
+	if reply, ok := m.replies.Purposes[purpose]; ok && reply != "" {
+		return reply
+	}
+	return fallback
+}
@@ -0,0 +1,31 @@
+package services
+
+import "testing"
+
+func TestStubRuleReplyPrefersLongestKey(t *testing.T) {
+	model := &StubChatModel{replies: StubReplies{Rules: map[string]string{
+		"SIM":                "umum",
+		"perpanjangan SIM":   "perpanjangan",
+		"perpanjangan SIM C": "sim c",
+		"STNK":               "stnk",
+	}}}
+
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"Perpanjangan SIM C", "sim c"},
+		{"perpanjangan sim a", "perpanjangan"},
+		{"SIM baru", "umum"},
+		{"pajak STNK tahunan", "stnk"},
+		{"balik nama", stubDefaultReply},
+	}
+	for _, tt := range tests {
+		// Map order changes between runs; repeat so a nondeterministic pick shows up
+		for i := 0; i < 20; i++ {
+			if got := model.reply(CompletionRequest{Rule: tt.rule}); got != tt.want {
+				t.Fatalf("reply for rule %q = %q, want %q", tt.rule, got, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package services
+
+import (
+	ctx "context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"police-assistant-backend/models"
+	"strings"
+)
+
+// Batas langkah sebuah rute yang ikut dikirim ke model; rute lengkap tetap ada di response
+const toolRouteMaxSteps = 10
+
+// ToolDefinition mendeskripsikan satu tool untuk model (JSON Schema pada Parameters)
+type ToolDefinition struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{}
+}
+
+// ToolExecutor menjalankan tool dengan argumen JSON dari model. Hasil terstruktur
+// disimpan ke context agar bisa dikirim ke frontend; nilai yang dikembalikan dikirim ke model.
+type ToolExecutor func(requestCtx ctx.Context, args json.RawMessage, context *models.Context) (interface{}, error)
+
+// ChatTools menyimpan tool yang bisa dipanggil model saat menjawab chat
+type ChatTools struct {
+	definitions []ToolDefinition
+	executors   map[string]ToolExecutor
+
+	etilangService   *ETilangService
+	pelayananService *PelayananService
+	orsService       *ORSService
+}
+
+func NewChatTools(etilangService *ETilangService, pelayananService *PelayananService, orsService *ORSService) *ChatTools {
+	tools := &ChatTools{
+		executors:        make(map[string]ToolExecutor),
+		etilangService:   etilangService,
+		pelayananService: pelayananService,
+		orsService:       orsService,
+	}
+
+	tools.Register(ToolDefinition{
+		Name:        "check_etilang",
+		Description: "Cek data tilang elektronik (e-tilang / ETLE) sebuah kendaraan berdasarkan nomor polisi. Gunakan jika user bertanya apakah kendaraannya kena tilang, denda, atau pelanggaran.",
+		Parameters: toolObject(map[string]interface{}{
+			"plate_number": toolString("Nomor polisi kendaraan, misal B 1234 ABC"),
+		}, "plate_number"),
+	}, tools.checkETilang)
+
+	tools.Register(ToolDefinition{
+		Name:        "verify_vehicle_ownership",
+		Description: "Verifikasi kepemilikan kendaraan agar data pemilik e-tilang (nama, nomor rangka, nomor mesin) bisa ditampilkan lengkap. Gunakan setelah user menyebutkan karakter terakhir nomor rangka atau nomor mesin dari STNK/BPKB.",
+		Parameters: toolObject(map[string]interface{}{
+			"plate_number": toolString("Nomor polisi kendaraan yang diverifikasi"),
+			"last_digits":  toolString("Minimal 5 karakter terakhir nomor rangka atau nomor mesin, persis seperti yang disebutkan user"),
+		}, "plate_number", "last_digits"),
+	}, tools.verifyOwnership)
+
+	tools.Register(ToolDefinition{
+		Name:        "search_regulations",
+		Description: "Cari pasal UU 22/2009 (LLAJ) beserta denda dan kurungan maksimal untuk sebuah pelanggaran lalu lintas. Gunakan jika user bertanya berapa denda, sanksi, atau pasal sebuah pelanggaran tanpa menyebut nomor polisi.",
+		Parameters: toolObject(map[string]interface{}{
+			"query": toolString("Pelanggaran atau nomor pasal yang ditanyakan, misal tidak pakai helm atau pasal 287 ayat 2"),
+		}, "query"),
+	}, tools.searchRegulations)
+
+	tools.Register(ToolDefinition{
+		Name:        "search_pelayanan",
+		Description: "Cari alur pelayanan Polantas (SIM, STNK, pajak kendaraan, balik nama, mutasi, dll) beserta dokumen yang perlu disiapkan.",
+		Parameters: toolObject(map[string]interface{}{
+			"query": toolString("Pelayanan yang ditanyakan user, misal perpanjang SIM C atau pajak tahunan"),
+		}, "query"),
+	}, tools.searchPelayanan)
+
+	tools.Register(ToolDefinition{
+		Name:        "get_alternative_routes",
+		Description: "Cari beberapa pilihan rute berkendara beserta jarak, waktu tempuh, dan kondisi lalu lintas.",
+		Parameters: toolObject(map[string]interface{}{
+			"origin":      toolString("Titik awal: alamat/nama tempat atau koordinat \"lat,lng\". Kosongkan untuk memakai lokasi user saat ini"),
+			"destination": toolString("Tujuan: alamat/nama tempat atau koordinat \"lat,lng\""),
+		}, "destination"),
+	}, tools.getAlternativeRoutes)
+
+	tools.Register(ToolDefinition{
+		Name:        "get_traffic_info",
+		Description: "Cek kondisi lalu lintas di sekitar sebuah koordinat. Tanpa argumen, memakai lokasi user saat ini.",
+		Parameters: toolObject(map[string]interface{}{
+			"latitude":  map[string]interface{}{"type": "number", "description": "Latitude lokasi"},
+			"longitude": map[string]interface{}{"type": "number", "description": "Longitude lokasi"},
+		}),
+	}, tools.getTrafficInfo)
+
+	log.Printf("✅ Chat Tools initialized with %d tools", len(tools.definitions))
+	return tools
+}
+
+// Register menambahkan tool beserta executor-nya
+func (t *ChatTools) Register(definition ToolDefinition, executor ToolExecutor) {
+	t.definitions = append(t.definitions, definition)
+	t.executors[definition.Name] = executor
+}
+
+// Definitions mengembalikan daftar tool untuk CompletionRequest.Tools
+func (t *ChatTools) Definitions() []ToolDefinition {
+	return t.definitions
+}
+
+// Execute menjalankan satu tool call dan mengembalikan hasilnya sebagai JSON untuk model.
+// Kegagalan tool dikembalikan sebagai {"error": ...} agar model bisa menjelaskannya ke user.
+func (t *ChatTools) Execute(requestCtx ctx.Context, call models.ToolCall, context *models.Context) string {
+	context.ToolsUsed = append(context.ToolsUsed, call.Name)
+
+	executor, exists := t.executors[call.Name]
+	if !exists {
+		log.Printf("⚠️  Model called unknown tool: %s", call.Name)
+		return toolError(fmt.Errorf("tool %s tidak tersedia", call.Name))
+	}
+
+	args := json.RawMessage(call.Arguments)
+	if strings.TrimSpace(call.Arguments) == "" {
+		args = json.RawMessage("{}")
+	}
+
+	result, err := executor(requestCtx, args, context)
+	if err != nil {
+		log.Printf("❌ Tool %s failed: %v", call.Name, err)
+		return toolError(err)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return toolError(err)
+	}
+
+	log.Printf("🔧 Tool %s executed (%d bytes)", call.Name, len(data))
+	return string(data)
+}
+
+// checkETilang menjalankan ETilangService.CheckETilang
+func (t *ChatTools) checkETilang(requestCtx ctx.Context, args json.RawMessage, context *models.Context) (interface{}, error) {
+	var params struct {
+		PlateNumber string `json:"plate_number"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("argumen tidak valid: %w", err)
+	}
+	if strings.TrimSpace(params.PlateNumber) == "" {
+		return nil, fmt.Errorf("nomor polisi wajib diisi")
+	}
+
+	requester := OwnershipRequester{SessionID: context.SessionID, ClientIP: context.ClientIP}
+	info, err := t.etilangService.CheckETilang(requestCtx, requester, params.PlateNumber, AuditSourceTool)
+	if err != nil {
+		return nil, err
+	}
+	context.ETilangInfo = info
+	return info, nil
+}
+
+// verifyOwnership menjalankan ETilangService.VerifyOwnership
+func (t *ChatTools) verifyOwnership(requestCtx ctx.Context, args json.RawMessage, context *models.Context) (interface{}, error) {
+	var params struct {
+		PlateNumber string `json:"plate_number"`
+		LastDigits  string `json:"last_digits"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("argumen tidak valid: %w", err)
+	}
+	if strings.TrimSpace(params.PlateNumber) == "" {
+		return nil, fmt.Errorf("nomor polisi wajib diisi")
+	}
+
+	requester := OwnershipRequester{SessionID: context.SessionID, ClientIP: context.ClientIP}
+	info, err := t.etilangService.VerifyOwnership(requestCtx, requester, params.PlateNumber, OwnershipEvidence{Digits: params.LastDigits}, AuditSourceTool)
+	if err != nil {
+		return nil, err
+	}
+	context.ETilangInfo = info
+	return info, nil
+}
+
+// searchRegulations menjalankan SearchRegulations pada katalog pasal
+func (t *ChatTools) searchRegulations(requestCtx ctx.Context, args json.RawMessage, context *models.Context) (interface{}, error) {
+	var params struct {
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("argumen tidak valid: %w", err)
+	}
+	if strings.TrimSpace(params.Query) == "" {
+		return nil, fmt.Errorf("query wajib diisi")
+	}
+
+	regulations := SearchRegulations(params.Query, RegulationChatLimit)
+	if len(regulations) == 0 {
+		return map[string]interface{}{"found": false, "query": params.Query}, nil
+	}
+	context.Regulations = regulations
+	return map[string]interface{}{"found": true, "regulations": regulations}, nil
+}
+
+// searchPelayanan menjalankan PelayananService.SearchPelayanan
+func (t *ChatTools) searchPelayanan(requestCtx ctx.Context, args json.RawMessage, context *models.Context) (interface{}, error) {
+	var params struct {
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("argumen tidak valid: %w", err)
+	}
+	if strings.TrimSpace(params.Query) == "" {
+		return nil, fmt.Errorf("query wajib diisi")
+	}
+
+	info := t.pelayananService.SearchPelayanan(params.Query)
+	if info.Found {
+		context.PelayananInfo = info
+	}
+	return info, nil
+}
+
+// getAlternativeRoutes menjalankan ORSService.GetAlternativeRoutes
+func (t *ChatTools) getAlternativeRoutes(requestCtx ctx.Context, args json.RawMessage, context *models.Context) (interface{}, error) {
+	var params struct {
+		Origin      string `json:"origin"`
+		Destination string `json:"destination"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("argumen tidak valid: %w", err)
+	}
+	if strings.TrimSpace(params.Destination) == "" {
+		return nil, fmt.Errorf("tujuan wajib diisi")
+	}
+
+	origin := strings.TrimSpace(params.Origin)
+	if origin == "" {
+		switch {
+		case context.Latitude != 0 && context.Longitude != 0:
+			origin = fmt.Sprintf("%f,%f", context.Latitude, context.Longitude)
+		case context.Location != "":
+			origin = context.Location
+		default:
+			return nil, fmt.Errorf("lokasi awal user tidak diketahui, tanyakan titik awal ke user")
+		}
+	}
+
+	routes, err := t.orsService.GetAlternativeRoutes(requestCtx, origin, params.Destination)
+	if err != nil {
+		return nil, err
+	}
+	context.Routes = routes
+
+	// Turn-by-turn steps are long; the model only needs the first few to describe the route
+	summaries := make([]map[string]interface{}, 0, len(routes))
+	for _, route := range routes {
+		summary := make(map[string]interface{}, len(route))
+		for key, value := range route {
+			summary[key] = value
+		}
+		if steps, ok := route["steps"].([]map[string]interface{}); ok && len(steps) > toolRouteMaxSteps {
+			summary["steps"] = steps[:toolRouteMaxSteps]
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// getTrafficInfo menjalankan ORSService.GetTrafficInfo
+func (t *ChatTools) getTrafficInfo(requestCtx ctx.Context, args json.RawMessage, context *models.Context) (interface{}, error) {
+	var params struct {
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("argumen tidak valid: %w", err)
+	}
+	if params.Latitude == 0 || params.Longitude == 0 {
+		params.Latitude, params.Longitude = context.Latitude, context.Longitude
+	}
+	if params.Latitude == 0 || params.Longitude == 0 {
+		return nil, fmt.Errorf("lokasi user tidak diketahui, minta user mengaktifkan lokasi")
+	}
+
+	traffic, err := t.orsService.GetTrafficInfo(requestCtx, params.Latitude, params.Longitude)
+	if err != nil {
+		return nil, err
+	}
+	context.TrafficInfo = traffic
+	return traffic, nil
+}
+
+func toolObject(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func toolString(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description}
+}
+
+func toolError(err error) string {
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return string(data)
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	ctx "context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"police-assistant-backend/models"
+)
+
+// ChoiceNone adalah hasil klasifikasi jika jawaban user tidak cocok dengan pilihan mana pun
+const ChoiceNone = "none"
+
+// ChoiceClassification adalah hasil pemetaan jawaban bebas user ke salah satu pilihan node
+type ChoiceClassification struct {
+	ChoiceID   string  `json:"choice_id"`
+	Confidence float64 `json:"confidence"` // 0.0 - 1.0
+	Reason     string  `json:"reason,omitempty"`
+}
+
+// ClassifyChoice memetakan jawaban bebas user (misal "yang motor aja") ke ID pilihan
+// pada node flow, atau "none" jika tidak ada yang sesuai
+func (s *OpenAIService) ClassifyChoice(requestCtx ctx.Context, message string, question string, choices []FlowChoice) (*ChoiceClassification, error) {
+	systemPrompt, err := s.promptService.Current().Render(PromptChoiceClassifier, SystemPromptData{
+		Classifier: &ChoicePromptData{Question: question, Choices: choices},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.model.Complete(requestCtx, CompletionRequest{
+		Purpose: PurposeClassify,
+		Messages: []models.OpenAIMessage{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: message},
+		},
+		Temperature:  0,
+		MaxTokens:    150,
+		JSONResponse: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result ChoiceClassification
+	if err := json.Unmarshal([]byte(response.Content), &result); err != nil {
+		return nil, fmt.Errorf("invalid classifier response: %w", err)
+	}
+
+	// Guard against IDs the model made up
+	if result.ChoiceID != ChoiceNone {
+		known := false
+		for _, choice := range choices {
+			if choice.ID == result.ChoiceID {
+				known = true
+				break
+			}
+		}
+		if !known {
+			log.Printf("⚠️  Classifier returned unknown choice %q, treating as none", result.ChoiceID)
+			result.ChoiceID = ChoiceNone
+		}
+	}
+
+	log.Printf("🏷️  Choice classified: %q -> %s (confidence: %.2f)", message, result.ChoiceID, result.Confidence)
+	return &result, nil
+}
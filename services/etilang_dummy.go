@@ -0,0 +1,164 @@
+package services
+
+import (
+	ctx "context"
+	"log"
+	"police-assistant-backend/models"
+)
+
+// DummyETilangProvider menyajikan data e-tilang contoh untuk development dan demo.
+// Nomor polisi di luar data contoh dilaporkan tidak ditemukan.
+type DummyETilangProvider struct {
+	data map[string]*models.ETilangInfo // Key: nomor polisi ternormalisasi (B1234SV)
+}
+
+func NewDummyETilangProvider() *DummyETilangProvider {
+	provider := &DummyETilangProvider{
+		data: make(map[string]*models.ETilangInfo),
+	}
+	provider.initializeDummyData()
+
+	log.Printf("✅ E-Tilang provider: dummy (%d plates)", len(provider.data))
+	return provider
+}
+
+func (p *DummyETilangProvider) Name() string {
+	return ETilangProviderDummy
+}
+
+func (p *DummyETilangProvider) Lookup(requestCtx ctx.Context, plateNumber string) (*models.ETilangInfo, error) {
+	info, exists := p.data[plateNumber]
+	if !exists {
+		return nil, ErrPlateNotFound
+	}
+	// Callers may annotate the result; the fixture itself stays untouched
+	result := *info
+	result.Found = true
+	result.Violations = append([]models.ETilangViolation(nil), info.Violations...)
+	return &result, nil
+}
+
+// Plates mengembalikan semua nomor polisi contoh (untuk mock server)
+func (p *DummyETilangProvider) Plates() []string {
+	plates := make([]string, 0, len(p.data))
+	for plate := range p.data {
+		plates = append(plates, plate)
+	}
+	return plates
+}
+
+func (p *DummyETilangProvider) initializeDummyData() {
+	// Data dummy 1: Ada pelanggaran
+	p.data["B1234SV"] = &models.ETilangInfo{
+		PlateNumber:   "B 1234 SV",
+		ChassisNumber: "MH1RP6701FK123456",
+		EngineNumber:  "JM31E1234567",
+		OwnerName:     "Budi Santoso",
+		VehicleType:   "Motor Honda Beat",
+		HasViolation:  true,
+		Violations: []models.ETilangViolation{
+			{
+				ID:          "ETLE-B1234SV-01",
+				Date:        "2025-12-15",
+				Violation:   "Melanggar lampu merah",
+				Location:    "Jl. Sudirman - Jakarta Pusat",
+				Fine:        500000,
+				OfficerName: "Brigadir Joko Widodo",
+				Status:      "unpaid",
+				ArticleCode: "287(2)",
+			},
+			{
+				ID:          "ETLE-B1234SV-02",
+				Date:        "2025-12-20",
+				Violation:   "Tidak menggunakan helm SNI",
+				Location:    "Jl. Gatot Subroto - Jakarta Selatan",
+				Fine:        250000,
+				OfficerName: "Aipda Siti Nurhaliza",
+				Status:      "unpaid",
+				ArticleCode: "291(1)",
+			},
+		},
+		TotalFine: 750000,
+	}
+
+	// Data dummy 2: Ada pelanggaran parkir
+	p.data["B5678XY"] = &models.ETilangInfo{
+		PlateNumber:   "B 5678 XY",
+		ChassisNumber: "MHKA42BA7JK098765",
+		EngineNumber:  "1NRF098765",
+		OwnerName:     "Siti Rahayu",
+		VehicleType:   "Mobil Toyota Avanza",
+		HasViolation:  true,
+		Violations: []models.ETilangViolation{
+			{
+				ID:          "ETLE-B5678XY-01",
+				Date:        "2026-01-02",
+				Violation:   "Parkir di tempat terlarang",
+				Location:    "Jl. MH Thamrin - Jakarta Pusat",
+				Fine:        300000,
+				OfficerName: "Bripka Ahmad Dahlan",
+				Status:      "unpaid",
+				ArticleCode: "287(1)",
+			},
+		},
+		TotalFine: 300000,
+	}
+
+	// Data dummy 3: Tidak ada pelanggaran
+	p.data["B9999ZZ"] = &models.ETilangInfo{
+		PlateNumber:   "B 9999 ZZ",
+		ChassisNumber: "MH1JC5101FK234567",
+		EngineNumber:  "KF41E2233445",
+		OwnerName:     "Ahmad Fauzi",
+		VehicleType:   "Motor Yamaha NMAX",
+		HasViolation:  false,
+		Violations:    []models.ETilangViolation{},
+		TotalFine:     0,
+	}
+
+	// Data dummy 4: Pelanggaran kecepatan
+	p.data["D1111AA"] = &models.ETilangInfo{
+		PlateNumber:   "D 1111 AA",
+		ChassisNumber: "MHRGN81235K876543",
+		EngineNumber:  "L15Z1876543",
+		OwnerName:     "Rina Kartika",
+		VehicleType:   "Mobil Honda CR-V",
+		HasViolation:  true,
+		Violations: []models.ETilangViolation{
+			{
+				ID:          "ETLE-D1111AA-01",
+				Date:        "2025-12-28",
+				Violation:   "Melebihi batas kecepatan (120 km/jam di tol)",
+				Location:    "Tol Jagorawi KM 15",
+				Fine:        500000,
+				OfficerName: "Aiptu Bambang Suryono",
+				Status:      "paid",
+				ArticleCode: "287(5)",
+			},
+		},
+		TotalFine: 0,
+	}
+
+	// Data dummy 5: Pelanggaran penggunaan HP
+	p.data["E7777BB"] = &models.ETilangInfo{
+		PlateNumber:   "E 7777 BB",
+		ChassisNumber: "MH1JFJ110FK345678",
+		EngineNumber:  "2NRF556677",
+		OwnerName:     "Dedi Gunawan",
+		VehicleType:   "Motor Kawasaki Ninja",
+		HasViolation:  true,
+		Violations: []models.ETilangViolation{
+			{
+				ID:          "ETLE-E7777BB-01",
+				Date:        "2026-01-05",
+				Violation:   "Menggunakan handphone saat berkendara",
+				Location:    "Jl. Asia Afrika - Bandung",
+				Fine:        750000,
+				OfficerName: "Brigadir Eka Prasetya",
+				Status:      "unpaid",
+				ArticleCode: "283",
+			},
+		},
+		TotalFine: 750000,
+	}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	ctx "context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"police-assistant-backend/config"
+	"police-assistant-backend/models"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// HTTPETilangProvider mengambil data dari API e-tilang/ETLE:
+//
+//	GET {ETILANG_API_URL}/vehicles/{plate}
+//
+// 200 berisi JSON berbentuk models.ETilangInfo, 404 berarti nomor polisi tidak terdaftar.
+type HTTPETilangProvider struct {
+	client   *resty.Client
+	baseURL  string
+	upstream *Upstream
+}
+
+// NewHTTPETilangProvider membuat adapter HTTP. authHeader "Authorization" mengirim
+// "Bearer <apiKey>"; header lain (misal X-API-Key) mengirim apiKey apa adanya.
+func NewHTTPETilangProvider(baseURL string, apiKey string, authHeader string) *HTTPETilangProvider {
+	client := resty.New()
+	client.SetHeader("Accept", "application/json")
+	if apiKey != "" {
+		if strings.EqualFold(authHeader, "Authorization") {
+			client.SetAuthToken(apiKey)
+		} else {
+			client.SetHeader(authHeader, apiKey)
+		}
+	}
+
+	log.Printf("✅ E-Tilang provider: HTTP at %s", baseURL)
+
+	return &HTTPETilangProvider{
+		client:  client,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		upstream: NewUpstream("etilang", RetryPolicy{
+			Timeout:    config.AppConfig.ETilangTimeout,
+			MaxRetries: config.AppConfig.ETilangMaxRetries,
+		}),
+	}
+}
+
+func (p *HTTPETilangProvider) Name() string {
+	return ETilangProviderHTTP
+}
+
+func (p *HTTPETilangProvider) Lookup(requestCtx ctx.Context, plateNumber string) (*models.ETilangInfo, error) {
+	var resp *resty.Response
+	err := p.upstream.Do(requestCtx, func(attemptCtx ctx.Context) error {
+		r, err := p.client.R().
+			SetContext(attemptCtx).
+			SetPathParam("plate", plateNumber).
+			Get(p.baseURL + "/vehicles/{plate}")
+		if err != nil {
+			return err
+		}
+		if retryableStatus(r.StatusCode()) {
+			return &StatusError{
+				StatusCode: r.StatusCode(),
+				RetryAfter: parseRetryAfter(r.Header().Get("Retry-After")),
+				Body:       r.String(),
+			}
+		}
+		resp = r
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call e-tilang API: %w", err)
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrPlateNotFound
+	default:
+		return nil, fmt.Errorf("e-tilang API error: %w", &StatusError{StatusCode: resp.StatusCode(), Body: resp.String()})
+	}
+
+	var info models.ETilangInfo
+	if err := json.Unmarshal(resp.Body(), &info); err != nil {
+		return nil, fmt.Errorf("invalid e-tilang API response: %w", err)
+	}
+	return &info, nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	ctx "context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// newTestHTTPETilangProvider mengarahkan adapter HTTP ke server test dengan retry tanpa config
+func newTestHTTPETilangProvider(t *testing.T, handler http.HandlerFunc) (*HTTPETilangProvider, *int32) {
+	t.Helper()
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return &HTTPETilangProvider{
+		client:   resty.New(),
+		baseURL:  server.URL,
+		upstream: newTestUpstream(2),
+	}, &calls
+}
+
+func TestHTTPETilangProviderLookup(t *testing.T) {
+	provider, calls := newTestHTTPETilangProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/vehicles/B 1234 SV" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"plate_number":"B 1234 SV","found":true,"chassis_number":"MH1JM1234KK123456","has_violation":true,"total_fine":500000,"violations":[{"id":"ETLE-1"}]}`))
+	})
+
+	info, err := provider.Lookup(ctx.Background(), "B 1234 SV")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.PlateNumber != "B 1234 SV" || !info.Found || !info.HasViolation || info.TotalFine != 500000 || len(info.Violations) != 1 {
+		t.Errorf("info = %+v", info)
+	}
+	if *calls != 1 {
+		t.Errorf("calls = %d, want 1", *calls)
+	}
+}
+
+func TestHTTPETilangProviderNotFound(t *testing.T) {
+	provider, calls := newTestHTTPETilangProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+	})
+
+	if _, err := provider.Lookup(ctx.Background(), "B 9999 ZZ"); !errors.Is(err, ErrPlateNotFound) {
+		t.Fatalf("err = %v, want ErrPlateNotFound", err)
+	}
+	if *calls != 1 || provider.upstream.failures != 0 {
+		t.Errorf("calls = %d, failures = %d; a 404 must not be retried or trip the breaker", *calls, provider.upstream.failures)
+	}
+}
+
+func TestHTTPETilangProviderRetriesServerErrors(t *testing.T) {
+	var attempts int32
+	provider, calls := newTestHTTPETilangProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"plate_number":"B 1234 SV","found":true}`))
+	})
+
+	info, err := provider.Lookup(ctx.Background(), "B 1234 SV")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Found || *calls != 2 {
+		t.Errorf("found = %v, calls = %d; want success on the second attempt", info.Found, *calls)
+	}
+}
+
+func TestHTTPETilangProviderGivesUpOnServerErrors(t *testing.T) {
+	provider, calls := newTestHTTPETilangProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	})
+
+	if _, err := provider.Lookup(ctx.Background(), "B 1234 SV"); !errors.Is(err, ErrUpstreamUnavailable) {
+		t.Fatalf("err = %v, want ErrUpstreamUnavailable", err)
+	}
+	if want := int32(provider.upstream.policy.MaxRetries + 1); *calls != want {
+		t.Errorf("calls = %d, want %d", *calls, want)
+	}
+}
+
+func TestHTTPETilangProviderMalformedBody(t *testing.T) {
+	provider, calls := newTestHTTPETilangProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html>gateway login</html>`))
+	})
+
+	_, err := provider.Lookup(ctx.Background(), "B 1234 SV")
+	if err == nil || errors.Is(err, ErrPlateNotFound) || errors.Is(err, ErrUpstreamUnavailable) {
+		t.Fatalf("err = %v, want an invalid response error", err)
+	}
+	if *calls != 1 {
+		t.Errorf("calls = %d, want 1", *calls)
+	}
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+	ctx "context"
+	"errors"
+	"fmt"
+	"log"
+	"police-assistant-backend/models"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Verifikasi kepemilikan: user menyebutkan karakter terakhir nomor rangka atau nomor mesin
+// (tertera di STNK/BPKB). Field hasil OCR yang dikirim client tidak dipercaya sebagai bukti.
+const (
+	ownershipMinDigits        = 5                // Karakter terakhir minimal yang harus disebutkan
+	ownershipMaxFailures      = 5                // Percobaan gagal per IP client dan nomor polisi sebelum dikunci
+	ownershipPlateMaxFailures = 20               // Percobaan gagal per nomor polisi dari semua IP sebelum dikunci
+	ownershipLockout          = 15 * time.Minute // Lama verifikasi dikunci, sekaligus jendela penghitungan gagal
+)
+
+// Prefix key session data untuk nomor polisi yang sudah diverifikasi; nilainya metode verifikasi
+const ownershipVerifiedKey = "etilang_verified:"
+
+// Metode verifikasi yang dicatat di session dan audit log
+const (
+	OwnershipByChassis = "chassis"
+	OwnershipByEngine  = "engine"
+)
+
+var (
+	ErrOwnershipInput    = fmt.Errorf("sebutkan minimal %d karakter terakhir nomor rangka atau nomor mesin (lihat STNK/BPKB)", ownershipMinDigits)
+	ErrOwnershipMismatch = errors.New("nomor rangka/mesin tidak cocok dengan data kendaraan")
+	ErrOwnershipLocked   = errors.New("verifikasi untuk nomor polisi ini dikunci sementara karena terlalu banyak percobaan gagal")
+)
+
+// Token yang mungkin berupa bagian akhir nomor rangka/mesin: huruf dan angka, minimal 3 angka
+var ownershipDigitsPattern = regexp.MustCompile(`\b[A-Za-z0-9]{5,17}\b`)
+
+// OwnershipEvidence adalah bukti kepemilikan dari user
+type OwnershipEvidence struct {
+	Digits string // Karakter terakhir nomor rangka ATAU nomor mesin (dari chat), boleh nomor lengkap
+}
+
+// OwnershipRequester mengidentifikasi pihak yang mencoba verifikasi
+type OwnershipRequester struct {
+	SessionID string
+	ClientIP  string
+}
+
+// failureKey: percobaan dihitung per IP client lintas session, agar karakter terakhir tidak
+// bisa ditebak dengan membuat session baru, tanpa membuat orang lain bisa mengunci pemilik
+// kendaraan. Tanpa IP (misal pemanggilan internal), hitungan per session.
+func (r OwnershipRequester) failureKey(plate Plate) string {
+	if r.ClientIP != "" {
+		return "ip:" + r.ClientIP + "|" + plate.Normalized()
+	}
+	return "session:" + r.SessionID + "|" + plate.Normalized()
+}
+
+// plateFailureKey: batas gagal global per nomor polisi, agar penebak yang berganti-ganti IP
+// (botnet, proxy) tetap berhenti setelah ownershipPlateMaxFailures percobaan
+func plateFailureKey(plate Plate) string {
+	return "plate:" + plate.Normalized()
+}
+
+// ownershipFailures menghitung percobaan gagal satu requester (atau semua requester) untuk satu nomor polisi
+type ownershipFailures struct {
+	count       int
+	since       time.Time // Percobaan gagal pertama di jendela penghitungan
+	lockedUntil time.Time
+}
+
+// VerifyOwnership mencocokkan bukti dengan nomor rangka/mesin kendaraan. Jika cocok, session
+// ditandai terverifikasi untuk nomor polisi tersebut dan data lengkap dikembalikan. Jika tidak
+// cocok, data tersamar dikembalikan bersama error yang bisa disampaikan ke user.
+func (s *ETilangService) VerifyOwnership(requestCtx ctx.Context, requester OwnershipRequester, plateNumber string, evidence OwnershipEvidence, source string) (*models.ETilangInfo, error) {
+	sessionID := requester.SessionID
+	entry := AuditEntry{Event: AuditEventOwnership, SessionID: sessionID, ClientIP: requester.ClientIP, Source: source, Subject: plateNumber}
+	defer func() { s.audit.Record(entry) }()
+
+	plate, ok := ParsePlateNumber(plateNumber)
+	if !ok {
+		entry.Result = "invalid"
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPlateNumber, plateNumber)
+	}
+	entry.Subject = plate.String()
+
+	evidence.Digits = normalizeOwnershipDigits(evidence.Digits)
+	if len(evidence.Digits) < ownershipMinDigits {
+		entry.Result = "invalid"
+		return nil, ErrOwnershipInput
+	}
+
+	failureKey, plateKey := requester.failureKey(plate), plateFailureKey(plate)
+	until := s.lockedUntil(failureKey)
+	if plateUntil := s.lockedUntil(plateKey); plateUntil.After(until) {
+		until = plateUntil
+	}
+	if !until.IsZero() {
+		entry.Result = "locked"
+		return nil, fmt.Errorf("%w, coba lagi setelah pukul %s", ErrOwnershipLocked, until.Format("15:04"))
+	}
+
+	info, err := s.lookup(requestCtx, plate)
+	if err != nil {
+		entry.Result, entry.Detail = "error", err.Error()
+		return nil, err
+	}
+	if !info.Found {
+		entry.Result = "not_found"
+		return info, fmt.Errorf("nomor polisi %s: %w", plate, ErrPlateNotFound)
+	}
+
+	method := matchOwnership(info, evidence)
+	if method == "" {
+		remaining := min(
+			s.recordOwnershipFailure(failureKey, ownershipMaxFailures),
+			s.recordOwnershipFailure(plateKey, ownershipPlateMaxFailures),
+		)
+		entry.Result = "mismatch"
+		entry.Detail = fmt.Sprintf("%d attempt(s) left", remaining)
+		log.Printf("🔒 Ownership verification failed for %s (session %s, %d attempt(s) left)", plate, sessionID, remaining)
+
+		err := fmt.Errorf("%w (sisa %d percobaan)", ErrOwnershipMismatch, remaining)
+		if remaining == 0 {
+			err = fmt.Errorf("%w, coba lagi dalam %d menit", ErrOwnershipLocked, int(ownershipLockout.Minutes()))
+		}
+		masked := maskETilangInfo(info)
+		masked.VerificationMessage = err.Error()
+		return masked, err
+	}
+
+	s.clearOwnershipFailures(failureKey)
+	GetSessionStore().SetData(sessionID, ownershipVerifiedKey+plate.Normalized(), method)
+	entry.Result = "verified"
+	entry.Detail = method
+	log.Printf("🔓 Ownership of %s verified by %s (session %s)", plate, method, sessionID)
+
+	info.Verified = true
+	return info, nil
+}
+
+// IsOwnershipVerified mengecek apakah session sudah memverifikasi kepemilikan nomor polisi
+func (s *ETilangService) IsOwnershipVerified(sessionID string, plateNumber string) bool {
+	plate, ok := ParsePlateNumber(plateNumber)
+	return ok && s.verifiedBy(sessionID, plate) != ""
+}
+
+func (s *ETilangService) verifiedBy(sessionID string, plate Plate) string {
+	if sessionID == "" {
+		return ""
+	}
+	return GetSessionStore().GetData(sessionID, ownershipVerifiedKey+plate.Normalized())
+}
+
+// FindOwnershipDigits mengambil kandidat karakter terakhir nomor rangka/mesin dari pesan
+// ("12345", "nomor rangkanya K123456"). Nomor polisi tidak dihitung.
+func FindOwnershipDigits(message string) []string {
+	plates := make(map[string]bool)
+	for _, plate := range FindPlateNumbers(message) {
+		plates[plate.Normalized()] = true
+	}
+
+	var candidates []string
+	for _, token := range ownershipDigitsPattern.FindAllString(message, -1) {
+		token = strings.ToUpper(token)
+		if plates[token] {
+			continue
+		}
+		digits := 0
+		for _, char := range token {
+			if char >= '0' && char <= '9' {
+				digits++
+			}
+		}
+		if digits >= 3 {
+			candidates = append(candidates, token)
+		}
+	}
+	return candidates
+}
+
+// matchOwnership mengembalikan metode verifikasi yang cocok, kosong jika tidak ada
+func matchOwnership(info *models.ETilangInfo, evidence OwnershipEvidence) string {
+	chassis := normalizeOwnershipDigits(info.ChassisNumber)
+	engine := normalizeOwnershipDigits(info.EngineNumber)
+
+	switch {
+	case ownershipSuffixMatch(chassis, evidence.Digits):
+		return OwnershipByChassis
+	case ownershipSuffixMatch(engine, evidence.Digits):
+		return OwnershipByEngine
+	default:
+		return ""
+	}
+}
+
+func ownershipSuffixMatch(actual string, given string) bool {
+	return len(given) >= ownershipMinDigits && actual != "" && strings.HasSuffix(actual, given)
+}
+
+func normalizeOwnershipDigits(value string) string {
+	value = strings.ToUpper(value)
+	return strings.NewReplacer(" ", "", "-", "", ".", "").Replace(strings.TrimSpace(value))
+}
+
+func (s *ETilangService) lockedUntil(key string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failures := s.failures[key]
+	if failures == nil || time.Now().After(failures.lockedUntil) {
+		return time.Time{}
+	}
+	return failures.lockedUntil
+}
+
+// recordOwnershipFailure menambah hitungan gagal dan mengembalikan sisa percobaan sebelum
+// key dikunci. Hitungan mulai dari nol lagi setelah kunci berakhir atau jendela penghitungan lewat.
+func (s *ETilangService) recordOwnershipFailure(key string, maxFailures int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	failures := s.failures[key]
+	if failures == nil || (!failures.lockedUntil.IsZero() && now.After(failures.lockedUntil)) ||
+		(failures.lockedUntil.IsZero() && now.Sub(failures.since) > ownershipLockout) {
+		failures = &ownershipFailures{since: now}
+		s.failures[key] = failures
+	}
+	failures.count++
+	if failures.count >= maxFailures {
+		failures.lockedUntil = now.Add(ownershipLockout)
+		return 0
+	}
+	return maxFailures - failures.count
+}
+
+func (s *ETilangService) clearOwnershipFailures(key string) {
+	s.mu.Lock()
+	delete(s.failures, key)
+	s.mu.Unlock()
+}
+
+// maskETilangInfo menyamarkan data pemilik: "Budi Santoso" -> "Bu** Sa*****", nomor rangka
+// hanya menyisakan kode pabrikan, nomor mesin disembunyikan, jenis kendaraan hanya kategori.
+// Pelanggaran dan denda tetap ditampilkan seperti di situs ETLE publik.
+func maskETilangInfo(info *models.ETilangInfo) *models.ETilangInfo {
+	masked := *info
+	masked.Verified = false
+	masked.OwnerName = maskName(info.OwnerName)
+	masked.ChassisNumber = maskKeepPrefix(info.ChassisNumber, 3)
+	masked.EngineNumber = maskKeepPrefix(info.EngineNumber, 0)
+	if fields := strings.Fields(info.VehicleType); len(fields) > 1 {
+		masked.VehicleType = fields[0]
+	}
+	return &masked
+}
+
+func maskName(name string) string {
+	words := strings.Fields(name)
+	for i, word := range words {
+		keep := 2
+		if utf8.RuneCountInString(word) <= 2 {
+			keep = 1
+		}
+		words[i] = maskKeepPrefix(word, keep)
+	}
+	return strings.Join(words, " ")
+}
+
+// maskKeepPrefix mengganti semua karakter setelah keep karakter pertama dengan *
+func maskKeepPrefix(value string, keep int) string {
+	runes := []rune(value)
+	for i := keep; i < len(runes); i++ {
+		runes[i] = '*'
+	}
+	return string(runes)
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"bufio"
+	ctx "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestETilangService(t *testing.T) *ETilangService {
+	t.Helper()
+	dir := t.TempDir()
+	payments, err := NewPaymentStore(filepath.Join(dir, "payments.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewETilangService(NewDummyETilangProvider(), NewAuditLog(filepath.Join(dir, "audit.jsonl")), payments)
+}
+
+// TestOwnershipLockoutPerClient: percobaan gagal dari satu IP tidak boleh mengunci pemilik
+// kendaraan yang memverifikasi dari IP lain
+func TestOwnershipLockoutPerClient(t *testing.T) {
+	service := newTestETilangService(t)
+	store := GetSessionStore()
+	background := ctx.Background()
+
+	attackerSession, _, _ := store.CreateSession()
+	attacker := OwnershipRequester{SessionID: attackerSession, ClientIP: "203.0.113.7"}
+	for i := 0; i < ownershipMaxFailures; i++ {
+		if _, err := service.VerifyOwnership(background, attacker, "B 1234 SV", OwnershipEvidence{Digits: "99999"}, AuditSourceTool); !errors.Is(err, ErrOwnershipMismatch) && !errors.Is(err, ErrOwnershipLocked) {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+	}
+
+	// A new session from the same client is still locked
+	otherSession, _, _ := store.CreateSession()
+	if _, err := service.VerifyOwnership(background, OwnershipRequester{SessionID: otherSession, ClientIP: attacker.ClientIP}, "B 1234 SV", OwnershipEvidence{Digits: "23456"}, AuditSourceTool); !errors.Is(err, ErrOwnershipLocked) {
+		t.Fatalf("new session from the locked client: err = %v, want ErrOwnershipLocked", err)
+	}
+
+	ownerSession, _, _ := store.CreateSession()
+	owner := OwnershipRequester{SessionID: ownerSession, ClientIP: "198.51.100.20"}
+	info, err := service.VerifyOwnership(background, owner, "B 1234 SV", OwnershipEvidence{Digits: "23456"}, AuditSourceTool)
+	if err != nil {
+		t.Fatalf("owner verification: %v", err)
+	}
+	if !info.Verified || !service.IsOwnershipVerified(ownerSession, "B1234SV") {
+		t.Fatal("owner was not verified")
+	}
+}
+
+// TestOwnershipLockoutPerPlate: penebak yang berganti IP tetap berhenti di batas global per nomor polisi
+func TestOwnershipLockoutPerPlate(t *testing.T) {
+	service := newTestETilangService(t)
+	store := GetSessionStore()
+	background := ctx.Background()
+
+	for i := 0; i < ownershipPlateMaxFailures; i++ {
+		sessionID, _, _ := store.CreateSession()
+		requester := OwnershipRequester{SessionID: sessionID, ClientIP: fmt.Sprintf("203.0.113.%d", i+1)}
+		if _, err := service.VerifyOwnership(background, requester, "B 1234 SV", OwnershipEvidence{Digits: "99999"}, AuditSourceTool); !errors.Is(err, ErrOwnershipMismatch) && !errors.Is(err, ErrOwnershipLocked) {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+	}
+
+	sessionID, _, _ := store.CreateSession()
+	fresh := OwnershipRequester{SessionID: sessionID, ClientIP: "198.51.100.99"}
+	if _, err := service.VerifyOwnership(background, fresh, "B 1234 SV", OwnershipEvidence{Digits: "23456"}, AuditSourceTool); !errors.Is(err, ErrOwnershipLocked) {
+		t.Fatalf("fresh IP after the plate ceiling: err = %v, want ErrOwnershipLocked", err)
+	}
+
+	// Other plates are not affected
+	if _, err := service.VerifyOwnership(background, fresh, "D 1111 AA", OwnershipEvidence{Digits: "99999"}, AuditSourceTool); errors.Is(err, ErrOwnershipLocked) {
+		t.Fatalf("other plate: err = %v, want it unlocked", err)
+	}
+}
+
+// TestCheckETilangAuditsClientIP: pengecekan e-tilang mencatat IP client seperti verifikasi kepemilikan
+func TestCheckETilangAuditsClientIP(t *testing.T) {
+	service := newTestETilangService(t)
+	sessionID, _, _ := GetSessionStore().CreateSession()
+	requester := OwnershipRequester{SessionID: sessionID, ClientIP: "198.51.100.20"}
+
+	if _, err := service.CheckETilang(ctx.Background(), requester, "B 1234 SV", AuditSourceKeyword); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(service.audit.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		t.Fatal("no audit entry written")
+	}
+	var entry AuditEntry
+	if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Event != AuditEventETilangLookup || entry.SessionID != sessionID || entry.ClientIP != requester.ClientIP || entry.Subject != "B 1234 SV" {
+		t.Errorf("audit entry = %+v", entry)
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"police-assistant-backend/config"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidFileSignature = errors.New("invalid download link")
+	ErrFileURLExpired       = errors.New("download link has expired")
+)
+
+// StoredFile menyimpan metadata file yang disimpan di storage lokal
+type StoredFile struct {
+	ID          string    `json:"id"`
+	SessionID   string    `json:"session_id"` // Hanya pemilik session ini yang boleh mengunduh
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// FileStore menyimpan file upload dan berkas hasil generate di disk
+type FileStore struct {
+	dir       string
+	baseURL   string
+	urlSecret []byte        // Kunci HMAC link download
+	urlExpiry time.Duration // Masa berlaku link download
+}
+
+func NewFileStore() *FileStore {
+	store := &FileStore{
+		dir:       config.AppConfig.StorageDir,
+		baseURL:   config.AppConfig.PublicBaseURL,
+		urlSecret: []byte(config.AppConfig.FileURLSecret),
+		urlExpiry: config.AppConfig.FileURLExpiry,
+	}
+	if len(store.urlSecret) == 0 {
+		// Links signed with a random key only survive until the next restart
+		store.urlSecret = make([]byte, 32)
+		if _, err := rand.Read(store.urlSecret); err != nil {
+			log.Fatalf("❌ Failed to generate download link secret: %v", err)
+		}
+	}
+
+	if err := os.MkdirAll(store.dir, 0o755); err != nil {
+		log.Printf("⚠️  Failed to create storage directory %s: %v", store.dir, err)
+	} else {
+		log.Printf("✅ File Store initialized (dir: %s)", store.dir)
+	}
+
+	return store
+}
+
+// Save menyimpan data file milik session dan mengembalikan metadata-nya
+func (s *FileStore) Save(sessionID string, fileName string, contentType string, data []byte) (*StoredFile, error) {
+	file := &StoredFile{
+		ID:          uuid.New().String(),
+		SessionID:   sessionID,
+		FileName:    filepath.Base(fileName),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		CreatedAt:   time.Now(),
+	}
+
+	if err := os.WriteFile(s.dataPath(file.ID), data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	meta, err := json.Marshal(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(s.metaPath(file.ID), meta, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write file metadata: %w", err)
+	}
+
+	return file, nil
+}
+
+// Get mengambil metadata dan isi file berdasarkan ID
+func (s *FileStore) Get(fileID string) (*StoredFile, []byte, error) {
+	if _, err := uuid.Parse(fileID); err != nil {
+		return nil, nil, fmt.Errorf("invalid file id: %s", fileID)
+	}
+
+	meta, err := os.ReadFile(s.metaPath(fileID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var file StoredFile
+	if err := json.Unmarshal(meta, &file); err != nil {
+		return nil, nil, err
+	}
+
+	data, err := os.ReadFile(s.dataPath(fileID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &file, data, nil
+}
+
+// URL mengembalikan link download bertanda tangan untuk file. Link bisa dibuka langsung di
+// browser tanpa header, hanya untuk file dan session ini, sampai FILE_URL_EXPIRY_HOURS.
+func (s *FileStore) URL(file *StoredFile) string {
+	expires := time.Now().Add(s.urlExpiry).Unix()
+	return fmt.Sprintf("%s/api/v1/files/%s?expires=%d&signature=%s", s.baseURL, file.ID, expires, s.signURL(file, expires))
+}
+
+// VerifyURL memeriksa query expires dan signature dari link download file
+func (s *FileStore) VerifyURL(file *StoredFile, expires string, signature string, now time.Time) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || signature == "" {
+		return ErrInvalidFileSignature
+	}
+	if !hmac.Equal([]byte(s.signURL(file, expiresAt)), []byte(signature)) {
+		return ErrInvalidFileSignature
+	}
+	if now.Unix() > expiresAt {
+		return ErrFileURLExpired
+	}
+	return nil
+}
+
+// signURL menghitung HMAC-SHA256 atas ID file, session pemilik, dan batas waktu link
+func (s *FileStore) signURL(file *StoredFile, expires int64) string {
+	mac := hmac.New(sha256.New, s.urlSecret)
+	fmt.Fprintf(mac, "%s|%s|%d", file.ID, file.SessionID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *FileStore) dataPath(fileID string) string {
+	return filepath.Join(s.dir, fileID+".bin")
+}
+
+func (s *FileStore) metaPath(fileID string) string {
+	return filepath.Join(s.dir, fileID+".json")
+}
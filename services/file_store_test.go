@@ -0,0 +1,53 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestFileURLSignature(t *testing.T) {
+	store := &FileStore{baseURL: "https://sobat-lantas.example", urlSecret: []byte("rahasia"), urlExpiry: time.Hour}
+	file := &StoredFile{ID: "6f1c1f0e-5b8a-4c1e-9d53-0c7f4b7f5a10", SessionID: "session-a"}
+
+	link, err := url.Parse(store.URL(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.Path != "/api/v1/files/"+file.ID {
+		t.Fatalf("path = %s", link.Path)
+	}
+	expires, signature := link.Query().Get("expires"), link.Query().Get("signature")
+	now := time.Now()
+
+	if err := store.VerifyURL(file, expires, signature, now); err != nil {
+		t.Fatalf("valid link: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		file      *StoredFile
+		expires   string
+		signature string
+		now       time.Time
+		want      error
+	}{
+		{"other session", &StoredFile{ID: file.ID, SessionID: "session-b"}, expires, signature, now, ErrInvalidFileSignature},
+		{"other file", &StoredFile{ID: "0d7c3a52-8f0b-4b44-a2f5-1f4b1f6c9e01", SessionID: file.SessionID}, expires, signature, now, ErrInvalidFileSignature},
+		{"extended expiry", file, "9999999999", signature, now, ErrInvalidFileSignature},
+		{"missing signature", file, expires, "", now, ErrInvalidFileSignature},
+		{"malformed expiry", file, "besok", signature, now, ErrInvalidFileSignature},
+		{"expired", file, expires, signature, now.Add(2 * time.Hour), ErrFileURLExpired},
+	}
+	for _, tt := range tests {
+		if err := store.VerifyURL(tt.file, tt.expires, tt.signature, tt.now); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	other := &FileStore{baseURL: store.baseURL, urlSecret: []byte("lain"), urlExpiry: time.Hour}
+	if err := other.VerifyURL(file, expires, signature, now); !errors.Is(err, ErrInvalidFileSignature) {
+		t.Errorf("other secret: err = %v, want ErrInvalidFileSignature", err)
+	}
+}
@@ -0,0 +1,577 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"police-assistant-backend/config"
+	"police-assistant-backend/models"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type FlowNode struct {
+	ID          string           `json:"id"`
+	Type        string           `json:"type"` // message, question, collect, action
+	Text        string           `json:"text"`
+	Choices     []FlowChoice     `json:"choices,omitempty"`
+	Collect     *FlowCollect     `json:"collect,omitempty"`
+	Action      *FlowAction      `json:"action,omitempty"`
+	Transitions []FlowTransition `json:"transitions"`
+	OnSelect    []FlowEffect     `json:"on_select,omitempty"`
+}
+
+// IsTerminal menandakan node tidak punya transition lagi (flow selesai)
+func (n *FlowNode) IsTerminal() bool {
+	return len(n.Transitions) == 0 && len(n.Choices) == 0
+}
+
+// GetChoice mengambil pilihan berdasarkan ID
+func (n *FlowNode) GetChoice(choiceID string) *FlowChoice {
+	for i := range n.Choices {
+		if n.Choices[i].ID == choiceID {
+			return &n.Choices[i]
+		}
+	}
+	return nil
+}
+
+type FlowChoice struct {
+	ID    string      `json:"id"`
+	Label string      `json:"label"`
+	Value interface{} `json:"value"`
+}
+
+type FlowCollect struct {
+	Key  string   `json:"key"`
+	Mime []string `json:"mime"`
+}
+
+type FlowAction struct {
+	Type      string   `json:"type"`
+	Template  string   `json:"template,omitempty"`
+	Inputs    []string `json:"inputs,omitempty"`
+	OutputKey string   `json:"output_key,omitempty"`
+	Target    string   `json:"target,omitempty"`
+	Reason    string   `json:"reason,omitempty"`
+}
+
+type FlowTransition struct {
+	When      string     `json:"when"`
+	To        string     `json:"to"`
+	condition *Condition // Hasil parse When saat flow di-load
+}
+
+// Flow adalah definisi satu alur layanan terstruktur (SIM, STNK, balik nama, ...)
+type Flow struct {
+	FlowID         string              `json:"flow_id"`
+	Title          string              `json:"title"`
+	Version        string              `json:"version"`
+	Locale         string              `json:"locale"`
+	EntryNode      string              `json:"entry_node"`
+	TriggerIntents []string            `json:"trigger_intents"` // Kata kunci yang memulai flow ini
+	ContextSchema  ContextSchema       `json:"context_schema,omitempty"`
+	Nodes          []FlowNode          `json:"nodes"`
+	nodeMap        map[string]FlowNode // For quick lookup
+}
+
+// GetNode mengambil node berdasarkan ID
+func (f *Flow) GetNode(nodeID string) *FlowNode {
+	if node, exists := f.nodeMap[nodeID]; exists {
+		return &node
+	}
+	return nil
+}
+
+// FlowService memuat semua flow dari direktori flow dan menjalankan state machine-nya
+type FlowService struct {
+	flows   map[string]*Flow
+	order   []string // Urutan flow_id untuk deteksi intent yang deterministik
+	actions *ActionRegistry
+	uploads *UploadService
+}
+
+// Batas jumlah action dan message node yang dilewati berturut-turut dalam satu request
+const maxActionChain = 10
+
+// Pola placeholder {{key}} pada teks node
+var templatePattern = regexp.MustCompile(`\{\{\s*([\w.]+)\s*\}\}`)
+
+func NewFlowService(actions *ActionRegistry, uploads *UploadService) *FlowService {
+	service := &FlowService{
+		flows:   make(map[string]*Flow),
+		actions: actions,
+		uploads: uploads,
+	}
+
+	if err := service.loadFlows(config.AppConfig.FlowsDir); err != nil {
+		log.Printf("⚠️  Failed to load flows: %v", err)
+	}
+
+	log.Printf("✅ Flow Service initialized with %d flow(s)", len(service.flows))
+	return service
+}
+
+// loadFlows memuat setiap file *.json di direktori flow
+func (s *FlowService) loadFlows(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		flow, err := LoadFlowFile(path)
+		if err != nil {
+			log.Printf("⚠️  Skipping flow %s: %v", path, err)
+			continue
+		}
+
+		// Static validation: errors keep the flow from loading, warnings are only logged
+		issues := LintFlow(flow)
+		for _, issue := range issues {
+			log.Printf("   🔎 %s", issue)
+		}
+		if HasLintErrors(issues) {
+			log.Printf("⚠️  Skipping flow %s: validation failed (run cmd/flowlint for details)", path)
+			continue
+		}
+
+		if _, exists := s.flows[flow.FlowID]; exists {
+			log.Printf("⚠️  Skipping flow %s: duplicate flow_id %s", path, flow.FlowID)
+			continue
+		}
+
+		s.flows[flow.FlowID] = flow
+		s.order = append(s.order, flow.FlowID)
+		log.Printf("   📄 Flow %s loaded from %s (%d nodes)", flow.FlowID, path, len(flow.Nodes))
+	}
+
+	return nil
+}
+
+// LoadFlowFile mem-parse file flow beserta kondisi when-nya; validasi struktur ada di LintFlow
+func LoadFlowFile(path string) (*Flow, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFlow(file)
+}
+
+// ParseFlow mem-parse definisi flow JSON; kondisi when yang tidak valid langsung ditolak
+func ParseFlow(data []byte) (*Flow, error) {
+	var flow Flow
+	if err := json.Unmarshal(data, &flow); err != nil {
+		return nil, err
+	}
+
+	// Parse transition conditions once at load time
+	for i := range flow.Nodes {
+		node := &flow.Nodes[i]
+		for j := range node.Transitions {
+			condition, err := ParseCondition(node.Transitions[j].When)
+			if err != nil {
+				return nil, fmt.Errorf("node %s transition %d: %w", node.ID, j, err)
+			}
+			node.Transitions[j].condition = condition
+		}
+		for j := range node.OnSelect {
+			condition, err := ParseCondition(node.OnSelect[j].When)
+			if err != nil {
+				return nil, fmt.Errorf("node %s on_select %d: %w", node.ID, j, err)
+			}
+			node.OnSelect[j].condition = condition
+		}
+	}
+
+	// Build node map for quick lookup; the first definition of a duplicated ID wins
+	flow.nodeMap = make(map[string]FlowNode)
+	for _, node := range flow.Nodes {
+		if _, exists := flow.nodeMap[node.ID]; !exists {
+			flow.nodeMap[node.ID] = node
+		}
+	}
+
+	return &flow, nil
+}
+
+// GetFlow mengambil flow berdasarkan flow_id
+func (s *FlowService) GetFlow(flowID string) *Flow {
+	return s.flows[flowID]
+}
+
+// DetectIntent mencari flow yang trigger intent-nya disebut di pesan user sebagai kata utuh.
+// Trigger terpanjang yang cocok dipilih agar frasa spesifik menang atas frasa umum.
+func (s *FlowService) DetectIntent(message string) *Flow {
+	var matched *Flow
+	matchedLength := 0
+	for _, flowID := range s.order {
+		flow := s.flows[flowID]
+		for _, trigger := range flow.TriggerIntents {
+			if containsPhrase(message, trigger) && len(trigger) > matchedLength {
+				matched = flow
+				matchedLength = len(trigger)
+			}
+		}
+	}
+
+	return matched
+}
+
+// GetNode mengambil node dari flow tertentu
+func (s *FlowService) GetNode(flowID string, nodeID string) *FlowNode {
+	flow := s.GetFlow(flowID)
+	if flow == nil {
+		return nil
+	}
+	return flow.GetNode(nodeID)
+}
+
+// StartFlow memulai flow dari entry_node dengan context kosong
+func (s *FlowService) StartFlow(sessionID string, flowID string) string {
+	flow := s.GetFlow(flowID)
+	if flow == nil {
+		return ""
+	}
+
+	GetSessionStore().StartFlow(sessionID, flowID, flow.EntryNode)
+	log.Printf("🆕 Starting flow %s from %s", flowID, flow.EntryNode)
+	return flow.EntryNode
+}
+
+// ProcessUserChoice processes user's choice and returns next node
+func (s *FlowService) ProcessUserChoice(sessionID string, flowID string, currentNodeID string, userInput string) (string, *FlowNode) {
+	flow := s.GetFlow(flowID)
+	if flow == nil {
+		return "", nil
+	}
+	node := flow.GetNode(currentNodeID)
+	if node == nil {
+		return "", nil
+	}
+
+	// Question nodes need a matching choice before any transition can fire
+	var choice *FlowChoice
+	if len(node.Choices) > 0 {
+		choice = s.MatchChoice(node, userInput)
+		if choice == nil {
+			return "", nil
+		}
+	}
+
+	return s.advance(sessionID, flow, node, choice)
+}
+
+// ProcessChoiceID memproses pilihan dari tombol frontend berdasarkan choice_id
+func (s *FlowService) ProcessChoiceID(sessionID string, flowID string, currentNodeID string, choiceID string) (string, *FlowNode, error) {
+	flow := s.GetFlow(flowID)
+	if flow == nil {
+		return "", nil, ErrNoActiveFlow
+	}
+	node := flow.GetNode(currentNodeID)
+	if node == nil {
+		return "", nil, ErrNoActiveFlow
+	}
+
+	choice := node.GetChoice(choiceID)
+	if choice == nil {
+		return "", nil, ErrUnknownChoice
+	}
+
+	nextNodeID, nextNode := s.advance(sessionID, flow, node, choice)
+	return nextNodeID, nextNode, nil
+}
+
+// advance menerapkan efek pilihan (jika ada) lalu mencari transition berikutnya
+func (s *FlowService) advance(sessionID string, flow *Flow, node *FlowNode, choice *FlowChoice) (string, *FlowNode) {
+	env := s.buildEnv(sessionID, flow.FlowID)
+
+	if choice != nil {
+		env["choice"] = map[string]interface{}{
+			"id":    choice.ID,
+			"label": choice.Label,
+			"value": choice.Value,
+		}
+
+		s.applyEffects(sessionID, flow, node, env)
+	}
+
+	nextNodeID := s.nextNodeID(node, env)
+	if nextNodeID == "" {
+		return "", nil
+	}
+
+	return nextNodeID, flow.GetNode(nextNodeID)
+}
+
+// ProcessUpload menyimpan dokumen untuk collect node dan mengembalikan node berikutnya.
+// Dokumen pertama yang lolos validasi dipakai; jika semua ditolak, alasan penolakan
+// dokumen pertama dikembalikan.
+func (s *FlowService) ProcessUpload(sessionID string, flowID string, currentNodeID string, documents []models.UploadedDocument) (string, *FlowNode, *models.UploadRejection) {
+	flow := s.GetFlow(flowID)
+	if flow == nil {
+		return "", nil, nil
+	}
+	node := flow.GetNode(currentNodeID)
+	if node == nil || node.Type != "collect" || node.Collect == nil {
+		return "", nil, nil
+	}
+
+	var firstRejection *models.UploadRejection
+	for _, doc := range documents {
+		stored, rejection := s.uploads.Store(sessionID, doc, node.Collect.Mime)
+		if rejection != nil {
+			if firstRejection == nil {
+				firstRejection = rejection
+			}
+			continue
+		}
+
+		if err := s.setContextValue(sessionID, flow, node.Collect.Key, stored.ID); err != nil {
+			log.Printf("⚠️  Failed to bind upload to %s: %v", node.Collect.Key, err)
+			continue
+		}
+		log.Printf("📎 Document %s bound to %s", doc.FileName, node.Collect.Key)
+
+		env := s.buildEnv(sessionID, flowID)
+		env["collect"] = map[string]interface{}{
+			"ok":  true,
+			"key": node.Collect.Key,
+		}
+
+		nextNodeID := s.nextNodeID(node, env)
+		if nextNodeID == "" {
+			return "", nil, nil
+		}
+		return nextNodeID, flow.GetNode(nextNodeID), nil
+	}
+
+	return "", nil, firstRejection
+}
+
+// MatchChoice mencocokkan input user dengan pilihan pada node
+func (s *FlowService) MatchChoice(node *FlowNode, userInput string) *FlowChoice {
+	userInputLower := strings.ToLower(strings.TrimSpace(userInput))
+
+	// Exact match with label or ID
+	for i, choice := range node.Choices {
+		if userInputLower == strings.ToLower(choice.Label) || userInputLower == strings.ToLower(choice.ID) {
+			return &node.Choices[i]
+		}
+	}
+
+	// Number input (1, 2, 3, etc.)
+	if n, err := strconv.Atoi(userInputLower); err == nil && n >= 1 && n <= len(node.Choices) {
+		return &node.Choices[n-1]
+	}
+
+	// Label mentioned as whole words inside a longer sentence ("Ya" must not match "saya");
+	// the longest label wins so "SIM A & C" is not mistaken for "SIM A". Anything else is
+	// left to the choice classifier.
+	var best *FlowChoice
+	for i, choice := range node.Choices {
+		if containsPhrase(userInputLower, choice.Label) && (best == nil || len(choice.Label) > len(best.Label)) {
+			best = &node.Choices[i]
+		}
+	}
+
+	return best
+}
+
+// containsPhrase mengecek apakah kata-kata phrase muncul berurutan sebagai kata utuh di text;
+// tanda baca diabaikan dan huruf besar/kecil tidak dibedakan
+func containsPhrase(text string, phrase string) bool {
+	words, phraseWords := phraseTokens(text), phraseTokens(phrase)
+	if len(phraseWords) == 0 {
+		return false
+	}
+	for start := 0; start+len(phraseWords) <= len(words); start++ {
+		matched := true
+		for i, word := range phraseWords {
+			if words[start+i] != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func phraseTokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// nextNodeID mengembalikan tujuan transition pertama yang kondisinya terpenuhi
+func (s *FlowService) nextNodeID(node *FlowNode, env map[string]interface{}) string {
+	for _, transition := range node.Transitions {
+		if transition.condition != nil && transition.condition.Eval(env) {
+			log.Printf("🔀 Transition matched on %s: %s -> %s", node.ID, transition.When, transition.To)
+			return transition.To
+		}
+	}
+	return ""
+}
+
+// applyEffects menjalankan efek on_select (set) ke context flow session
+func (s *FlowService) applyEffects(sessionID string, flow *Flow, node *FlowNode, env map[string]interface{}) {
+	sessionStore := GetSessionStore()
+
+	for _, effect := range node.OnSelect {
+		if effect.condition != nil && !effect.condition.Eval(env) {
+			continue
+		}
+
+		for key, rawValue := range effect.Set {
+			value := interpolateValue(rawValue, env)
+			if err := flow.ContextSchema.Validate(key, value); err != nil {
+				log.Printf("⚠️  Rejected on_select value on %s: %v", node.ID, err)
+				continue
+			}
+
+			sessionStore.SetFlowValue(sessionID, flow.FlowID, key, value)
+			setPath(env, key, value)
+			log.Printf("📝 Flow context set: %s = %v", key, value)
+		}
+	}
+}
+
+// setContextValue menyimpan output ke context flow, divalidasi jika key dideklarasikan di schema
+func (s *FlowService) setContextValue(sessionID string, flow *Flow, key string, value interface{}) error {
+	if flow.ContextSchema.Lookup(key) != nil {
+		if err := flow.ContextSchema.Validate(key, value); err != nil {
+			return err
+		}
+	}
+
+	GetSessionStore().SetFlowValue(sessionID, flow.FlowID, key, value)
+	return nil
+}
+
+// buildEnv menyiapkan variabel untuk evaluasi kondisi dari context flow session
+func (s *FlowService) buildEnv(sessionID string, flowID string) map[string]interface{} {
+	return GetSessionStore().GetFlowContext(sessionID, flowID)
+}
+
+// RunActions menjalankan action node dan melewati message node tanpa pilihan yang transition-nya
+// langsung terpenuhi, sampai tiba di node yang butuh input user. Teks message node yang
+// dilewati dikembalikan berurutan agar tetap disampaikan ke user.
+func (s *FlowService) RunActions(sessionID string, flowID string, nodeID string) (string, *ActionResult, []string) {
+	flow := s.GetFlow(flowID)
+	if flow == nil {
+		return nodeID, nil, nil
+	}
+
+	var lastResult *ActionResult
+	var messages []string
+
+	for i := 0; i < maxActionChain; i++ {
+		node := flow.GetNode(nodeID)
+		if node == nil {
+			return nodeID, lastResult, messages
+		}
+
+		if node.Type == "message" && len(node.Choices) == 0 {
+			// Informational node ("sedang menyusun berkas"): show it and move on if nothing is asked
+			env := s.buildEnv(sessionID, flowID)
+			nextNodeID := s.nextNodeID(node, env)
+			if nextNodeID == "" {
+				return nodeID, lastResult, messages
+			}
+			if text := strings.TrimSpace(renderText(node.Text, func(key string) string {
+				return formatContextValue(lookupPath(env, key))
+			})); text != "" {
+				messages = append(messages, text)
+			}
+			log.Printf("💬 Passing message node: %s", node.ID)
+			nodeID = nextNodeID
+			continue
+		}
+		if node.Type != "action" {
+			return nodeID, lastResult, messages
+		}
+
+		log.Printf("⚙️  Running action node: %s (%s)", node.ID, node.Action.Type)
+		lastResult = s.actions.Execute(sessionID, node.Action, s.buildEnv(sessionID, flowID))
+
+		if lastResult.OK && node.Action.OutputKey != "" {
+			if err := s.setContextValue(sessionID, flow, node.Action.OutputKey, lastResult.Output); err != nil {
+				log.Printf("⚠️  Failed to store action output: %v", err)
+			}
+		}
+
+		env := s.buildEnv(sessionID, flowID)
+		env["action"] = map[string]interface{}{
+			"ok":     lastResult.OK,
+			"output": lastResult.Output,
+		}
+
+		nextNodeID := s.nextNodeID(node, env)
+
+		if nextNodeID == "" {
+			log.Printf("⏸️  Action %s did not resolve a transition, staying on node", node.ID)
+			return nodeID, lastResult, messages
+		}
+		nodeID = nextNodeID
+	}
+
+	log.Printf("⚠️  Action chain limit reached at node: %s", nodeID)
+	return nodeID, lastResult, messages
+}
+
+// renderText mengganti placeholder {{key}} pada teks dengan nilai dari lookup
+func renderText(text string, lookup func(key string) string) string {
+	return templatePattern.ReplaceAllStringFunc(text, func(match string) string {
+		key := templatePattern.FindStringSubmatch(match)[1]
+		return lookup(key)
+	})
+}
+
+// GetFlowInfo returns info about the active flow for context
+func (s *FlowService) GetFlowInfo(sessionID string, flowID string, nodeID string) *models.FlowInfo {
+	flow := s.GetFlow(flowID)
+	if flow == nil {
+		return nil
+	}
+
+	node := flow.GetNode(nodeID)
+	if node == nil {
+		return nil
+	}
+
+	sessionStore := GetSessionStore()
+	flowContext := sessionStore.GetFlowContext(sessionID, flowID)
+
+	info := &models.FlowInfo{
+		FlowID:      flow.FlowID,
+		Title:       flow.Title,
+		Active:      !node.IsTerminal(),
+		CanGoBack:   !node.IsTerminal() && sessionStore.GetFlowStackDepth(sessionID, flowID) > 0,
+		CurrentNode: nodeID,
+		NodeType:    node.Type,
+		NodeText: renderText(node.Text, func(key string) string {
+			return formatContextValue(lookupPath(flowContext, key))
+		}),
+		Choices: []models.FlowChoiceInfo{},
+		Context: flowContext,
+	}
+
+	for _, choice := range node.Choices {
+		info.Choices = append(info.Choices, models.FlowChoiceInfo{
+			ID:    choice.ID,
+			Label: choice.Label,
+		})
+	}
+
+	return info
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ActionResult menyimpan hasil eksekusi action node pada flow
+type ActionResult struct {
+	Type    string
+	OK      bool
+	Output  string // Nilai yang disimpan ke output_key (misal URL berkas)
+	Message string // Pesan untuk user terkait hasil action
+}
+
+// ActionExecutor menjalankan satu jenis action untuk session tertentu dengan context flow-nya
+type ActionExecutor func(sessionID string, action *FlowAction, flowContext map[string]interface{}) (*ActionResult, error)
+
+// ActionRegistry menyimpan executor untuk setiap action.type pada flow
+type ActionRegistry struct {
+	executors map[string]ActionExecutor
+	fileStore *FileStore
+}
+
+// Tujuan handoff yang dikenal beserta pesan untuk user
+var handoffTargets = map[string]string{
+	"DIGITAL_KORLANTAS": "Silakan lanjutkan proses melalui aplikasi Digital Korlantas POLRI yang tersedia di Play Store dan App Store, Sobat Lantas.",
+}
+
+func NewActionRegistry(fileStore *FileStore) *ActionRegistry {
+	registry := &ActionRegistry{
+		executors: make(map[string]ActionExecutor),
+		fileStore: fileStore,
+	}
+
+	registry.Register("generate_zip", registry.generateZip)
+	registry.Register("handoff", registry.handoff)
+
+	log.Printf("✅ Action Registry initialized with %d action types", len(registry.executors))
+	return registry
+}
+
+// Register menambahkan executor untuk action type tertentu
+func (r *ActionRegistry) Register(actionType string, executor ActionExecutor) {
+	r.executors[actionType] = executor
+}
+
+// Execute menjalankan action sesuai action.type
+func (r *ActionRegistry) Execute(sessionID string, action *FlowAction, flowContext map[string]interface{}) *ActionResult {
+	if action == nil {
+		return &ActionResult{OK: false, Message: "Action tidak ditemukan pada node ini."}
+	}
+
+	executor, exists := r.executors[action.Type]
+	if !exists {
+		log.Printf("⚠️  Unknown action type: %s", action.Type)
+		return &ActionResult{Type: action.Type, OK: false, Message: "Action belum didukung oleh sistem."}
+	}
+
+	result, err := executor(sessionID, action, flowContext)
+	if err != nil {
+		log.Printf("❌ Action %s failed: %v", action.Type, err)
+		return &ActionResult{Type: action.Type, OK: false, Message: "Maaf, terjadi kendala saat memproses permintaan Anda."}
+	}
+	result.Type = action.Type
+
+	log.Printf("⚙️  Action %s executed (ok: %v)", action.Type, result.OK)
+	return result
+}
+
+// generateZip menyusun berkas ZIP dari dokumen yang sudah diupload user
+func (r *ActionRegistry) generateZip(sessionID string, action *FlowAction, flowContext map[string]interface{}) (*ActionResult, error) {
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	fileCount := 0
+
+	for _, input := range action.Inputs {
+		fileID, _ := lookupPath(flowContext, input).(string)
+		if fileID == "" {
+			log.Printf("   ⏭️  Input %s not uploaded, skipping", input)
+			continue
+		}
+
+		file, data, err := r.fileStore.Get(fileID)
+		if err != nil {
+			log.Printf("   ⚠️  Failed to read input %s (%s): %v", input, fileID, err)
+			continue
+		}
+		if file.SessionID != sessionID {
+			log.Printf("   ⚠️  Input %s (%s) belongs to another session, skipping", input, fileID)
+			continue
+		}
+
+		// Nama file di dalam ZIP mengikuti key, misal uploads.ktp -> ktp.jpg
+		name := input[strings.LastIndex(input, ".")+1:] + filepath.Ext(file.FileName)
+		writer, err := zipWriter.Create(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %s to zip: %w", name, err)
+		}
+		if _, err := writer.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to write %s to zip: %w", name, err)
+		}
+		fileCount++
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize zip: %w", err)
+	}
+
+	if fileCount == 0 {
+		return &ActionResult{
+			OK:      false,
+			Message: "Belum ada dokumen yang diupload untuk disusun menjadi berkas.",
+		}, nil
+	}
+
+	template := action.Template
+	if template == "" {
+		template = "berkas"
+	}
+	zipName := fmt.Sprintf("%s_%s.zip", template, time.Now().Format("20060102150405"))
+
+	stored, err := r.fileStore.Save(sessionID, zipName, "application/zip", buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("📦 Generated package %s with %d file(s)", zipName, fileCount)
+
+	return &ActionResult{
+		OK:      true,
+		Output:  r.fileStore.URL(stored),
+		Message: fmt.Sprintf("Berkas berisi %d dokumen sudah siap diunduh.", fileCount),
+	}, nil
+}
+
+// handoff mengarahkan user ke layanan lain (misal Digital Korlantas)
+func (r *ActionRegistry) handoff(sessionID string, action *FlowAction, flowContext map[string]interface{}) (*ActionResult, error) {
+	message, exists := handoffTargets[action.Target]
+	if !exists {
+		return nil, fmt.Errorf("unknown handoff target: %s", action.Target)
+	}
+
+	sessionStore := GetSessionStore()
+	sessionStore.SetData(sessionID, "handoff_target", action.Target)
+	sessionStore.SetData(sessionID, "handoff_reason", action.Reason)
+
+	log.Printf("🤝 Handoff to %s (reason: %s)", action.Target, action.Reason)
+
+	return &ActionResult{
+		OK:      true,
+		Output:  action.Target,
+		Message: message,
+	}, nil
+}
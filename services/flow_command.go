@@ -0,0 +1,116 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"police-assistant-backend/models"
+	"strings"
+)
+
+// Perintah navigasi flow yang bisa diketik user atau dipanggil lewat REST
+const (
+	FlowCommandBack    = "back"
+	FlowCommandRestart = "restart"
+	FlowCommandCancel  = "cancel"
+	FlowCommandResume  = "resume"
+)
+
+var (
+	ErrNoActiveFlow   = errors.New("no active flow in session")
+	ErrNoPreviousNode = errors.New("no previous node to go back to")
+	ErrNoPausedFlow   = errors.New("no cancelled flow to resume")
+	ErrStaleFlowNode  = errors.New("flow has moved past the requested node")
+	ErrUnknownChoice  = errors.New("choice is not available on the current node")
+)
+
+// Frasa yang dikenali sebagai perintah navigasi; dicocokkan dengan seluruh pesan
+// agar kalimat biasa seperti "saya mau kembali ke kantor" tidak dianggap perintah
+var flowCommandPhrases = map[string][]string{
+	FlowCommandBack:    {"kembali", "balik", "sebelumnya", "mundur", "back"},
+	FlowCommandRestart: {"ulang dari awal", "ulangi dari awal", "mulai dari awal", "mulai ulang", "ulang", "restart"},
+	FlowCommandCancel:  {"batal", "batalkan", "keluar", "berhenti", "stop", "cancel"},
+	FlowCommandResume:  {"lanjutkan", "lanjut lagi", "lanjutkan lagi", "resume"},
+}
+
+// DetectCommand mengenali perintah navigasi flow dari pesan user. Jika pesan sama persis
+// dengan label atau ID pilihan di node saat ini (misal pilihan "Kembali"), pesan diperlakukan
+// sebagai pilihan, bukan perintah. node boleh nil jika tidak ada flow aktif.
+func (s *FlowService) DetectCommand(message string, node *FlowNode) string {
+	normalized := strings.Trim(strings.ToLower(strings.TrimSpace(message)), ".!? ")
+
+	if node != nil {
+		for _, choice := range node.Choices {
+			if normalized == strings.ToLower(choice.Label) || normalized == strings.ToLower(choice.ID) {
+				return ""
+			}
+		}
+	}
+
+	for command, phrases := range flowCommandPhrases {
+		for _, phrase := range phrases {
+			if normalized == phrase {
+				return command
+			}
+		}
+	}
+	return ""
+}
+
+// ExecuteCommand menjalankan perintah navigasi pada flow session dan
+// mengembalikan info node tujuan
+func (s *FlowService) ExecuteCommand(sessionID string, command string) (*models.FlowInfo, error) {
+	sessionStore := GetSessionStore()
+	flowID, nodeID := sessionStore.GetActiveFlow(sessionID)
+
+	switch command {
+	case FlowCommandBack:
+		if flowID == "" {
+			return nil, ErrNoActiveFlow
+		}
+		previousNodeID, ok := sessionStore.PopFlowNode(sessionID, flowID)
+		if !ok {
+			return nil, ErrNoPreviousNode
+		}
+		log.Printf("↩️  Flow %s back: %s -> %s", flowID, nodeID, previousNodeID)
+		nodeID = previousNodeID
+
+	case FlowCommandRestart:
+		if flowID == "" {
+			return nil, ErrNoActiveFlow
+		}
+		nodeID = s.StartFlow(sessionID, flowID)
+		log.Printf("🔄 Flow %s restarted", flowID)
+
+	case FlowCommandCancel:
+		if flowID == "" {
+			return nil, ErrNoActiveFlow
+		}
+		sessionStore.PauseFlow(sessionID)
+		log.Printf("🛑 Flow %s cancelled at node: %s", flowID, nodeID)
+
+	case FlowCommandResume:
+		if flowID != "" {
+			break
+		}
+		flowID, nodeID = sessionStore.ResumeFlow(sessionID)
+		if flowID == "" {
+			return nil, ErrNoPausedFlow
+		}
+		log.Printf("▶️  Flow %s resumed at node: %s", flowID, nodeID)
+
+	default:
+		return nil, errors.New("unknown flow command: " + command)
+	}
+
+	info := s.GetFlowInfo(sessionID, flowID, nodeID)
+	if info == nil {
+		return nil, ErrNoActiveFlow
+	}
+	if command == FlowCommandCancel {
+		info.Active = false
+		info.CanGoBack = false
+	}
+	info.Command = command
+
+	return info, nil
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Condition adalah ekspresi "when" pada flow yang sudah di-parse.
+//
+// Sintaks yang didukung:
+//   - literal: 'teks', "teks", angka, true, false, null
+//   - variabel: choice.id, choice.value, collect.ok, action.ok, sim_type, uploads.ktp
+//   - operator: ==, !=, &&, ||, ! dan tanda kurung
+type Condition struct {
+	source string
+	root   conditionNode
+}
+
+type conditionNode interface {
+	eval(env map[string]interface{}) interface{}
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+type variableNode struct {
+	path string
+}
+
+type notNode struct {
+	operand conditionNode
+}
+
+type binaryNode struct {
+	op          string
+	left, right conditionNode
+}
+
+// ParseCondition mem-parse ekspresi "when"; ekspresi kosong selalu bernilai true
+func ParseCondition(source string) (*Condition, error) {
+	if strings.TrimSpace(source) == "" {
+		return &Condition{source: source, root: literalNode{value: true}}, nil
+	}
+
+	tokens, err := tokenizeCondition(source)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &conditionParser{tokens: tokens}
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", source, err)
+	}
+	if !parser.done() {
+		return nil, fmt.Errorf("invalid condition %q: unexpected %q", source, parser.peek().text)
+	}
+
+	return &Condition{source: source, root: root}, nil
+}
+
+// Eval mengevaluasi kondisi terhadap environment (choice, collect, action, context)
+func (c *Condition) Eval(env map[string]interface{}) bool {
+	return truthy(c.root.eval(env))
+}
+
+func (c *Condition) String() string {
+	return c.source
+}
+
+func (n literalNode) eval(env map[string]interface{}) interface{} {
+	return n.value
+}
+
+func (n variableNode) eval(env map[string]interface{}) interface{} {
+	return lookupPath(env, n.path)
+}
+
+func (n notNode) eval(env map[string]interface{}) interface{} {
+	return !truthy(n.operand.eval(env))
+}
+
+func (n binaryNode) eval(env map[string]interface{}) interface{} {
+	switch n.op {
+	case "&&":
+		return truthy(n.left.eval(env)) && truthy(n.right.eval(env))
+	case "||":
+		return truthy(n.left.eval(env)) || truthy(n.right.eval(env))
+	case "==":
+		return valuesEqual(n.left.eval(env), n.right.eval(env))
+	case "!=":
+		return !valuesEqual(n.left.eval(env), n.right.eval(env))
+	}
+	return false
+}
+
+// lookupPath mengambil nilai "a.b.c" dari env; key utuh dicek lebih dulu
+func lookupPath(env map[string]interface{}, path string) interface{} {
+	if value, exists := env[path]; exists {
+		return value
+	}
+
+	var current interface{} = env
+	for _, part := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current, ok = m[part]
+		if !ok {
+			return nil
+		}
+	}
+	return current
+}
+
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != "" && v != "false"
+	case float64:
+		return v != 0
+	case int:
+		return v != 0
+	}
+	return true
+}
+
+func valuesEqual(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	if af, ok := toFloat(a); ok {
+		if bf, ok := toFloat(b); ok {
+			return af == bf
+		}
+	}
+
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// Tokenizer
+
+type conditionToken struct {
+	kind string // ident, string, number, op
+	text string
+}
+
+func tokenizeCondition(source string) ([]conditionToken, error) {
+	var tokens []conditionToken
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '\'' || r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated string in %q", source)
+			}
+			tokens = append(tokens, conditionToken{kind: "string", text: string(runes[i+1 : end])})
+			i = end + 1
+
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			end := i + 1
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, conditionToken{kind: "number", text: string(runes[i:end])})
+			i = end
+
+		case unicode.IsLetter(r) || r == '_':
+			end := i + 1
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_' || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, conditionToken{kind: "ident", text: string(runes[i:end])})
+			i = end
+
+		default:
+			if i+1 < len(runes) {
+				pair := string(runes[i : i+2])
+				if pair == "==" || pair == "!=" || pair == "&&" || pair == "||" {
+					tokens = append(tokens, conditionToken{kind: "op", text: pair})
+					i += 2
+					continue
+				}
+			}
+			if r == '!' || r == '(' || r == ')' {
+				tokens = append(tokens, conditionToken{kind: "op", text: string(r)})
+				i++
+				continue
+			}
+			return nil, fmt.Errorf("unexpected character %q in %q", r, source)
+		}
+	}
+
+	return tokens, nil
+}
+
+// Parser (recursive descent): or -> and -> not -> comparison -> primary
+
+type conditionParser struct {
+	tokens []conditionToken
+	pos    int
+}
+
+func (p *conditionParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *conditionParser) peek() conditionToken {
+	if p.done() {
+		return conditionToken{}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *conditionParser) acceptOp(op string) bool {
+	if tok := p.peek(); tok.kind == "op" && tok.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *conditionParser) parseOr() (conditionNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd() (conditionNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("&&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseNot() (conditionNode, error) {
+	if p.acceptOp("!") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *conditionParser) parseComparison() (conditionNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!="} {
+		if p.acceptOp(op) {
+			right, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			return binaryNode{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parsePrimary() (conditionNode, error) {
+	if p.done() {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	tok := p.tokens[p.pos]
+	p.pos++
+
+	switch tok.kind {
+	case "string":
+		return literalNode{value: tok.text}, nil
+	case "number":
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.text)
+		}
+		return literalNode{value: value}, nil
+	case "ident":
+		switch tok.text {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		case "null":
+			return literalNode{value: nil}, nil
+		}
+		if strings.HasSuffix(tok.text, ".") || strings.Contains(tok.text, "..") {
+			return nil, fmt.Errorf("invalid variable %q", tok.text)
+		}
+		return variableNode{path: tok.text}, nil
+	case "op":
+		if tok.text == "(" {
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.acceptOp(")") {
+				return nil, fmt.Errorf("missing closing parenthesis")
+			}
+			return inner, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected %q", tok.text)
+}
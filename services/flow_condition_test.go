@@ -0,0 +1,70 @@
+package services
+
+import "testing"
+
+func TestConditionEval(t *testing.T) {
+	env := map[string]interface{}{
+		"choice":       map[string]interface{}{"id": "sim_c", "value": "C"},
+		"action":       map[string]interface{}{"ok": false},
+		"ever_had_sim": true,
+		"age":          float64(17),
+		"uploads.ktp":  "file-1",
+		"empty":        "",
+	}
+
+	tests := []struct {
+		when string
+		want bool
+	}{
+		{"", true},
+		{"true", true},
+		{"false", false},
+		{"choice.id == 'sim_c'", true},
+		{`choice.id == "sim_a"`, false},
+		{"choice.id != 'sim_a'", true},
+		{"ever_had_sim", true},
+		{"!ever_had_sim", false},
+		{"action.ok", false},
+		{"!action.ok", true},
+		{"age == 17", true},
+		{"age == 17.0", true},
+		{"age == -1", false},
+		{"uploads.ktp", true},
+		{"uploads.sim_lama", false},
+		{"missing == null", true},
+		{"empty", false},
+		{"choice.id == 'sim_a' || choice.id == 'sim_c'", true},
+		{"choice.id == 'sim_c' && action.ok", false},
+		{"choice.id == 'sim_c' && (action.ok || ever_had_sim)", true},
+		{"!(choice.id == 'sim_c')", false},
+		{"choice.id == 'sim_a' || choice.id == 'sim_c' && !ever_had_sim", false},
+	}
+	for _, tt := range tests {
+		condition, err := ParseCondition(tt.when)
+		if err != nil {
+			t.Errorf("ParseCondition(%q): %v", tt.when, err)
+			continue
+		}
+		if got := condition.Eval(env); got != tt.want {
+			t.Errorf("Eval(%q) = %v, want %v", tt.when, got, tt.want)
+		}
+	}
+}
+
+func TestParseConditionRejectsInvalid(t *testing.T) {
+	for _, when := range []string{
+		"choice.id == 'sim_c",
+		"choice.id = 'sim_c'",
+		"choice.id ==",
+		"(choice.id == 'sim_c'",
+		"choice.id == 'sim_c')",
+		"choice. == 'x'",
+		"choice..id",
+		"&& true",
+		"a b",
+	} {
+		if _, err := ParseCondition(when); err == nil {
+			t.Errorf("ParseCondition(%q) succeeded, want an error", when)
+		}
+	}
+}
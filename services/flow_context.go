@@ -0,0 +1,137 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContextProperty mendefinisikan tipe satu key pada context_schema flow
+type ContextProperty struct {
+	Type       string                      `json:"type"` // string, boolean, number, integer, object
+	Enum       []interface{}               `json:"enum,omitempty"`
+	Properties map[string]*ContextProperty `json:"properties,omitempty"`
+}
+
+// ContextSchema adalah definisi context_schema pada file flow
+type ContextSchema map[string]*ContextProperty
+
+// FlowEffect adalah satu efek on_select, misal { "set": { "sim_type": "{{choice.value}}" } }
+type FlowEffect struct {
+	Set       map[string]interface{} `json:"set"`
+	When      string                 `json:"when,omitempty"`
+	condition *Condition
+}
+
+// Lookup mencari definisi property berdasarkan key bertitik (misal uploads.ktp)
+func (schema ContextSchema) Lookup(key string) *ContextProperty {
+	parts := strings.Split(key, ".")
+
+	property, exists := schema[parts[0]]
+	for _, part := range parts[1:] {
+		if !exists || property == nil || property.Properties == nil {
+			return nil
+		}
+		property, exists = property.Properties[part]
+	}
+
+	if !exists {
+		return nil
+	}
+	return property
+}
+
+// Validate memastikan value sesuai tipe dan enum yang dideklarasikan untuk key
+func (schema ContextSchema) Validate(key string, value interface{}) error {
+	property := schema.Lookup(key)
+	if property == nil {
+		return fmt.Errorf("context key %q is not declared in context_schema", key)
+	}
+
+	switch property.Type {
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("context key %q expects string, got %T", key, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("context key %q expects boolean, got %T", key, value)
+		}
+	case "number", "integer":
+		number, ok := toFloat(value)
+		if !ok {
+			return fmt.Errorf("context key %q expects %s, got %T", key, property.Type, value)
+		}
+		if property.Type == "integer" && number != float64(int64(number)) {
+			return fmt.Errorf("context key %q expects integer, got %v", key, value)
+		}
+	case "object":
+		if _, ok := value.(map[string]interface{}); !ok {
+			return fmt.Errorf("context key %q expects object, got %T", key, value)
+		}
+	}
+
+	if len(property.Enum) > 0 {
+		for _, allowed := range property.Enum {
+			if valuesEqual(allowed, value) {
+				return nil
+			}
+		}
+		return fmt.Errorf("context key %q does not allow value %v (allowed: %v)", key, value, property.Enum)
+	}
+
+	return nil
+}
+
+// interpolateValue mengganti placeholder {{...}} pada nilai efek.
+// Jika nilai hanya berisi satu placeholder, tipe aslinya dipertahankan
+// sehingga "{{choice.value}}" tetap menjadi boolean untuk pilihan Ya/Tidak.
+func interpolateValue(value interface{}, env map[string]interface{}) interface{} {
+	text, ok := value.(string)
+	if !ok {
+		return value
+	}
+
+	if match := templatePattern.FindStringSubmatch(text); match != nil && match[0] == strings.TrimSpace(text) {
+		return lookupPath(env, match[1])
+	}
+
+	return renderText(text, func(key string) string {
+		return formatContextValue(lookupPath(env, key))
+	})
+}
+
+// formatContextValue mengubah nilai context menjadi teks untuk ditampilkan
+func formatContextValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// setPath menyimpan value ke map bersarang berdasarkan key bertitik
+func setPath(target map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	current := target
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := current[part].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			current[part] = next
+		}
+		current = next
+	}
+	current[parts[len(parts)-1]] = value
+}
+
+// copyContext membuat salinan dalam dari flow context
+func copyContext(source map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(source))
+	for key, value := range source {
+		if nested, ok := value.(map[string]interface{}); ok {
+			result[key] = copyContext(nested)
+		} else {
+			result[key] = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Tingkat keparahan temuan lint flow
+const (
+	LintError   = "error"   // Flow tidak bisa berjalan dengan benar, tidak dimuat saat startup
+	LintWarning = "warning" // Kemungkinan kesalahan desain flow
+)
+
+// Node yang boleh menjadi akhir flow tanpa transition
+const flowEndNode = "end"
+
+// FlowIssue adalah satu temuan validasi statis pada definisi flow
+type FlowIssue struct {
+	FlowID   string
+	NodeID   string
+	Severity string
+	Code     string
+	Message  string
+}
+
+func (i FlowIssue) String() string {
+	location := i.FlowID
+	if i.NodeID != "" {
+		location += "/" + i.NodeID
+	}
+	return fmt.Sprintf("%s: %s [%s] %s", i.Severity, location, i.Code, i.Message)
+}
+
+// HasLintErrors mengecek apakah ada temuan dengan tingkat error
+func HasLintErrors(issues []FlowIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == LintError {
+			return true
+		}
+	}
+	return false
+}
+
+// LintFlow memvalidasi struktur flow: entry_node, ID node atau pilihan ganda, transition ke
+// node yang tidak ada, node yang tidak terjangkau, dead end selain "end", pilihan tanpa
+// transition, dan key on_select yang tidak dideklarasikan di context_schema. Kondisi when
+// yang tidak bisa di-parse sudah ditolak ParseFlow.
+func LintFlow(flow *Flow) []FlowIssue {
+	var issues []FlowIssue
+	report := func(nodeID string, severity string, code string, format string, args ...interface{}) {
+		issues = append(issues, FlowIssue{
+			FlowID:   flow.FlowID,
+			NodeID:   nodeID,
+			Severity: severity,
+			Code:     code,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	if flow.FlowID == "" {
+		report("", LintError, "missing_flow_id", "flow_id is required")
+	}
+	if flow.EntryNode == "" {
+		report("", LintError, "missing_entry_node", "entry_node is required")
+	} else if flow.GetNode(flow.EntryNode) == nil {
+		report("", LintError, "unknown_entry_node", "entry_node %q does not exist", flow.EntryNode)
+	}
+
+	seen := make(map[string]bool)
+	for i := range flow.Nodes {
+		node := &flow.Nodes[i]
+
+		if node.ID == "" {
+			report("", LintError, "missing_node_id", "node #%d has no id", i)
+			continue
+		}
+		if seen[node.ID] {
+			report(node.ID, LintError, "duplicate_node_id", "node id %q is defined more than once", node.ID)
+		}
+		seen[node.ID] = true
+
+		switch node.Type {
+		case "message", "question":
+		case "collect":
+			if node.Collect == nil || node.Collect.Key == "" {
+				report(node.ID, LintError, "missing_collect", "collect node needs collect.key")
+			} else if flow.ContextSchema.Lookup(node.Collect.Key) == nil {
+				report(node.ID, LintWarning, "undeclared_context_key", "collect key %q is not declared in context_schema", node.Collect.Key)
+			}
+		case "action":
+			if node.Action == nil || node.Action.Type == "" {
+				report(node.ID, LintError, "missing_action", "action node needs action.type")
+			}
+		default:
+			report(node.ID, LintError, "unknown_node_type", "unknown node type %q", node.Type)
+		}
+
+		for _, transition := range node.Transitions {
+			if flow.GetNode(transition.To) == nil {
+				report(node.ID, LintError, "dangling_transition", "transition %q points to missing node %q", transition.When, transition.To)
+			}
+		}
+
+		if len(node.Transitions) == 0 && node.ID != flowEndNode {
+			report(node.ID, LintWarning, "dead_end", "node has no transitions; only %q should end the flow", flowEndNode)
+		}
+
+		lintChoices(flow, node, report)
+	}
+
+	for _, nodeID := range unreachableNodes(flow) {
+		report(nodeID, LintWarning, "unreachable_node", "node cannot be reached from entry_node %q", flow.EntryNode)
+	}
+
+	return issues
+}
+
+// lintChoices mengecek setiap pilihan punya transition dan efek on_select-nya valid
+func lintChoices(flow *Flow, node *FlowNode, report func(string, string, string, string, ...interface{})) {
+	for _, effect := range node.OnSelect {
+		for key := range effect.Set {
+			if flow.ContextSchema.Lookup(key) == nil {
+				report(node.ID, LintError, "undeclared_context_key", "on_select sets %q which is not declared in context_schema", key)
+			}
+		}
+	}
+
+	seen := make(map[string]bool, len(node.Choices))
+	for _, choice := range node.Choices {
+		if seen[choice.ID] {
+			report(node.ID, LintError, "duplicate_choice_id", "choice id %q is defined more than once", choice.ID)
+		}
+		seen[choice.ID] = true
+
+		env := map[string]interface{}{
+			"choice": map[string]interface{}{
+				"id":    choice.ID,
+				"label": choice.Label,
+				"value": choice.Value,
+			},
+		}
+
+		// Conditions that also read flow context may match at runtime even if
+		// they do not match here; the choice-only env catches the common typo case.
+		matched := false
+		for _, transition := range node.Transitions {
+			if transition.condition != nil && transition.condition.Eval(env) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			report(node.ID, LintWarning, "choice_without_transition", "choice %q has no matching transition", choice.ID)
+		}
+
+		for _, effect := range node.OnSelect {
+			if effect.condition != nil && !effect.condition.Eval(env) {
+				continue
+			}
+			for key, rawValue := range effect.Set {
+				if flow.ContextSchema.Lookup(key) == nil {
+					continue
+				}
+				value := interpolateValue(rawValue, env)
+				if value == nil {
+					continue
+				}
+				if err := flow.ContextSchema.Validate(key, value); err != nil {
+					report(node.ID, LintWarning, "invalid_context_value", "choice %q: %v", choice.ID, err)
+				}
+			}
+		}
+	}
+}
+
+// unreachableNodes mengembalikan node yang tidak bisa dicapai dari entry_node
+func unreachableNodes(flow *Flow) []string {
+	if flow.GetNode(flow.EntryNode) == nil {
+		return nil
+	}
+
+	visited := map[string]bool{flow.EntryNode: true}
+	queue := []string{flow.EntryNode}
+	for len(queue) > 0 {
+		node := flow.GetNode(queue[0])
+		queue = queue[1:]
+		if node == nil {
+			continue
+		}
+		for _, transition := range node.Transitions {
+			if !visited[transition.To] {
+				visited[transition.To] = true
+				queue = append(queue, transition.To)
+			}
+		}
+	}
+
+	var unreachable []string
+	for _, node := range flow.Nodes {
+		if node.ID != "" && !visited[node.ID] {
+			unreachable = append(unreachable, node.ID)
+		}
+	}
+	sort.Strings(unreachable)
+	return unreachable
+}
+
+// ExportDOT menghasilkan graph flow dalam format Graphviz DOT
+func ExportDOT(flow *Flow) string {
+	shapes := map[string]string{
+		"message":  "box",
+		"question": "diamond",
+		"collect":  "folder",
+		"action":   "component",
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", flow.FlowID)
+	b.WriteString("  rankdir=TB;\n")
+	b.WriteString("  node [fontname=\"Helvetica\", fontsize=10];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=9];\n")
+
+	for _, node := range flow.Nodes {
+		shape := shapes[node.Type]
+		if shape == "" {
+			shape = "ellipse"
+		}
+		style := ""
+		if node.ID == flow.EntryNode {
+			style = ", style=bold"
+		}
+		fmt.Fprintf(&b, "  %q [label=%q, shape=%s%s];\n", node.ID, node.ID+"\n("+node.Type+")", shape, style)
+	}
+
+	for _, node := range flow.Nodes {
+		for _, transition := range node.Transitions {
+			fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", node.ID, transition.To, transition.When)
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// ExportMermaid menghasilkan graph flow dalam format Mermaid flowchart
+func ExportMermaid(flow *Flow) string {
+	escape := func(text string) string {
+		return strings.ReplaceAll(text, `"`, "#quot;")
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+
+	for _, node := range flow.Nodes {
+		label := escape(node.ID + " (" + node.Type + ")")
+		switch node.Type {
+		case "question":
+			fmt.Fprintf(&b, "  %s{\"%s\"}\n", node.ID, label)
+		case "collect":
+			fmt.Fprintf(&b, "  %s[/\"%s\"/]\n", node.ID, label)
+		case "action":
+			fmt.Fprintf(&b, "  %s[[\"%s\"]]\n", node.ID, label)
+		default:
+			fmt.Fprintf(&b, "  %s[\"%s\"]\n", node.ID, label)
+		}
+	}
+
+	for _, node := range flow.Nodes {
+		for _, transition := range node.Transitions {
+			fmt.Fprintf(&b, "  %s -->|\"%s\"| %s\n", node.ID, escape(transition.When), transition.To)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestFlowsLint menjalankan lint strict terhadap semua flow di flows/, sama seperti
+// cmd/flowlint -strict, agar go test menangkap flow yang rusak
+func TestFlowsLint(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join(filepath.Dir(testFlowFile), "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("no flows in %s", filepath.Dir(testFlowFile))
+	}
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			flow, err := LoadFlowFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, issue := range LintFlow(flow) {
+				t.Error(issue)
+			}
+		})
+	}
+}
+
+// lintTestFlow adalah flow kecil yang bersih; setiap kasus mengganti satu bagian
+const lintTestFlow = `{
+  "flow_id": "lint_test",
+  "entry_node": "start",
+  "context_schema": { "answer": { "type": "string" } },
+  "nodes": [
+    {
+      "id": "start",
+      "type": "question",
+      "text": "Pilih",
+      "choices": [
+        { "id": "yes", "label": "Ya", "value": "Y" },
+        { "id": "no", "label": "Tidak", "value": "N" }
+      ],
+      "on_select": [{ "set": { "answer": "{{choice.value}}" } }],
+      "transitions": [
+        { "when": "choice.id == 'yes'", "to": "end" },
+        { "when": "choice.id == 'no'", "to": "end" }
+      ]
+    },
+    { "id": "end", "type": "message", "text": "Selesai" }
+  ]
+}`
+
+func TestLintFlow(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     string
+		new     string
+		code    string // "" = no issues
+		isError bool
+	}{
+		{name: "clean"},
+		{
+			name: "unreachable node",
+			old:  `{ "id": "end", "type": "message", "text": "Selesai" }`,
+			new:  `{ "id": "end", "type": "message", "text": "Selesai" }, { "id": "orphan", "type": "message", "text": "x", "transitions": [{ "when": "true", "to": "end" }] }`,
+			code: "unreachable_node",
+		},
+		{
+			name:    "dangling next",
+			old:     `{ "when": "choice.id == 'no'", "to": "end" }`,
+			new:     `{ "when": "choice.id == 'no'", "to": "finish" }`,
+			code:    "dangling_transition",
+			isError: true,
+		},
+		{
+			name:    "duplicate choice id",
+			old:     `{ "id": "no", "label": "Tidak", "value": "N" }`,
+			new:     `{ "id": "yes", "label": "Tidak", "value": "N" }`,
+			code:    "duplicate_choice_id",
+			isError: true,
+		},
+		{
+			name: "choice without transition",
+			old:  `{ "when": "choice.id == 'no'", "to": "end" }`,
+			new:  `{ "when": "choice.id == 'nope'", "to": "end" }`,
+			code: "choice_without_transition",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flow, err := ParseFlow([]byte(strings.Replace(lintTestFlow, tt.old, tt.new, 1)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			issues := LintFlow(flow)
+			if tt.code == "" {
+				for _, issue := range issues {
+					t.Error(issue)
+				}
+				return
+			}
+
+			found := false
+			for _, issue := range issues {
+				found = found || issue.Code == tt.code
+			}
+			if !found {
+				t.Fatalf("issues %v, want %s", issues, tt.code)
+			}
+			if HasLintErrors(issues) != tt.isError {
+				t.Errorf("HasLintErrors = %v, want %v", !tt.isError, tt.isError)
+			}
+		})
+	}
+}
+
+// TestParseFlowRejectsInvalidWhen: kondisi yang tidak bisa di-parse gagal saat load, sebelum lint
+func TestParseFlowRejectsInvalidWhen(t *testing.T) {
+	data := strings.Replace(lintTestFlow, `"choice.id == 'no'"`, `"choice.id = 'no'"`, 1)
+	if _, err := ParseFlow([]byte(data)); err == nil || !strings.Contains(err.Error(), "node start transition 1") {
+		t.Fatalf("err = %v, want a parse error for node start transition 1", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "broken.json")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFlowFile(path); err == nil {
+		t.Fatal("LoadFlowFile accepted an unparseable when")
+	}
+}
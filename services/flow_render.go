@@ -0,0 +1,56 @@
+package services
+
+import (
+	"fmt"
+	"police-assistant-backend/models"
+	"strings"
+)
+
+// Mode balasan saat flow aktif (FLOW_REPLY_MODE)
+const (
+	FlowReplyDeterministic = "deterministic" // Teks node dirender langsung tanpa LLM
+	FlowReplyLLM           = "llm"           // Teks node disampaikan ulang oleh LLM
+)
+
+// RenderReply menyusun balasan untuk user langsung dari node flow:
+// hasil action (jika ada), teks node, lalu pilihan sebagai list bernomor
+func (s *FlowService) RenderReply(info *models.FlowInfo) string {
+	if info == nil {
+		return ""
+	}
+
+	title := info.Title
+	if title == "" {
+		title = info.FlowID
+	}
+
+	if info.Command == FlowCommandCancel {
+		return fmt.Sprintf("Baik, Sobat Lantas. Alur %s sudah dibatalkan.\nKetik \"lanjutkan\" jika ingin melanjutkan dari langkah terakhir.", title)
+	}
+
+	var parts []string
+
+	if action := info.Action; action != nil && action.Message != "" {
+		line := action.Message
+		if action.OK && strings.HasPrefix(action.Output, "http") {
+			line += "\n" + action.Output
+		}
+		parts = append(parts, line)
+	}
+
+	parts = append(parts, info.Messages...)
+
+	if text := strings.TrimSpace(info.NodeText); text != "" {
+		parts = append(parts, text)
+	}
+
+	if len(info.Choices) > 0 {
+		lines := make([]string, len(info.Choices))
+		for i, choice := range info.Choices {
+			lines[i] = fmt.Sprintf("%d. %s", i+1, choice.Label)
+		}
+		parts = append(parts, strings.Join(lines, "\n"))
+	}
+
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,107 @@
+package services
+
+import "testing"
+
+const testFlowFile = "../flows/perpanjangan_sim.json"
+
+func TestMatchChoice(t *testing.T) {
+	flow, err := LoadFlowFile(testFlowFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := &FlowService{}
+
+	tests := []struct {
+		node  string
+		input string
+		want  string // "" = no match, left to the choice classifier
+	}{
+		{"start", "SIM C", "sim_c"},
+		{"start", "sim_ac", "sim_ac"},
+		{"start", "2", "sim_c"},
+		{"start", "4", ""},
+		{"start", "saya mau perpanjang SIM A", "sim_a"},
+		{"start", "sim a & c dong", "sim_ac"},
+		{"start", "SIM A & C", "sim_ac"},
+		{"start", "simcard saya hilang", ""},
+		{"ask_ever_had_sim", "Ya", "ever_yes"},
+		{"ask_ever_had_sim", "ya, sudah", "ever_yes"},
+		{"ask_ever_had_sim", "saya tidak pernah punya sim", ""},
+		{"ask_ever_had_sim", "punya dong", ""},
+		{"ask_ever_had_sim", "belum pernah sama sekali", "ever_no"},
+		{"ask_sim_validity", "ya, masih berlaku kok", "valid_yes"},
+	}
+	for _, tt := range tests {
+		node := flow.GetNode(tt.node)
+		if node == nil {
+			t.Fatalf("node %s not found in %s", tt.node, testFlowFile)
+		}
+		got := ""
+		if choice := service.MatchChoice(node, tt.input); choice != nil {
+			got = choice.ID
+		}
+		if got != tt.want {
+			t.Errorf("MatchChoice(%s, %q) = %q, want %q", tt.node, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDetectIntentMatchesWholeWords(t *testing.T) {
+	flow, err := LoadFlowFile(testFlowFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := &FlowService{flows: map[string]*Flow{flow.FlowID: flow}, order: []string{flow.FlowID}}
+
+	tests := []struct {
+		message string
+		want    bool
+	}{
+		{"saya mau perpanjang SIM", true},
+		{"Perpanjangan SIM C dong", true},
+		{"bikin sim a gimana?", true},
+		{"syarat sim apa saja", false},
+		{"simcard saya hilang", false},
+		{"cek tilang B 1234 SV", false},
+	}
+	for _, tt := range tests {
+		if got := service.DetectIntent(tt.message) != nil; got != tt.want {
+			t.Errorf("DetectIntent(%q) matched = %v, want %v", tt.message, got, tt.want)
+		}
+	}
+}
+
+// TestRunActionsPassesMessageNodes: message node tanpa pilihan dengan transition "true" tidak
+// menunggu pesan user berikutnya, tetapi teksnya tetap disampaikan
+func TestRunActionsPassesMessageNodes(t *testing.T) {
+	flow, err := LoadFlowFile(testFlowFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := &FlowService{flows: map[string]*Flow{flow.FlowID: flow}, order: []string{flow.FlowID}}
+	sessionID, _, err := GetSessionStore().CreateSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	GetSessionStore().StartFlow(sessionID, flow.FlowID, flow.EntryNode)
+
+	tests := []struct {
+		from     string
+		want     string
+		messages int
+	}{
+		{"expired_to_new_info", "new_sim_offer_help", 1},
+		{"renewal_compiling", "renewal_ready", 1},
+		{"ask_ever_had_sim", "ask_ever_had_sim", 0},
+		{"end", "end", 0},
+	}
+	for _, tt := range tests {
+		nodeID, result, messages := service.RunActions(sessionID, flow.FlowID, tt.from)
+		if nodeID != tt.want || result != nil || len(messages) != tt.messages {
+			t.Errorf("RunActions(%s) = %s, %v, %q; want %s with %d message(s)", tt.from, nodeID, result, messages, tt.want, tt.messages)
+		}
+	}
+	if _, _, messages := service.RunActions(sessionID, flow.FlowID, "expired_to_new_info"); len(messages) == 1 && messages[0] != flow.GetNode("expired_to_new_info").Text {
+		t.Errorf("message = %q, want the node text", messages[0])
+	}
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	ctx "context"
+	"fmt"
+	"log"
+	"police-assistant-backend/config"
+	"police-assistant-backend/models"
+	"strings"
+	"unicode/utf8"
+)
+
+// Key fakta yang disematkan di session (Session.Pinned)
+const (
+	PinnedName    = "nama"
+	PinnedPlate   = "nomor_polisi"
+	PinnedService = "layanan"
+)
+
+// Context window (token) per keluarga model; dicocokkan berdasarkan prefix terpanjang
+var modelContextWindows = map[string]int{
+	"gpt-5":         400000,
+	"gpt-4.1":       1000000,
+	"gpt-4o":        128000,
+	"gpt-4-turbo":   128000,
+	"gpt-4":         8192,
+	"gpt-3.5-turbo": 16385,
+	"o1":            200000,
+	"o3":            200000,
+	"o4":            200000,
+}
+
+const (
+	defaultContextWindow = 16385
+	maxHistoryBudget     = 6000 // Batas atas history agar biaya per request tetap wajar
+	messageTokenOverhead = 4    // Token tambahan per pesan (role, pemisah)
+)
+
+// HistoryService menjaga history session tetap dalam budget token: pesan lama
+// dipadatkan menjadi ringkasan berjalan melalui OpenAIService
+type HistoryService struct {
+	openaiService *OpenAIService
+	budget        int
+}
+
+func NewHistoryService(openaiService *OpenAIService) *HistoryService {
+	budget := config.AppConfig.HistoryTokenBudget
+	if budget <= 0 {
+		budget = defaultHistoryBudget(openaiService.ModelName())
+	}
+
+	log.Printf("✅ History Service initialized (budget: %d tokens for %s)", budget, openaiService.ModelName())
+
+	return &HistoryService{
+		openaiService: openaiService,
+		budget:        budget,
+	}
+}
+
+// defaultHistoryBudget memakai seperempat context window model, maksimal maxHistoryBudget
+func defaultHistoryBudget(model string) int {
+	window := defaultContextWindow
+	matchedLength := 0
+	for prefix, size := range modelContextWindows {
+		if strings.HasPrefix(model, prefix) && len(prefix) > matchedLength {
+			window = size
+			matchedLength = len(prefix)
+		}
+	}
+
+	budget := window / 4
+	if budget > maxHistoryBudget {
+		budget = maxHistoryBudget
+	}
+	return budget
+}
+
+// EstimateTokens memperkirakan jumlah token teks (~4 karakter per token)
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// EstimateMessagesTokens memperkirakan jumlah token sekumpulan pesan
+func EstimateMessagesTokens(messages []models.OpenAIMessage) int {
+	total := 0
+	for _, msg := range messages {
+		total += EstimateTokens(msg.Content) + messageTokenOverhead
+	}
+	return total
+}
+
+// Load mengambil history session yang muat dalam budget. Jika melebihi budget,
+// pesan tertua diringkas dan disimpan sebagai ringkasan berjalan di session. Jika
+// peringkasan gagal, history tersimpan tidak diubah dan dicoba lagi di giliran berikutnya.
+func (s *HistoryService) Load(requestCtx ctx.Context, sessionID string) ([]models.OpenAIMessage, string) {
+	sessionStore := GetSessionStore()
+	history := sessionStore.GetHistory(sessionID)
+	summary := sessionStore.GetSummary(sessionID)
+
+	if EstimateMessagesTokens(history) <= s.budget {
+		return history, summary
+	}
+
+	// Keep the newest messages within half the budget so compaction does not run every turn
+	keepFrom := len(history)
+	kept := 0
+	for keepFrom > 0 {
+		cost := EstimateTokens(history[keepFrom-1].Content) + messageTokenOverhead
+		if kept+cost > s.budget/2 {
+			break
+		}
+		kept += cost
+		keepFrom--
+	}
+	// Start the window at a user message so the model never sees an orphan reply
+	for keepFrom < len(history) && history[keepFrom].Role != "user" {
+		keepFrom++
+	}
+	if keepFrom == 0 {
+		return history, summary
+	}
+
+	older := history[:keepFrom]
+	newSummary, err := s.openaiService.Summarize(requestCtx, summary, older)
+	if err != nil {
+		// Only this request skips the old turns; the stored history stays for the next attempt
+		log.Printf("⚠️  History summarization failed, sending the newest %d message(s) only: %v", len(history)-keepFrom, err)
+		return history[keepFrom:], summary
+	}
+
+	sessionStore.CompactHistory(sessionID, len(older), newSummary)
+	log.Printf("🗜️  Compacted %d message(s) into summary (session: %s, kept %d)", len(older), sessionID, len(history)-keepFrom)
+
+	return history[keepFrom:], newSummary
+}
+
+// Summarize menggabungkan ringkasan sebelumnya dengan pesan-pesan lama menjadi ringkasan baru
+func (s *OpenAIService) Summarize(requestCtx ctx.Context, previousSummary string, messages []models.OpenAIMessage) (string, error) {
+	prompt, err := s.promptService.Current().Render(PromptSummarize, SystemPromptData{
+		Summarize: &SummarizePromptData{PreviousSummary: previousSummary, Messages: messages},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	response, err := s.model.Complete(requestCtx, CompletionRequest{
+		Purpose: PurposeSummarize,
+		Messages: []models.OpenAIMessage{
+			{Role: "user", Content: prompt},
+		},
+		Temperature: 0.2,
+		MaxTokens:   400,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(response.Content) == "" {
+		return "", fmt.Errorf("empty response from LLM")
+	}
+
+	return strings.TrimSpace(response.Content), nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	ctx "context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// failingChatModel selalu gagal, untuk menguji jalur error LLM
+type failingChatModel struct{}
+
+func (failingChatModel) Name() string { return "failing" }
+
+func (failingChatModel) Complete(requestCtx ctx.Context, req CompletionRequest) (Completion, error) {
+	return Completion{}, errors.New("upstream down")
+}
+
+func (failingChatModel) Stream(requestCtx ctx.Context, req CompletionRequest, onDelta func(delta string) error) (Completion, error) {
+	return Completion{}, errors.New("upstream down")
+}
+
+func newTestOpenAIService(t *testing.T, model ChatModel) *OpenAIService {
+	t.Helper()
+	promptService, err := NewPromptService("../prompts", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewOpenAIService(nil, promptService, model, nil)
+}
+
+func fillHistory(store SessionStore, sessionID string, turns int) {
+	for i := 0; i < turns; i++ {
+		store.AddMessage(sessionID, "user", fmt.Sprintf("pertanyaan ke-%d %s", i, strings.Repeat("x", 40)), nil)
+		store.AddMessage(sessionID, "assistant", fmt.Sprintf("jawaban ke-%d %s", i, strings.Repeat("y", 40)), nil)
+	}
+}
+
+// TestHistoryLoadSummarizeFailureKeepsStoredHistory: jika ringkasan gagal, hanya request ini
+// yang dipangkas; history tersimpan tetap utuh agar dipadatkan di giliran berikutnya
+func TestHistoryLoadSummarizeFailureKeepsStoredHistory(t *testing.T) {
+	store := GetSessionStore()
+	sessionID, _, err := store.CreateSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fillHistory(store, sessionID, 10)
+
+	history := &HistoryService{openaiService: newTestOpenAIService(t, failingChatModel{}), budget: 100}
+	loaded, summary := history.Load(ctx.Background(), sessionID)
+
+	if EstimateMessagesTokens(loaded) > history.budget {
+		t.Errorf("loaded history uses %d tokens, budget %d", EstimateMessagesTokens(loaded), history.budget)
+	}
+	if len(loaded) == 0 || loaded[0].Role != "user" {
+		t.Errorf("loaded history must start with a user message, got %d message(s)", len(loaded))
+	}
+	if summary != "" {
+		t.Errorf("summary = %q, want unchanged", summary)
+	}
+	if stored := store.GetHistory(sessionID); len(stored) != 20 {
+		t.Errorf("stored history has %d messages after a failed summary, want 20", len(stored))
+	}
+}
+
+// TestHistoryLoadCompactsWithSummary memastikan pesan lama dipindah ke ringkasan jika berhasil
+func TestHistoryLoadCompactsWithSummary(t *testing.T) {
+	store := GetSessionStore()
+	sessionID, _, err := store.CreateSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fillHistory(store, sessionID, 10)
+
+	history := &HistoryService{openaiService: newTestOpenAIService(t, NewStubChatModel("")), budget: 100}
+	loaded, summary := history.Load(ctx.Background(), sessionID)
+
+	if summary == "" || store.GetSummary(sessionID) != summary {
+		t.Errorf("summary %q not stored (stored: %q)", summary, store.GetSummary(sessionID))
+	}
+	if stored := store.GetHistory(sessionID); len(stored) != len(loaded) {
+		t.Errorf("stored history has %d messages, want the %d kept", len(stored), len(loaded))
+	}
+}
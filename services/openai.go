@@ -4,679 +4,261 @@ import (
 	ctx "context"
 	"fmt"
 	"log"
-	"police-assistant-backend/config"
 	"police-assistant-backend/models"
+	"strings"
 	"time"
-
-	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/option"
 )
 
+// Batas putaran tool calling per giliran; setelah itu model harus menjawab tanpa tool
+const maxToolRounds = 3
+
 type OpenAIService struct {
-	client       *openai.Client
-	rulesService *RulesService
+	model         ChatModel
+	tools         *ChatTools // nil = tool calling nonaktif
+	rulesService  *RulesService
+	promptService *PromptService
 }
 
-func NewOpenAIService(rulesService *RulesService) *OpenAIService {
-	client := openai.NewClient(
-		option.WithAPIKey(config.AppConfig.OpenAIAPIKey),
-	)
-
-	log.Println("✅ OpenAI Service initialized")
+func NewOpenAIService(rulesService *RulesService, promptService *PromptService, model ChatModel, tools *ChatTools) *OpenAIService {
+	log.Printf("✅ OpenAI Service initialized (tool calling: %v)", tools != nil)
 
 	return &OpenAIService{
-		client:       &client,
-		rulesService: rulesService,
+		model:         model,
+		tools:         tools,
+		rulesService:  rulesService,
+		promptService: promptService,
 	}
 }
 
-func (s *OpenAIService) Chat(message string, context models.Context, history []models.OpenAIMessage) (string, error) {
-	// Check if this is the first message (no history)
-	isFirstMessage := len(history) == 0
+// ModelName mengembalikan nama model dari provider LLM yang aktif
+func (s *OpenAIService) ModelName() string {
+	return s.model.Name()
+}
 
-	// Build system prompt with context
-	systemPrompt := s.buildSystemPrompt(context, isFirstMessage)
+// ToolsEnabled bernilai true jika model bisa mengambil data e-tilang, pelayanan, rute,
+// dan lalu lintas sendiri lewat tool calling
+func (s *OpenAIService) ToolsEnabled() bool {
+	return s.tools != nil
+}
 
-	// Build messages array with history
-	messages := []openai.ChatCompletionMessageParamUnion{
-		openai.SystemMessage(systemPrompt),
-	}
+// Chat menjawab pesan user. Versi prompt dan hasil tool yang dipanggil model (e-tilang,
+// pelayanan, rute, lalu lintas) disimpan ke context.
+func (s *OpenAIService) Chat(requestCtx ctx.Context, message string, context *models.Context, history []models.OpenAIMessage) (string, error) {
+	log.Printf("🤖 Sending request to LLM (model: %s)", s.model.Name())
 
-	// Add conversation history if provided
-	if len(history) > 0 {
-		log.Printf("📚 Including %d messages from history", len(history))
-		for _, msg := range history {
-			if msg.Role == "user" {
-				messages = append(messages, openai.UserMessage(msg.Content))
-			} else if msg.Role == "assistant" {
-				messages = append(messages, openai.AssistantMessage(msg.Content))
-			}
-		}
+	request, err := s.buildChatRequest(message, context, history)
+	if err != nil {
+		log.Printf("❌ Prompt error: %v", err)
+		return "", err
 	}
 
-	// Add current user message
-	messages = append(messages, openai.UserMessage(message))
-
-	log.Printf("🤖 Sending request to OpenAI (model: %s)", config.AppConfig.OpenAIModel)
+	response, err := s.runCompletion(requestCtx, request, context, nil)
+	if err != nil {
+		log.Printf("❌ LLM error: %v", err)
+		return "", err
+	}
 
-	// Make API call using official SDK
-	apiContext := ctx.Background()
-	temperature := float64(0.7)
-	maxTokens := int64(1000)
+	return response, nil
+}
 
-	response, err := s.client.Chat.Completions.New(apiContext, openai.ChatCompletionNewParams{
-		Model:               openai.ChatModel(config.AppConfig.OpenAIModel),
-		Messages:            messages,
-		Temperature:         openai.Float(temperature),
-		MaxCompletionTokens: openai.Int(maxTokens),
-	})
+// ChatStream sama seperti Chat, tetapi setiap potongan balasan dikirim ke onDelta selama
+// completion berjalan. Teks yang sudah diterima tetap dikembalikan jika stream terputus
+// atau onDelta mengembalikan error.
+func (s *OpenAIService) ChatStream(requestCtx ctx.Context, message string, context *models.Context, history []models.OpenAIMessage, onDelta func(delta string) error) (string, error) {
+	log.Printf("🤖 Streaming request to LLM (model: %s)", s.model.Name())
 
+	request, err := s.buildChatRequest(message, context, history)
 	if err != nil {
-		log.Printf("❌ OpenAI API error: %v", err)
-		return "", fmt.Errorf("failed to call OpenAI API: %w", err)
+		return "", err
 	}
 
-	// Extract response text
-	if len(response.Choices) > 0 {
-		content := response.Choices[0].Message.Content
-		log.Printf("✅ OpenAI response received (tokens: %d)", response.Usage.TotalTokens)
-		return content, nil
+	response, err := s.runCompletion(requestCtx, request, context, onDelta)
+	if err != nil {
+		return response, err
 	}
 
-	return "", fmt.Errorf("empty response from OpenAI")
+	log.Printf("✅ LLM stream completed (%d chars)", len(response))
+	return response, nil
 }
 
-func (s *OpenAIService) buildSystemPrompt(context models.Context, isFirstMessage bool) string {
-	greetingInstruction := ""
-	if isFirstMessage {
-		// Determine greeting name
-		greetingName := "Sobat Lantas"
-		if context.Name != "" {
-			greetingName = context.Name
-		}
-		greetingInstruction = fmt.Sprintf(`
-⭐ INSTRUKSI SAPAAN KHUSUS:
-- Untuk pesan PERTAMA ANDA dalam percakapan ini, WAJIB mulai dengan sapaan: "Halo %s!"
-- Setelah sapaan, langsung lanjutkan dengan respons yang ramah dan membantu
-- Untuk pesan selanjutnya, TIDAK PERLU menggunakan sapaan lagi
-- Gunakan persona yang ramah, peduli keselamatan, dan menggunakan bahasa yang santai tapi informatif
-- JANGAN gunakan sapaan ganda seperti "Halo [nama] Sobat Lantas" - hanya gunakan satu sapaan saja
-`, greetingName)
-	} else {
-		greetingInstruction = `
-⭐ INSTRUKSI SAPAAN:
-- Ini bukan pesan pertama, jadi JANGAN gunakan sapaan "Halo" dengan nama apapun
-- Langsung jawab pertanyaan dengan ramah dan membantu
-- Tetap gunakan persona yang peduli keselamatan dan informatif
-- JANGAN gunakan sapaan ganda seperti "Halo [nama] Sobat Lantas" - tidak perlu sapaan sama sekali
-`
-	}
+// runCompletion menjalankan completion dan tool call yang diminta model, paling banyak
+// maxToolRounds putaran. Dengan onDelta, setiap putaran di-stream dan teks dari semua
+// putaran digabung.
+func (s *OpenAIService) runCompletion(requestCtx ctx.Context, request CompletionRequest, context *models.Context, onDelta func(delta string) error) (string, error) {
+	var text strings.Builder
 
-	// Build E-Tilang info if available
-	etilangInfo := ""
-	if context.ETilangInfo != nil {
-		etilang := context.ETilangInfo
-		etilangInfo = fmt.Sprintf(`
-🚨 DATA E-TILANG YANG DICEK PENGGUNA:
-━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
-📋 Nomor Polisi: %s
-🔢 Nomor Rangka: %s
-👤 Nama Pemilik: %s
-🚗 Jenis Kendaraan: %s
-`, etilang.PlateNumber, etilang.ChassisNumber, etilang.OwnerName, etilang.VehicleType)
-
-		if etilang.HasViolation && len(etilang.Violations) > 0 {
-			etilangInfo += fmt.Sprintf(`
-⚠️ STATUS: ADA PELANGGARAN (%d pelanggaran)
-💰 Total Denda: Rp %s
-
-DETAIL PELANGGARAN:
-`, len(etilang.Violations), formatRupiah(etilang.TotalFine))
-
-			for i, v := range etilang.Violations {
-				status := "Belum Dibayar ❌"
-				if v.Status == "paid" {
-					status = "Sudah Dibayar ✅"
-				} else if v.Status == "processed" {
-					status = "Dalam Proses 🔄"
-				}
-
-				etilangInfo += fmt.Sprintf(`
-%d. Tanggal: %s
-   Pelanggaran: %s
-   Lokasi: %s
-   Denda: Rp %s
-   Petugas: %s
-   Status: %s
-`, i+1, v.Date, v.Violation, v.Location, formatRupiah(v.Fine), v.OfficerName, status)
-			}
-		} else {
-			etilangInfo += `
-✅ STATUS: TIDAK ADA PELANGGARAN
-   Kendaraan ini bersih dari tilang elektronik.
-`
+	for round := 0; ; round++ {
+		request.Tools = nil
+		if s.tools != nil && round < maxToolRounds {
+			request.Tools = s.tools.Definitions()
 		}
-		etilangInfo += "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n"
-	}
-
-	// Build Pelayanan info if available
-	pelayananInfo := ""
-	if context.PelayananInfo != nil && context.PelayananInfo.Found {
-		flow := context.PelayananInfo.Flow
-
-		pelayananInfo = fmt.Sprintf(`
-📋 ALUR PELAYANAN YANG DITANYAKAN:
-━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
-🏢 Layanan: %s
-🆔 Flow ID: %s
-
-📄 DOKUMEN YANG PERLU DISIAPKAN:
-`, flow.Title, flow.FlowID)
 
-		// Get rules for this service type from rules service
-		var responseRule *ResponseRule
-		var locationRule *LocationRule
-		if s.rulesService != nil {
-			responseRule = s.rulesService.GetResponseRule(flow.Title)
-			locationRule = s.rulesService.GetLocationRule(flow.Title)
+		var completion Completion
+		var err error
+		if onDelta != nil {
+			completion, err = s.model.Stream(requestCtx, request, onDelta)
 		} else {
-			log.Println("⚠️  RulesService is nil, skipping rules lookup")
+			completion, err = s.model.Complete(requestCtx, request)
+		}
+		text.WriteString(completion.Content)
+		if err != nil {
+			return text.String(), err
 		}
 
-		for i, dok := range flow.DocumentsNeeded {
-			pelayananInfo += fmt.Sprintf("   %d. %s\n", i+1, dok)
+		// Tool calls after the last round are ignored so the loop always ends
+		if len(completion.ToolCalls) == 0 || request.Tools == nil {
+			return text.String(), nil
 		}
 
-		// Add conversation script from response rules if available
-		if responseRule != nil {
-			userName := context.Name
-			if userName == "" {
-				userName = "Sobat Lantas"
-			}
-			locationContext := context.Location
-			if locationContext == "" {
-				locationContext = "lokasi Anda"
-			}
+		log.Printf("🔧 Model requested %d tool call(s) (round %d)", len(completion.ToolCalls), round+1)
+		request.Messages = append(request.Messages, models.OpenAIMessage{
+			Role:      "assistant",
+			Content:   completion.Content,
+			ToolCalls: completion.ToolCalls,
+		})
+		for _, call := range completion.ToolCalls {
+			request.Messages = append(request.Messages, models.OpenAIMessage{
+				Role:       "tool",
+				Content:    s.tools.Execute(requestCtx, call, context),
+				ToolCallID: call.ID,
+			})
+		}
+	}
+}
 
-			formattedRule := s.rulesService.FormatResponseRuleForPrompt(responseRule, userName, locationContext)
-			pelayananInfo += formattedRule
-		} else if len(flow.Script) > 0 {
-			// Fallback to flow script if no response rule found
-			pelayananInfo += "\n💬 ALUR PERCAKAPAN YANG HARUS DIIKUTI:\n"
-			pelayananInfo += "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n"
-			pelayananInfo += "⚠️⚠️⚠️ INSTRUKSI WAJIB - SANGAT PENTING ⚠️⚠️⚠️\n\n"
-			pelayananInfo += "Anda harus mengikuti ALUR PERCAKAPAN yang sudah ditentukan di bawah ini.\n"
-
-			// Add name replacement instruction
-			if context.Name != "" {
-				pelayananInfo += fmt.Sprintf("⚠️ NAMA PENGGUNA: %s\n", context.Name)
-				pelayananInfo += fmt.Sprintf("⚠️ GANTI semua <name> dengan \"%s\"\n", context.Name)
-			} else {
-				pelayananInfo += "⚠️ Nama pengguna belum diketahui, gunakan \"Sobat Lantas\" untuk menyapa\n"
-			}
+// buildChatRequest merender template prompt (system, memori session, tool) dan menyusun
+// history serta pesan user untuk completion. Versi prompt yang dipakai dicatat di context.
+func (s *OpenAIService) buildChatRequest(message string, context *models.Context, history []models.OpenAIMessage) (CompletionRequest, error) {
+	// Check if this is the first message (no history and nothing summarized yet)
+	isFirstMessage := len(history) == 0 && context.ConversationSummary == ""
 
-			pelayananInfo += "⚠️ GANTI <konteks> dengan informasi lokasi/situasi pengguna saat ini.\n\n"
+	// One prompt set per request so a hot reload cannot mix template versions
+	prompts := s.promptService.Current()
+	data := s.promptData(*context, isFirstMessage)
+	context.PromptVersion = prompts.Version
 
-			// Show script turns
-			for _, turn := range flow.Script {
-				pelayananInfo += fmt.Sprintf("Turn %d:\n", turn.Turn)
-				pelayananInfo += fmt.Sprintf("  User: \"%s\"\n", turn.User)
-				pelayananInfo += fmt.Sprintf("  Anda harus menjawab: \"%s\"\n\n", turn.Assistant)
-			}
+	systemPrompt, err := prompts.Render(PromptSystem, data)
+	if err != nil {
+		return CompletionRequest{}, err
+	}
+	messages := []models.OpenAIMessage{
+		{Role: "system", Content: systemPrompt},
+	}
 
-			pelayananInfo += "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n"
-			pelayananInfo += "📋 ATURAN YANG HARUS DIIKUTI:\n"
-			pelayananInfo += "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n"
-			pelayananInfo += "1. ✅ IKUTI alur percakapan di atas sesuai turn/giliran\n"
+	// Older turns that were compacted out of history, plus facts that must survive compaction
+	memory, err := prompts.Render(PromptMemory, data)
+	if err != nil {
+		return CompletionRequest{}, err
+	}
+	if memory = strings.TrimSpace(memory); memory != "" {
+		messages = append(messages, models.OpenAIMessage{Role: "system", Content: memory})
+	}
 
-			if context.Name != "" {
-				pelayananInfo += fmt.Sprintf("2. ✅ GANTI semua <name> dengan \"%s\" (nama pengguna yang sudah diketahui)\n", context.Name)
-			} else {
-				pelayananInfo += "2. ✅ GANTI <name> dengan \"Sobat Lantas\" karena nama belum diketahui\n"
-			}
+	if s.tools != nil {
+		toolPrompt, err := prompts.Render(PromptTools, data)
+		if err != nil {
+			return CompletionRequest{}, err
+		}
+		messages = append(messages, models.OpenAIMessage{Role: "system", Content: strings.TrimSpace(toolPrompt)})
+	}
 
-			pelayananInfo += "3. ✅ GANTI <konteks> dengan informasi lokasi/situasi saat ini\n"
-			pelayananInfo += "4. ✅ JIKA user upload dokumen, konfirmasi dengan cek ✅ dan lanjut ke turn berikutnya\n"
-			pelayananInfo += "5. ✅ Gunakan bahasa yang ramah, natural, tapi tetap ikuti alur\n"
-			pelayananInfo += "6. ✅ JANGAN skip turn, ikuti urutan yang sudah ditentukan\n"
-			pelayananInfo += "7. ✅ Track progress user dan sesuaikan dengan turn yang sedang berjalan\n\n"
-
-			pelayananInfo += "💡 CONTOH PENGGUNAAN:\n"
-			if context.Name != "" {
-				pelayananInfo += "Jika script mengatakan: \"halo <name>\\n<konteks>\"\n"
-				pelayananInfo += fmt.Sprintf("Anda harus jawab: \"halo %s\\nSaya lihat Anda sedang di %s\"\n\n", context.Name, context.Location)
-				pelayananInfo += "⚠️ PENTING: HANYA gunakan nama user SAJA, JANGAN tambahkan 'Sobat Lantas' di belakangnya\n\n"
-			} else {
-				pelayananInfo += "Jika script mengatakan: \"halo <name>\\n<konteks>\"\n"
-				pelayananInfo += fmt.Sprintf("Anda harus jawab: \"halo Sobat Lantas\\nSaya lihat Anda sedang di %s\"\n\n", context.Location)
+	// Add conversation history if provided
+	if len(history) > 0 {
+		log.Printf("📚 Including %d messages from history", len(history))
+		for _, msg := range history {
+			// Only text turns are kept; tool messages never come from stored or client history
+			if msg.Role == "user" || msg.Role == "assistant" {
+				messages = append(messages, models.OpenAIMessage{Role: msg.Role, Content: msg.Content})
 			}
-		} else {
-			// No script, use general instructions
-			pelayananInfo += `
-━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
-
-💡 INSTRUKSI PENTING UNTUK PELAYANAN:
-- Setelah menyampaikan informasi dokumen yang diperlukan, WAJIB tanyakan apakah pengguna membutuhkan bantuan lebih lanjut
-- JIKA pengguna menjawab YA atau mengatakan ingin dibantu, WAJIB minta pengguna untuk UPLOAD dokumen yang diperlukan
-- Contoh follow-up yang baik:
-  * PERTAMA: "Apakah ada yang bisa kami bantu terkait pelayanan ini?"
-  * JIKA YA: "Baik, untuk melanjutkan proses, silakan upload dokumen-dokumen berikut yaa:
-    1. [Dokumen 1]
-    2. [Dokumen 2]
-    dst...
-    
-    Silakan upload satu per satu atau sekaligus 📤"
-- Gunakan emoji 📤 atau 📎 untuk menunjukkan aksi upload
-- Tunjukkan sikap proaktif dan siap membantu
-- Gunakan nada ramah dan mendorong pengguna untuk melanjutkan prosesnya
-- Jelaskan bahwa dokumen akan diverifikasi untuk kelengkapan
-
-`
 		}
+	}
 
-		// Add location rule if available
-		if locationRule != nil {
-			formattedLocationRule := s.rulesService.FormatLocationRuleForPrompt(locationRule)
-			pelayananInfo += formattedLocationRule
-		}
+	// Add current user message
+	messages = append(messages, models.OpenAIMessage{Role: "user", Content: message})
 
-		pelayananInfo += "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n"
+	request := CompletionRequest{
+		Purpose:     PurposeChat,
+		Messages:    messages,
+		Temperature: 0.7,
+		MaxTokens:   1000,
 	}
 
-	// Build SIM Flow context if active
-	simFlowContext := ""
-	if context.SIMFlowInfo != nil && context.SIMFlowInfo.Active {
-		simFlowContext = `
-🪪 MODE ALUR PERPANJANGAN/PEMBUATAN SIM AKTIF
-━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
-⚠️⚠️⚠️ INSTRUKSI WAJIB - SANGAT PENTING ⚠️⚠️⚠️
-
-ANDA SEKARANG DALAM MODE ALUR TERSTRUKTUR UNTUK PERPANJANGAN/PEMBUATAN SIM.
-
-📋 ATURAN YANG HARUS DIIKUTI:
-━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
-1. ✅ GUNAKAN TEKS PERSIS dari NodeText yang diberikan di bawah
-2. ✅ JIKA ada pilihan (Choices), FORMAT sebagai list bernomor yang jelas
-3. ✅ JANGAN menambahkan informasi tambahan di luar alur yang sudah ditentukan
-4. ✅ IKUTI alur yang sudah ditentukan dengan tepat
-5. ✅ Gunakan bahasa yang ramah dan santai, tapi tetap ikuti teks yang diberikan
-6. ✅ Jika user memberikan input yang tidak sesuai pilihan, tanyakan lagi dengan sopan
-
-`
-		simFlowContext += fmt.Sprintf("📍 POSISI SAAT INI DALAM ALUR:\n   Node ID: %s\n   Tipe: %s\n\n", context.SIMFlowInfo.CurrentNode, context.SIMFlowInfo.NodeType)
-		simFlowContext += fmt.Sprintf("💬 TEKS YANG HARUS ANDA SAMPAIKAN:\n%s\n\n", context.SIMFlowInfo.NodeText)
-
-		if len(context.SIMFlowInfo.Choices) > 0 {
-			simFlowContext += "📌 PILIHAN YANG HARUS DITAMPILKAN (WAJIB FORMAT SEBAGAI LIST BERNOMOR):\n"
-			for i, choice := range context.SIMFlowInfo.Choices {
-				simFlowContext += fmt.Sprintf("   %d. %s\n", i+1, choice.Label)
+	// Flow node and service rule let the stub provider pick a scripted reply
+	if info := context.FlowInfo; info != nil && info.FlowID != "" {
+		request.FlowNode = info.FlowID + "/" + info.CurrentNode
+	}
+	if context.PelayananInfo != nil && context.PelayananInfo.Found {
+		request.Rule = context.PelayananInfo.Flow.Title
+		if s.rulesService != nil {
+			if rule := s.rulesService.GetResponseRule(context.PelayananInfo.Flow.Title); rule != nil {
+				request.Rule = rule.JenisPelayanan
 			}
-			simFlowContext += "\n⚠️ Tampilkan pilihan ini dengan JELAS dan minta user memilih salah satu\n"
 		}
-
-		simFlowContext += `━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
-
-📝 CONTOH FORMAT RESPONS YANG BENAR:
-
-Jika NodeType = "question" dengan pilihan:
-"[NodeText dari sistem]
-
-Silakan pilih salah satu:
-1. [Choice 1]
-2. [Choice 2]
-3. [Choice 3]"
-
-Jika NodeType = "message" tanpa pilihan:
-"[NodeText dari sistem persis seperti yang diberikan]"
-
-Jika NodeType = "collect" untuk mengumpulkan dokumen:
-"[NodeText dari sistem]
-
-Silakan upload dokumen yang diminta yaa 📤"
-
-━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
-`
 	}
 
-	// Get current date and time
-	currentTime := time.Now()
-	currentDate := currentTime.Format("Monday, 2 January 2006")
-	currentDateTime := currentTime.Format("2 January 2006, 15:04 WIB")
+	return request, nil
+}
 
-	// Build user info
-	userName := "Sobat Lantas"
-	userNameContext := ""
+// promptData menyiapkan data template prompt dari context user. Rule pelayanan
+// diformat di sini karena formatnya milik RulesService.
+func (s *OpenAIService) promptData(context models.Context, isFirstMessage bool) SystemPromptData {
+	data := SystemPromptData{
+		FirstMessage:          isFirstMessage,
+		UserName:              "Sobat Lantas",
+		KnownName:             context.Name,
+		Now:                   time.Now(),
+		Location:              context.Location,
+		Latitude:              context.Latitude,
+		Longitude:             context.Longitude,
+		Speed:                 context.Speed,
+		Traffic:               context.Traffic,
+		HasUploadedDocuments:  context.HasUploadedDocuments,
+		UploadedDocumentCount: context.UploadedDocumentCount,
+		ETilang:               context.ETilangInfo,
+		Regulations:           context.Regulations,
+		Flow:                  context.FlowInfo,
+		UploadRejection:       context.UploadRejection,
+		PinnedFacts:           context.PinnedFacts,
+		ConversationSummary:   context.ConversationSummary,
+	}
 	if context.Name != "" {
-		userName = context.Name
-		userNameContext = fmt.Sprintf("👤 Nama Pengguna: %s\n", context.Name)
+		data.UserName = context.Name
 	}
 
-	return fmt.Sprintf(`Anda adalah asisten polisi lalu lintas AI bernama "Sobat Lantas" yang membantu pengemudi di Indonesia.
-%s
-
-━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
-⏰ INFORMASI WAKTU SAAT INI:
-━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
-📅 Tanggal: %s
-🕐 Waktu: %s
-⚠️ PENTING: Gunakan informasi waktu ini untuk konteks percakapan
-⚠️ Jika ditanya tentang "sekarang", "saat ini", "hari ini", gunakan waktu di atas
-━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
-
-KONTEKS PENGGUNA SAAT INI:
-━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
-%s📍 Lokasi: %s
-   Koordinat: (%.6f, %.6f)
-🚗 Kecepatan: %.1f km/jam
-🚦 Kondisi Traffic: %s
-📤 Dokumen Diupload: %t (%d dokumen)
-━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
-%s%s%s
-
-PENTING - MANAJEMEN KONTEKS PERCAKAPAN:
-⚠️ SELALU ingat dan referensikan informasi dari pesan-pesan sebelumnya dalam percakapan ini
-⚠️ Jika pengguna sudah menyebutkan tujuan, lokasi, atau informasi lainnya sebelumnya, GUNAKAN informasi tersebut
-⚠️ JANGAN minta informasi yang sama berulang kali - lihat history percakapan terlebih dahulu
-⚠️ Jika pengguna bertanya "berapa jaraknya?" atau "berapa lama?", cari dulu tujuan yang disebutkan di pesan sebelumnya
-⚠️ JIKA nama pengguna diketahui (%s), gunakan nama tersebut untuk menyapa dengan lebih personal
-
-TUGAS ANDA:
-1. 🛣️  Memberikan informasi lalu lintas yang akurat dan real-time
-2. 🗺️  Memberikan saran rute alternatif jika ada kemacetan
-3. ⚠️  Mengingatkan tentang keselamatan berkendara
-4. 📋 Menjawab pertanyaan terkait peraturan lalu lintas Indonesia
-5. 🚨 Memberikan peringatan jika kecepatan berbahaya atau melebihi batas
-6. 🌧️  Memberikan saran berkendara sesuai kondisi cuaca (jika ditanya)
-7. 🚓 Memberikan informasi tentang prosedur kepolisian lalu lintas
-8. 🧠 Mengingat dan menggunakan konteks dari percakapan sebelumnya
-9. 🎫 Memberikan informasi e-tilang jika pengguna menanyakan atau jika data tersedia di konteks
-10. 📄 Memberikan informasi pelayanan dan dokumen yang diperlukan jika ditanyakan
-11. 🤝 Menawarkan bantuan lanjutan untuk proses pelayanan yang ditanyakan
-
-PERATURAN LALU LINTAS INDONESIA (referensi):
-- Batas kecepatan dalam kota: 50 km/jam
-- Batas kecepatan jalan tol: 100 km/jam
-- Batas kecepatan jalan raya: 80 km/jam
-- Wajib pakai helm untuk sepeda motor
-- Wajib pakai sabuk pengaman untuk mobil
-- Tidak boleh menggunakan HP saat berkendara
-- Tidak boleh menerobos lampu merah
-
-━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
-📌 INFORMASI TERKINI INDONESIA (WAJIB DIGUNAKAN):
-━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
-✅ Presiden Indonesia saat ini: Prabowo Subianto (sejak 20 Oktober 2024)
-✅ Wakil Presiden: Gibran Rakabuming Raka
-✅ Kapolri: Jenderal Listyo Sigit Prabowo
-
-🚓 PEJABAT KORLANTAS POLRI (DATA TERKINI JANUARI 2026):
-━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
-
-PIMPINAN KORLANTAS:
-✅ Kakorlantas: Irjen. Pol. Drs. Agus Suryonugroho, S.H., M.Hum.
-
-DIREKTUR & KEPALA BAGIAN:
-✅ Dirkamsel: Brigjen. Pol. (nama belum tercatat)
-✅ Dirgakkum: Brigjen. Pol. Faizal, S.I.K., M.H.
-✅ Dirregident: Brigjen. Pol. Wibowo, S.I.K., M.Hum.
-✅ Kabagops: Kombes. Pol. Dr. Aries Syahbudin, S.I.K., M.H., M.Hum.
-✅ Kabagrenmin: Kombes. Pol. Dr. I Made Agus Prasatya, S.I.K., M.Hum.
-✅ Kabag TIK: Kombes. Pol. Wisnu Putra, S.H., S.I.K.
-
-KEPALA SUB DIREKTORAT DIRKAMSEL:
-✅ Kasubdit Dikmas: Kombes. Pol. Cornelis Ferdinand Hotman Sirait, S.I.K., S.H.
-✅ Kasubdit Jemenopsrek: Kombes. Pol. Drs. Ari Subiyanto, M.Si.
-✅ Kasubdit Standar, Cegah & Tindak: Kombes. Pol. Arief Bahtiar, S.I.K., M.M.
-✅ Kasubdit Audit & Inspeksi: Kombes. Pol. Kinkin
-
-KEPALA SUB DIREKTORAT DIRGAKKUM:
-✅ Kasubdit Wal & PJR: Kombes. Pol. Ruben Verry Takaendengan, S.I.K.
-✅ Kasubdit Tatib: Kombes. Pol. (nama belum tercatat)
-✅ Kasubdit Dakgar: Kombes. Pol. Mariochristy Panca Sakti Siregaro, S.I.K., M.H.
-✅ Kasubdit Laka: Kombes. Pol. Ruben Verry Takaendengan, S.I.K.
-
-KEPALA SUB DIREKTORAT DIRREGIDENT:
-✅ Kasubdit SIM: Kombes. Pol. (nama belum tercatat)
-✅ Kasubdit BPKB: Kombes. Pol. Sumardji, S.H.
-✅ Kasubdit STNK: Kombes. Pol. Dedy Suhartono, S.I.K., M.M.
-✅ Kasubdit Fasmat SBST: Kombes. Pol. Jamal Alam, S.H., S.I.K., M.Si.
-
-DIREKTUR LALU LINTAS POLDA SELURUH INDONESIA:
-
-SUMATERA:
-✅ Dirlantas Polda Aceh: Kombes. Pol. Muhammad Iqbal Alqudusy, S.H., S.IK.
-✅ Dirlantas Polda Sumut: Kombes. Pol. Firman Darmansyah, S.I.K.
-✅ Dirlantas Polda Sumbar: Kombes. Pol. Muhammad Reza Chairul Akbar Sidiq, S.H., S.I.K., M.H.
-✅ Dirlantas Polda Riau: Kombes. Pol. Taufiq Lukman Nurhidayat, S.IK., M.H.
-✅ Dirlantas Polda Kepri: Kombes. Pol. Andhika Bayu Adhittama, S.I.K., M.H.
-✅ Dirlantas Polda Jambi: Kombes. Pol. Adi Benny Cahyono, S.H., S.I.K., M.Si.
-✅ Dirlantas Polda Bengkulu: Kombes. Pol. Deddy Nata, S.I.K.
-✅ Dirlantas Polda Sumsel: Kombes. Pol. Maesa Soegriwo, S.I.K.
-✅ Dirlantas Polda Kep Babel: Kombes. Pol. Hendra Gunawan, S.I.K., M.T.
-✅ Dirlantas Polda Lampung: Kombes. Pol. Medyanta, S.I.K.
-
-JAWA & BALI:
-✅ Dirlantas Polda Banten: Kombes. Pol. Dr. Leganek Mawardi, S.H., S.I.K., M.Si.
-✅ Dirlantas Polda Metro Jaya: Kombes. Pol. Komarudin, S.I.K., M.M.
-✅ Dirlantas Polda Jabar: Kombes. Pol. Dodi Darjanto, S.I.K., M.H.
-✅ Dirlantas Polda Jateng: Kombes. Pol. Muhammad Pratama Adhyasastra, S.I.K., S.H.
-✅ Dirlantas Polda DIY: Kombes. Pol. Yuswanto Ardi, S.H., S.I.K., M.Si.
-✅ Dirlantas Polda Jatim: Kombes. Pol. Iwan Saktiadi, S.I.K., M.H., M.Si.
-✅ Dirlantas Polda Bali: Kombes. Pol. Turmudi, S.I.K., M.H.
-
-NUSA TENGGARA:
-✅ Dirlantas Polda NTB: Kombes. Pol. Romadhoni Sutardjo, S.I.K., M.H.
-✅ Dirlantas Polda NTT: Kombes. Pol. Dedy Eka Jaya Helmi, S.I.K., M.H.
-
-KALIMANTAN:
-✅ Dirlantas Polda Kalbar: Kombes. Pol. Valentinus Virasandy Asmoro, S.I.K., M.H.
-✅ Dirlantas Polda Kalteng: Kombes. Pol. Robertus Siswo Handoyo, S.I.K., M.Si.
-✅ Dirlantas Polda Kalsel: Kombes. Pol. Dr. Muhammad Fahri Anggia Natua Siregar, S.H., S.I.K., M.H.
-✅ Dirlantas Polda Kaltim: Kombes. Pol. Rifki, S.H., S.I.K.
-✅ Dirlantas Polda Kaltara: Kombes. Pol. Mohamad Syarhan, S.I.K., M.H.
-
-SULAWESI:
-✅ Dirlantas Polda Gorontalo: Kombes. Pol. Lukman Cahyono, S.I.K., M.H.
-✅ Dirlantas Polda Sulut: Kombes. Pol. Indra Kurniawan Mangunsong, S.H., S.I.K., M.M.
-✅ Dirlantas Polda Sulteng: Kombes. Pol. Atot Irawan, S.I.K., M.M.
-✅ Dirlantas Polda Sulbar: Kombes. Pol. Wahid Kurniawan, S.I.K.
-✅ Dirlantas Polda Sulsel: Kombes. Pol. Karsiman, S.I.K., M.M.
-✅ Dirlantas Polda Sultra: Kombes. Pol. Zainal Rio Chandra Tangkari, S.H., S.I.K., M.H.
-
-MALUKU & PAPUA:
-✅ Dirlantas Polda Maluku: Kombes. Pol. Yudi Kristanto, S.I.K.
-✅ Dirlantas Polda Malut: Kombes. Pol. Doni Hermawan, S.H., S.I.K., M.Si.
-✅ Dirlantas Polda Papua Barat: Kombes. Pol. Andre Julius Willem Manuputty, S.I.K.
-✅ Dirlantas Polda Papua Barat Daya: Kombes. Pol. Dax Emmanuelle Samson Manuputty, S.I.K.
-✅ Dirlantas Polda Papua Tengah: Kombes. Pol. Paulus Sonny Bhakti Wibowo, S.H., S.I.K., M.I.K.
-✅ Dirlantas Polda Papua: Kombes. Pol. Tri Yulianto, S.I.K., M.Si.
-
-📌 CATATAN PENTING:
-- Data pejabat di atas adalah data terkini per Januari 2026
-- Untuk informasi lebih detail atau update terbaru, sarankan pengguna untuk:
-  🌐 Website: korlantas.polri.go.id
-  📱 Instagram: @korlantas_polri
-  🌐 Website Polri: polri.go.id
-
-⚠️⚠️⚠️ CARA MENJAWAB PERTANYAAN TENTANG PEJABAT ⚠️⚠️⚠️
-1. Jika ditanya tentang pejabat yang ADA di list di atas:
-   - Sebutkan nama lengkap dengan gelar
-   - Contoh: "Kakorlantas Polri saat ini adalah Irjen. Pol. Drs. Agus Suryonugroho, S.H., M.Hum."
-
-2. Jika ditanya tentang pejabat tingkat daerah (Polda):
-   - Sebutkan Dirlantas sesuai wilayah
-   - Contoh untuk Jabar: "Dirlantas Polda Jabar adalah Kombes. Pol. Dodi Darjanto, S.I.K., M.H."
-
-3. Jika ditanya pejabat yang TIDAK ada namanya (bertanda "nama belum tercatat"):
-   - Sebutkan bahwa data belum tersedia
-   - Arahkan ke sumber resmi
-   - Contoh: "Untuk informasi Kasubdit SIM saat ini, silakan cek langsung ke website resmi korlantas.polri.go.id yaa"
-
-4. SELALU tambahkan disclaimer bahwa:
-   "Untuk info paling update, bisa cek website resmi korlantas.polri.go.id atau Instagram @korlantas_polri"
-
-━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
-
-⚠️⚠️⚠️ BATASAN PENGETAHUAN - SANGAT PENTING ⚠️⚠️⚠️
-1. Data pejabat di atas adalah data terkini yang dimiliki sistem (Januari 2026)
-2. Posisi pejabat dapat berubah sewaktu-waktu
-3. Untuk informasi paling akurat dan terkini, WAJIB sarankan cek website resmi
-
-4. Selalu berikan informasi yang ada + arahkan ke sumber resmi untuk konfirmasi
-5. Untuk tanggal/waktu, gunakan INFORMASI WAKTU SAAT INI yang sudah diberikan
-
-PERSONA "SOBAT LANTAS":
-✓ Anda adalah asisten yang ramah, peduli, dan fokus pada keselamatan berkendara
-✓ Gunakan bahasa yang santai tapi tetap informatif dan profesional
-✓ Tunjukkan empati dan kepedulian terhadap keselamatan pengguna
-✓ Berikan nasihat dengan nada yang bersahabat tapi tegas saat menyangkut keselamatan
-
-GAYA KOMUNIKASI:
-✓ Ramah, sopan, dan bersahabat (seperti teman yang peduli)
-✓ Jelas, ringkas, dan mudah dipahami
-✓ Fokus pada keselamatan pengguna dan keluarga
-✓ Gunakan emoji yang sesuai untuk visual clarity
-✓ Berikan jawaban dalam Bahasa Indonesia yang baik dan santai
-✓ Jika kondisi berbahaya, berikan peringatan yang tegas tapi tetap ramah
-✓ Tunjukkan bahwa Anda mengingat percakapan sebelumnya dengan mereferensikannya
-✓ Gunakan kata-kata seperti "yaa", "loh", "nih" untuk kesan ramah (tidak berlebihan)
-✓ JANGAN gunakan format Markdown (# untuk heading, * untuk bold/italic, ** untuk list)
-✓ Gunakan bahasa natural tanpa formatting karakter khusus
-✓ Untuk penekanan, gunakan huruf kapital atau emoji, BUKAN asterisk (*)
-✓ Untuk list/poin, gunakan angka (1. 2. 3.) atau emoji, BUKAN asterisk (*)
-
-CONTOH RESPONS YANG BAIK:
-- Pesan PERTAMA (jika nama user diketahui): "Halo [Nama User]! Demi keselamatan, sebaiknya jangan bonceng dua anak kecil yaa. Bahaya banget loh. Anak-anak harus pakai helm SNI dan cukup satu saja yang dibonceng. Utamakan keselamatan keluarga kita!"
-- Pesan PERTAMA (jika nama belum diketahui): "Halo Sobat Lantas! Demi keselamatan, sebaiknya jangan bonceng dua anak kecil yaa. Bahaya banget loh. Anak-anak harus pakai helm SNI dan cukup satu saja yang dibonceng. Utamakan keselamatan keluarga kita!"
-- Pesan lanjutan: "Wah, kecepatan kamu saat ini [kecepatan] km/jam sudah melebihi batas dalam kota nih. Kurangi kecepatan yaa demi keselamatan!"
-- PENTING: Hanya gunakan SATU sapaan saja (hanya nama ATAU Sobat Lantas, JANGAN keduanya)
-- "Kondisi lalu lintas di depan lagi padat nih. Mending ambil rute alternatif biar gak macet."
-- "Oke, untuk ke Kantor Samsat Tangsel yang tadi kamu sebutkan, jaraknya sekitar..."
-- E-Tilang (ada pelanggaran): "Untuk kendaraan dengan nomor polisi [nomor], ada [jumlah] pelanggaran yang tercatat nih. Total dendanya Rp [total]. Sebaiknya segera dilunasi yaa. Kamu bisa bayar online di https://etle-pmj.id/ untuk kemudahan pembayaran."
-- E-Tilang (bersih): "Kabar baik! Untuk kendaraan dengan nomor polisi [nomor] tidak ada tilang yang tercatat. Tetap patuhi peraturan lalu lintas yaa!"
-- Pelayanan (follow-up): "Apakah Anda membutuhkan bantuan untuk proses pembuatan SIM A ini?"
-- Pelayanan (minta upload): "Baik! Untuk melanjutkan proses pembuatan SIM A, silakan upload dokumen-dokumen berikut yaa:
-  1. KTP Asli
-  2. Fotokopi KTP
-  3. HP Aktif
-  
-  Silakan upload dokumen-dokumen tersebut di sini 📤"
-
-INSTRUKSI KHUSUS E-TILANG:
-- Jika ada data e-tilang di konteks, sampaikan informasinya dengan jelas dan ramah
-- Untuk pelanggaran yang belum dibayar, ingatkan untuk segera melunasi dan WAJIB berikan link pembayaran: https://etle-pmj.id/
-- Contoh: "Sebaiknya segera dilunasi yaa. Kamu bisa bayar online di https://etle-pmj.id/ untuk kemudahan pembayaran."
-- Berikan apresiasi jika kendaraan bersih dari pelanggaran
-- Gunakan format yang mudah dibaca dengan poin-poin jika ada banyak pelanggaran
-
-INSTRUKSI KHUSUS PELAYANAN:
-- Jika ada data pelayanan di konteks, sampaikan dengan jelas dokumen apa saja yang diperlukan
-- Gunakan format yang rapi dan mudah dibaca (dengan numbering)
-- WAJIB memberikan follow-up question yang proaktif:
-  * LANGKAH 1: Tanyakan "Apakah Anda memerlukan bantuan untuk proses [nama pelayanan] ini?"
-  * LANGKAH 2: Jika user menjawab YA/mau dibantu, LANGSUNG minta upload dokumen dengan format:
-    "Baik! Silakan upload dokumen-dokumen berikut yaa:
-     1. [Dokumen 1]
-     2. [Dokumen 2]
-     ...
-     
-     Silakan upload dokumen-dokumen tersebut di sini 📤"
-  * LANGKAH 3: Jika user menyatakan sudah upload dokumen (kata kunci: "sudah upload", "sudah saya kirim", "done", "sudah", "oke sudah"), berikan konfirmasi dengan ramah (gunakan nama user jika ada):
-    "Terima kasih [Nama User / Sobat Lantas]! ✅
-    
-    Dokumen Anda sudah kami terima dengan baik. Tim kami akan segera memproses permohonan [nama pelayanan] Anda.
-    
-    📋 Yang akan kami lakukan selanjutnya:
-    1. Verifikasi kelengkapan dokumen
-    2. Pemeriksaan validitas data
-    3. Proses administrasi
-    
-    Estimasi waktu proses: [sesuai jenis pelayanan, misal: 1-3 hari kerja]
-    
-    Anda akan mendapatkan notifikasi melalui HP yang terdaftar untuk update status permohonan.
-    
-    Ada yang ingin ditanyakan lagi? 😊"
-
-INSTRUKSI KHUSUS UPLOAD DOKUMEN:
-- Jika context.HasUploadedDocuments = true, ini berarti user SUDAH UPLOAD DOKUMEN
-- WAJIB berikan konfirmasi penerimaan dokumen dengan format berikut (gunakan nama user jika ada):
-  "Terima kasih [Nama User / Sobat Lantas]! ✅
-  
-  Dokumen yang Anda upload sudah kami terima dengan baik ({UploadedDocumentCount} dokumen).
-  
-  Tim kami akan segera memproses permohonan Anda dengan tahapan:
-  📋 Verifikasi kelengkapan dokumen
-  🔍 Pemeriksaan validitas data  
-  ⚙️ Proses administrasi
-  
-  Estimasi waktu proses: 1-3 hari kerja
-  
-  Anda akan mendapatkan notifikasi melalui HP yang terdaftar untuk update status permohonan.
-  
-  Ada yang ingin ditanyakan lagi? 😊"
-- Gunakan emoji ✅ untuk konfirmasi
-- Tunjukkan profesionalisme dan kepastian proses
-- Berikan informasi yang jelas tentang tahapan selanjutnya
-- Gunakan emoji 📤 atau 📎 untuk menunjukkan aksi upload dokumen
-- Gunakan emoji ✅ untuk konfirmasi dokumen diterima
-- JANGAN gunakan sapaan di akhir kalimat seperti "Sobat Lantas" kecuali memang sangat diperlukan
-- Tunjukkan sikap siap membantu dan mendorong pengguna untuk melanjutkan
-- Jika pengguna bertanya tentang pelayanan yang tidak ada di database, berikan saran untuk menghubungi kantor polisi terdekat
-
-Berikan respons yang membantu, relevan, dan sesuai dengan situasi pengguna saat ini.`,
-		greetingInstruction,
-		currentDate,
-		currentDateTime,
-		userNameContext,
-		context.Location,
-		context.Latitude,
-		context.Longitude,
-		context.Speed,
-		context.Traffic,
-		context.HasUploadedDocuments,
-		context.UploadedDocumentCount,
-		etilangInfo,
-		pelayananInfo,
-		simFlowContext,
-		userName,
-	)
-}
-
-// ChatWithHistory allows for conversation history (optional for MVP)
-func (s *OpenAIService) ChatWithHistory(messages []models.OpenAIMessage) (string, error) {
-	apiContext := ctx.Background()
-	temperature := float64(0.7)
-	maxTokens := int64(1000)
-
-	// Convert to OpenAI SDK format
-	sdkMessages := []openai.ChatCompletionMessageParamUnion{}
-	for _, msg := range messages {
-		switch msg.Role {
-		case "system":
-			sdkMessages = append(sdkMessages, openai.SystemMessage(msg.Content))
-		case "user":
-			sdkMessages = append(sdkMessages, openai.UserMessage(msg.Content))
-		case "assistant":
-			sdkMessages = append(sdkMessages, openai.AssistantMessage(msg.Content))
+	if context.PelayananInfo != nil && context.PelayananInfo.Found {
+		flow := context.PelayananInfo.Flow
+		pelayanan := &PelayananPromptData{
+			Title:     flow.Title,
+			FlowID:    flow.FlowID,
+			Documents: flow.DocumentsNeeded,
+			Script:    flow.Script,
 		}
-	}
-
-	response, err := s.client.Chat.Completions.New(apiContext, openai.ChatCompletionNewParams{
-		Model:               openai.ChatModel(config.AppConfig.OpenAIModel),
-		Messages:            sdkMessages,
-		Temperature:         openai.Float(temperature),
-		MaxCompletionTokens: openai.Int(maxTokens),
-	})
 
-	if err != nil {
-		return "", fmt.Errorf("failed to call OpenAI API: %w", err)
+		if s.rulesService != nil {
+			locationContext := context.Location
+			if locationContext == "" {
+				locationContext = "lokasi Anda"
+			}
+			pelayanan.ResponseRule = s.rulesService.FormatResponseRuleForPrompt(s.rulesService.GetResponseRule(flow.Title), data.UserName, locationContext)
+			pelayanan.LocationRule = s.rulesService.FormatLocationRuleForPrompt(s.rulesService.GetLocationRule(flow.Title))
+		} else {
+			log.Println("⚠️  RulesService is nil, skipping rules lookup")
+		}
+		data.Pelayanan = pelayanan
 	}
 
-	if len(response.Choices) > 0 {
-		return response.Choices[0].Message.Content, nil
-	}
+	return data
+}
 
-	return "", fmt.Errorf("empty response from OpenAI")
+// ChatWithHistory allows for conversation history (optional for MVP)
+func (s *OpenAIService) ChatWithHistory(requestCtx ctx.Context, messages []models.OpenAIMessage) (string, error) {
+	completion, err := s.model.Complete(requestCtx, CompletionRequest{
+		Purpose:     PurposeChat,
+		Messages:    messages,
+		Temperature: 0.7,
+		MaxTokens:   1000,
+	})
+	return completion.Content, err
 }
 
 // Helper function to format Rupiah
@@ -694,10 +276,10 @@ func formatRupiah(amount int) string {
 		return str
 	}
 
-	// Build from right to left
+	// Build from right to left, a dot before every third digit
 	result := ""
 	for i := 0; i < n; i++ {
-		if i > 0 && (n-i)%3 == 0 {
+		if i > 0 && i%3 == 0 {
 			result = "." + result
 		}
 		result = string(str[n-1-i]) + result
@@ -0,0 +1,23 @@
+package services
+
+import "testing"
+
+func TestFormatRupiah(t *testing.T) {
+	tests := []struct {
+		amount int
+		want   string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1000, "1.000"},
+		{12345, "12.345"},
+		{250000, "250.000"},
+		{1000000, "1.000.000"},
+		{3000000, "3.000.000"},
+	}
+	for _, tt := range tests {
+		if got := formatRupiah(tt.amount); got != tt.want {
+			t.Errorf("formatRupiah(%d) = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package services
 
 import (
+	ctx "context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"police-assistant-backend/config"
@@ -18,7 +20,8 @@ const (
 )
 
 type ORSService struct {
-	client *resty.Client
+	client   *resty.Client
+	upstream *Upstream
 }
 
 func NewORSService() *ORSService {
@@ -31,11 +34,15 @@ func NewORSService() *ORSService {
 
 	return &ORSService{
 		client: client,
+		upstream: NewUpstream("ors", RetryPolicy{
+			Timeout:    config.AppConfig.ORSTimeout,
+			MaxRetries: config.AppConfig.ORSMaxRetries,
+		}),
 	}
 }
 
 // GetTrafficInfo gets current route information around a location
-func (s *ORSService) GetTrafficInfo(lat, lng float64) (map[string]interface{}, error) {
+func (s *ORSService) GetTrafficInfo(requestCtx ctx.Context, lat, lng float64) (map[string]interface{}, error) {
 	// Create a small route to nearby point to estimate traffic
 	destLat := lat + 0.01 // ~1km away
 	destLng := lng + 0.01
@@ -51,11 +58,13 @@ func (s *ORSService) GetTrafficInfo(lat, lng float64) (map[string]interface{}, e
 	log.Printf("🗺️  Getting traffic info for: %.6f, %.6f", lat, lng)
 
 	var result map[string]interface{}
-	resp, err := s.client.R().
-		SetHeader("Accept", "application/json, application/geo+json").
-		SetBody(requestBody).
-		SetResult(&result).
-		Post(orsDirectionsURL)
+	resp, err := s.do(requestCtx, func(req *resty.Request) (*resty.Response, error) {
+		return req.
+			SetHeader("Accept", "application/json, application/geo+json").
+			SetBody(requestBody).
+			SetResult(&result).
+			Post(orsDirectionsURL)
+	})
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get directions: %w", err)
@@ -114,15 +123,15 @@ func (s *ORSService) GetTrafficInfo(lat, lng float64) (map[string]interface{}, e
 }
 
 // GetAlternativeRoutes gets multiple route options
-func (s *ORSService) GetAlternativeRoutes(origin, destination string) ([]map[string]interface{}, error) {
+func (s *ORSService) GetAlternativeRoutes(requestCtx ctx.Context, origin, destination string) ([]map[string]interface{}, error) {
 	// Parse or geocode origin
-	originCoords, err := s.parseOrGeocode(origin)
+	originCoords, err := s.parseOrGeocode(requestCtx, origin)
 	if err != nil {
 		return nil, fmt.Errorf("failed to process origin '%s': %w", origin, err)
 	}
 
 	// Parse or geocode destination
-	destCoords, err := s.parseOrGeocode(destination)
+	destCoords, err := s.parseOrGeocode(requestCtx, destination)
 	if err != nil {
 		return nil, fmt.Errorf("failed to process destination '%s': %w", destination, err)
 	}
@@ -144,11 +153,13 @@ func (s *ORSService) GetAlternativeRoutes(origin, destination string) ([]map[str
 	log.Printf("📤 Request body: %s", string(reqBodyBytes))
 
 	var result map[string]interface{}
-	resp, err := s.client.R().
-		SetHeader("Accept", "application/json, application/geo+json").
-		SetBody(requestBody).
-		SetResult(&result).
-		Post(orsDirectionsURL)
+	resp, err := s.do(requestCtx, func(req *resty.Request) (*resty.Response, error) {
+		return req.
+			SetHeader("Accept", "application/json, application/geo+json").
+			SetBody(requestBody).
+			SetResult(&result).
+			Post(orsDirectionsURL)
+	})
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get routes: %w", err)
@@ -272,7 +283,7 @@ func (s *ORSService) GetAlternativeRoutes(origin, destination string) ([]map[str
 }
 
 // ReverseGeocode converts coordinates to address using Nominatim (OSM)
-func (s *ORSService) ReverseGeocode(lat, lng float64) (string, error) {
+func (s *ORSService) ReverseGeocode(requestCtx ctx.Context, lat, lng float64) (string, error) {
 	params := map[string]string{
 		"point.lon": fmt.Sprintf("%.6f", lng),
 		"point.lat": fmt.Sprintf("%.6f", lat),
@@ -280,10 +291,12 @@ func (s *ORSService) ReverseGeocode(lat, lng float64) (string, error) {
 	}
 
 	var result map[string]interface{}
-	resp, err := s.client.R().
-		SetQueryParams(params).
-		SetResult(&result).
-		Get(orsReverseURL)
+	resp, err := s.do(requestCtx, func(req *resty.Request) (*resty.Response, error) {
+		return req.
+			SetQueryParams(params).
+			SetResult(&result).
+			Get(orsReverseURL)
+	})
 
 	if err != nil {
 		return "", fmt.Errorf("failed to reverse geocode: %w", err)
@@ -308,6 +321,27 @@ func (s *ORSService) ReverseGeocode(lat, lng float64) (string, error) {
 	return "Lokasi tidak diketahui", nil
 }
 
+// do menjalankan request ORS dengan timeout per percobaan, retry untuk 429/5xx, dan circuit breaker
+func (s *ORSService) do(requestCtx ctx.Context, send func(req *resty.Request) (*resty.Response, error)) (*resty.Response, error) {
+	var resp *resty.Response
+	err := s.upstream.Do(requestCtx, func(attemptCtx ctx.Context) error {
+		r, err := send(s.client.R().SetContext(attemptCtx))
+		if err != nil {
+			return err
+		}
+		if retryableStatus(r.StatusCode()) {
+			return &StatusError{
+				StatusCode: r.StatusCode(),
+				RetryAfter: parseRetryAfter(r.Header().Get("Retry-After")),
+				Body:       r.String(),
+			}
+		}
+		resp = r
+		return nil
+	})
+	return resp, err
+}
+
 // getStepTypeName converts ORS step type code to human-readable name
 func getStepTypeName(stepType int) string {
 	switch stepType {
@@ -349,7 +383,7 @@ func getStepTypeName(stepType int) string {
 }
 
 // parseOrGeocode tries to parse coordinates from string, or geocode if it's an address
-func (s *ORSService) parseOrGeocode(location string) (map[string]interface{}, error) {
+func (s *ORSService) parseOrGeocode(requestCtx ctx.Context, location string) (map[string]interface{}, error) {
 	// Try to parse as coordinates first (format: "lat,lng" or "lat, lng")
 	location = strings.TrimSpace(location)
 	parts := strings.Split(location, ",")
@@ -376,16 +410,19 @@ func (s *ORSService) parseOrGeocode(location string) (map[string]interface{}, er
 
 	// If not valid coordinates, treat as address and geocode
 	log.Printf("🔍 Geocoding address: %s", location)
-	return s.geocode(location)
+	return s.geocode(requestCtx, location)
 }
 
 // geocode converts address to coordinates
-func (s *ORSService) geocode(address string) (map[string]interface{}, error) {
+func (s *ORSService) geocode(requestCtx ctx.Context, address string) (map[string]interface{}, error) {
 	// First attempt with full address
-	coords, err := s.geocodeAttempt(address)
+	coords, err := s.geocodeAttempt(requestCtx, address)
 	if err == nil {
 		return coords, nil
 	}
+	if errors.Is(err, ErrUpstreamUnavailable) {
+		return nil, err
+	}
 
 	log.Printf("⚠️  Full address geocoding failed, trying simplified query...")
 
@@ -394,7 +431,7 @@ func (s *ORSService) geocode(address string) (map[string]interface{}, error) {
 	simplifiedAddress := s.extractMainLocation(address)
 	if simplifiedAddress != address {
 		log.Printf("🔍 Trying with simplified address: %s", simplifiedAddress)
-		coords, err = s.geocodeAttempt(simplifiedAddress)
+		coords, err = s.geocodeAttempt(requestCtx, simplifiedAddress)
 		if err == nil {
 			return coords, nil
 		}
@@ -434,7 +471,7 @@ func (s *ORSService) extractMainLocation(address string) string {
 }
 
 // geocodeAttempt performs a single geocoding attempt
-func (s *ORSService) geocodeAttempt(address string) (map[string]interface{}, error) {
+func (s *ORSService) geocodeAttempt(requestCtx ctx.Context, address string) (map[string]interface{}, error) {
 	params := map[string]string{
 		"text": address,
 		"size": "5",
@@ -445,10 +482,12 @@ func (s *ORSService) geocodeAttempt(address string) (map[string]interface{}, err
 	}
 
 	var result map[string]interface{}
-	resp, err := s.client.R().
-		SetQueryParams(params).
-		SetResult(&result).
-		Get(orsGeocodeURL)
+	resp, err := s.do(requestCtx, func(req *resty.Request) (*resty.Response, error) {
+		return req.
+			SetQueryParams(params).
+			SetResult(&result).
+			Get(orsGeocodeURL)
+	})
 
 	if err != nil {
 		return nil, fmt.Errorf("geocoding failed: %w", err)
@@ -0,0 +1,382 @@
+package services
+
+import (
+	ctx "context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"police-assistant-backend/config"
+	"police-assistant-backend/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status tagihan
+const (
+	PaymentStatusPending = "pending" // Virtual account aktif, menunggu pembayaran
+	PaymentStatusPaid    = "paid"
+	PaymentStatusExpired = "expired" // Lewat ExpiresAt tanpa pembayaran
+	PaymentStatusFailed  = "failed"  // Provider gagal membuat virtual account atau membatalkan tagihan
+
+	// Uang masuk setelah tagihan expired sementara pelanggarannya sudah ditagih ulang di
+	// tagihan lain; pelanggaran tidak dianggap lunas dan dana harus dikembalikan manual
+	PaymentStatusRefundRequired = "refund_required"
+)
+
+// Status pelanggaran e-tilang
+const (
+	ViolationStatusUnpaid    = "unpaid"
+	ViolationStatusPaid      = "paid"
+	ViolationStatusProcessed = "processed" // Ada tagihan virtual account yang belum dibayar
+)
+
+// Payment provider yang didukung (PAYMENT_PROVIDER)
+const (
+	PaymentProviderFake = "fake" // Virtual account tiruan untuk development dan pengujian; pembayaran lewat cmd/paysim
+)
+
+// PaymentSignatureHeader berisi "sha256=<hex HMAC-SHA256 body dengan PAYMENT_WEBHOOK_SECRET>"
+const PaymentSignatureHeader = "X-Callback-Signature"
+
+var (
+	ErrPaymentNotFound      = errors.New("tagihan tidak ditemukan")
+	ErrViolationNotPayable  = errors.New("pelanggaran tidak bisa dibayar")
+	ErrNothingToPay         = errors.New("tidak ada denda yang belum dibayar")
+	ErrInvalidSignature     = errors.New("invalid callback signature")
+	ErrWebhookNotConfigured = errors.New("payment webhook secret is not configured")
+	ErrAmountMismatch       = errors.New("paid amount does not match the payment")
+	ErrOwnershipRequired    = errors.New("verifikasi kepemilikan kendaraan di session ini dulu sebelum membuat tagihan")
+)
+
+// VirtualAccount adalah nomor pembayaran yang dibuat payment provider
+type VirtualAccount struct {
+	Bank      string
+	Number    string
+	Reference string // ID tagihan di sisi provider
+}
+
+// PaymentProvider membuat virtual account untuk tagihan. Status pembayaran dikirim provider
+// ke webhook POST /api/v1/payments/webhook.
+type PaymentProvider interface {
+	// Name mengembalikan nama provider yang dicatat di tagihan
+	Name() string
+	// CreateVirtualAccount membuka virtual account sebesar payment.Amount yang berlaku sampai payment.ExpiresAt
+	CreateVirtualAccount(requestCtx ctx.Context, payment *models.Payment) (*VirtualAccount, error)
+}
+
+// NewPaymentProvider membuat PaymentProvider sesuai PAYMENT_PROVIDER
+func NewPaymentProvider() (PaymentProvider, error) {
+	switch config.AppConfig.PaymentProvider {
+	case PaymentProviderFake:
+		return NewFakePaymentProvider(config.AppConfig.PaymentVACompanyCode), nil
+	default:
+		return nil, fmt.Errorf("unsupported PAYMENT_PROVIDER %q", config.AppConfig.PaymentProvider)
+	}
+}
+
+// PaymentService membuat tagihan denda e-tilang dan memproses callback pembayaran
+type PaymentService struct {
+	etilangService *ETilangService
+	store          *PaymentStore
+	provider       PaymentProvider
+	audit          *AuditLog
+	webhookSecret  string
+	expiry         time.Duration
+}
+
+func NewPaymentService(etilangService *ETilangService, store *PaymentStore, provider PaymentProvider, audit *AuditLog) *PaymentService {
+	if config.AppConfig.PaymentWebhookSecret == "" {
+		log.Println("⚠️  PAYMENT_WEBHOOK_SECRET is not set, payment callbacks will be rejected")
+	}
+	log.Printf("✅ Payment Service initialized (provider: %s, expiry: %s)", provider.Name(), config.AppConfig.PaymentExpiry)
+
+	return &PaymentService{
+		etilangService: etilangService,
+		store:          store,
+		provider:       provider,
+		audit:          audit,
+		webhookSecret:  config.AppConfig.PaymentWebhookSecret,
+		expiry:         config.AppConfig.PaymentExpiry,
+	}
+}
+
+// CreatePayment membuat tagihan virtual account untuk pelanggaran yang belum dibayar.
+// violationIDs kosong berarti semua pelanggaran yang belum dibayar. Session harus sudah
+// memverifikasi kepemilikan kendaraan. Jika pelanggaran yang diminta sudah punya tagihan
+// pending, tagihan itu yang dikembalikan (created = false) tanpa membuat virtual account baru.
+func (s *PaymentService) CreatePayment(requestCtx ctx.Context, sessionID string, plateNumber string, violationIDs []string) (payment *models.Payment, created bool, err error) {
+	plate, ok := ParsePlateNumber(plateNumber)
+	if !ok {
+		return nil, false, fmt.Errorf("%w: %q", ErrInvalidPlateNumber, plateNumber)
+	}
+	if !s.etilangService.IsOwnershipVerified(sessionID, plate.String()) {
+		return nil, false, ErrOwnershipRequired
+	}
+
+	now := time.Now()
+	if len(violationIDs) > 0 {
+		if existing := s.findPending(plate, violationIDs, now); existing != nil {
+			log.Printf("💳 Reusing pending payment %s for %s (session: %s)", existing.ID, plate, sessionID)
+			return existing, false, nil
+		}
+	}
+
+	info, err := s.etilangService.lookup(requestCtx, plate)
+	if err != nil {
+		return nil, false, err
+	}
+	if !info.Found {
+		return nil, false, fmt.Errorf("nomor polisi %s: %w", plate, ErrPlateNotFound)
+	}
+
+	violations := make(map[string]models.ETilangViolation, len(info.Violations))
+	for _, violation := range info.Violations {
+		violations[violation.ID] = violation
+	}
+	if len(violationIDs) == 0 {
+		for _, violation := range info.Violations {
+			if violation.Status == ViolationStatusUnpaid {
+				violationIDs = append(violationIDs, violation.ID)
+			}
+		}
+		if len(violationIDs) == 0 {
+			// Everything left is already waiting on a virtual account
+			if existing := s.findPending(plate, nil, now); existing != nil {
+				log.Printf("💳 Reusing pending payment %s for %s (session: %s)", existing.ID, plate, sessionID)
+				return existing, false, nil
+			}
+			return nil, false, ErrNothingToPay
+		}
+	}
+
+	amount := 0
+	seen := make(map[string]bool, len(violationIDs))
+	for _, id := range violationIDs {
+		violation, exists := violations[id]
+		switch {
+		case !exists:
+			return nil, false, fmt.Errorf("%w: %s bukan pelanggaran nomor polisi %s", ErrViolationNotPayable, id, plate)
+		case seen[id]:
+			return nil, false, fmt.Errorf("%w: %s dipilih lebih dari sekali", ErrViolationNotPayable, id)
+		case violation.Status != ViolationStatusUnpaid:
+			return nil, false, fmt.Errorf("%w: %s berstatus %s", ErrViolationNotPayable, id, violation.Status)
+		}
+		seen[id] = true
+		amount += violation.Fine
+	}
+
+	payment = &models.Payment{
+		ID:           "PAY-" + strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", "")[:16]),
+		SessionID:    sessionID,
+		PlateNumber:  plate.String(),
+		ViolationIDs: violationIDs,
+		Amount:       amount,
+		Status:       PaymentStatusPending,
+		Provider:     s.provider.Name(),
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(s.expiry),
+	}
+
+	// Reserve first so a concurrent request cannot bill the same violations while the provider is called
+	if err := s.store.Reserve(payment, now); err != nil {
+		// A concurrent request from the owner may have just billed the same violations
+		if errors.Is(err, ErrViolationNotPayable) {
+			if existing := s.findPending(plate, violationIDs, now); existing != nil {
+				return existing, false, nil
+			}
+		}
+		return nil, false, err
+	}
+
+	account, err := s.provider.CreateVirtualAccount(requestCtx, payment)
+	if err != nil {
+		payment.Status = PaymentStatusFailed
+		if saveErr := s.store.Save(payment); saveErr != nil {
+			log.Printf("❌ Failed to release payment %s: %v", payment.ID, saveErr)
+		}
+		s.recordAudit("payment_failed", payment, err.Error())
+		return nil, false, fmt.Errorf("failed to create virtual account: %w", err)
+	}
+	payment.Bank = account.Bank
+	payment.VirtualAccount = account.Number
+	payment.ProviderRef = account.Reference
+	if err := s.store.Save(payment); err != nil {
+		return nil, false, err
+	}
+
+	s.recordAudit("payment_created", payment, fmt.Sprintf("%d violation(s), Rp %d", len(violationIDs), amount))
+	log.Printf("💳 Payment %s created for %s: Rp %d, VA %s %s (expires %s)",
+		payment.ID, plate, amount, payment.Bank, payment.VirtualAccount, payment.ExpiresAt.Format(time.RFC3339))
+	return payment, true, nil
+}
+
+// findPending mencari tagihan pending yang bisa dipakai ulang: tagihan dengan pelanggaran
+// yang persis sama, atau tagihan terbaru jika violationIDs kosong
+func (s *PaymentService) findPending(plate Plate, violationIDs []string, now time.Time) *models.Payment {
+	pending := s.store.PendingPayments(plate.String(), now)
+	if len(violationIDs) == 0 {
+		if len(pending) == 0 {
+			return nil
+		}
+		return &pending[0]
+	}
+
+	requested := make(map[string]bool, len(violationIDs))
+	for _, id := range violationIDs {
+		requested[id] = true
+	}
+	for i := range pending {
+		if len(pending[i].ViolationIDs) != len(requested) {
+			continue
+		}
+		matches := true
+		for _, id := range pending[i].ViolationIDs {
+			matches = matches && requested[id]
+		}
+		if matches {
+			return &pending[i]
+		}
+	}
+	return nil
+}
+
+// GetPayment mengambil tagihan beserta status terkininya
+func (s *PaymentService) GetPayment(paymentID string) (*models.Payment, error) {
+	payment, err := s.store.Get(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	refreshPaymentStatus(payment, time.Now())
+	return payment, nil
+}
+
+// HandleCallback memverifikasi tanda tangan callback provider lalu memperbarui status tagihan.
+// Callback yang sama boleh dikirim ulang; tagihan yang sudah dibayar tidak berubah lagi.
+// Pembayaran untuk tagihan expired yang pelanggarannya sudah ditagih ulang ditandai
+// refund_required dan tidak melunasi pelanggaran.
+func (s *PaymentService) HandleCallback(body []byte, signature string) (*models.Payment, error) {
+	if s.webhookSecret == "" {
+		return nil, ErrWebhookNotConfigured
+	}
+	expected := SignPaymentCallback(s.webhookSecret, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var callback models.PaymentCallback
+	if err := json.Unmarshal(body, &callback); err != nil {
+		return nil, fmt.Errorf("invalid callback body: %w", err)
+	}
+
+	paymentID := callback.PaymentID
+	if paymentID == "" {
+		existing, err := s.store.FindByVirtualAccount(callback.VirtualAccount)
+		if err != nil {
+			return nil, err
+		}
+		paymentID = existing.ID
+	}
+
+	now := time.Now()
+	var rebilled []string
+	payment, err := s.store.Update(paymentID, now, func(payment *models.Payment, billedElsewhere []string) error {
+		if callback.VirtualAccount != "" && callback.VirtualAccount != payment.VirtualAccount {
+			return fmt.Errorf("virtual account %s does not belong to payment %s", callback.VirtualAccount, payment.ID)
+		}
+		if payment.Status == PaymentStatusPaid || payment.Status == PaymentStatusRefundRequired {
+			return nil
+		}
+
+		switch callback.Status {
+		case PaymentStatusPaid:
+			if callback.Amount != payment.Amount {
+				return fmt.Errorf("%w: got %d, want %d", ErrAmountMismatch, callback.Amount, payment.Amount)
+			}
+			// Money already received is recorded even if the bank settled it after our expiry
+			paidAt := callback.PaidAt
+			if paidAt.IsZero() {
+				paidAt = now
+			}
+			payment.Status = PaymentStatusPaid
+			payment.PaidAt = &paidAt
+			if len(billedElsewhere) > 0 {
+				// The reservation lapsed and another payment now owns these violations
+				payment.Status = PaymentStatusRefundRequired
+				rebilled = billedElsewhere
+			}
+		case PaymentStatusExpired, PaymentStatusFailed:
+			payment.Status = callback.Status
+		default:
+			return fmt.Errorf("unknown callback status %q", callback.Status)
+		}
+		if callback.Reference != "" {
+			payment.ProviderRef = callback.Reference
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rebilled) > 0 {
+		s.recordAudit("payment_"+payment.Status, payment, fmt.Sprintf("callback %s, violation(s) %s billed in another payment", callback.Reference, strings.Join(rebilled, ", ")))
+		log.Printf("⚠️  Payment %s paid after expiry but %s already billed elsewhere, refund required (Rp %d)",
+			payment.ID, strings.Join(rebilled, ", "), payment.Amount)
+		return payment, nil
+	}
+	s.recordAudit("payment_"+payment.Status, payment, "callback "+callback.Reference)
+	log.Printf("💳 Payment %s callback: %s (Rp %d)", payment.ID, payment.Status, payment.Amount)
+	return payment, nil
+}
+
+func (s *PaymentService) recordAudit(event string, payment *models.Payment, detail string) {
+	s.audit.Record(AuditEntry{
+		Event:     event,
+		SessionID: payment.SessionID,
+		Subject:   payment.PlateNumber,
+		Result:    payment.ID,
+		Detail:    detail,
+	})
+}
+
+// SignPaymentCallback menghitung nilai header X-Callback-Signature untuk body callback
+func SignPaymentCallback(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// refreshPaymentStatus menandai tagihan pending yang sudah lewat batas waktu sebagai expired
+func refreshPaymentStatus(payment *models.Payment, now time.Time) {
+	if payment.Status == PaymentStatusPending && now.After(payment.ExpiresAt) {
+		payment.Status = PaymentStatusExpired
+	}
+}
+
+// paymentCoversViolations: pelanggaran di tagihan ini tidak boleh ditagih lagi
+func paymentCoversViolations(status string) bool {
+	return status == PaymentStatusPending || status == PaymentStatusPaid
+}
+
+// applyPaymentStatuses menimpa status pelanggaran dengan status tagihan lalu menghitung
+// ulang TotalFine dan HasViolation dari pelanggaran yang belum dibayar
+func applyPaymentStatuses(info *models.ETilangInfo, statuses map[string]string) {
+	info.TotalFine = 0
+	info.HasViolation = false
+	for i := range info.Violations {
+		violation := &info.Violations[i]
+		if status, exists := statuses[violation.ID]; exists && violation.Status != ViolationStatusPaid {
+			violation.Status = status
+		}
+		if violation.Status != ViolationStatusPaid {
+			info.TotalFine += violation.Fine
+			info.HasViolation = true
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	ctx "context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"math/big"
+	"police-assistant-backend/models"
+)
+
+// FakePaymentProvider membuat nomor virtual account berformat BRIVA (kode perusahaan 5 digit
+// + nomor pelanggan 11 digit) tanpa menghubungi bank. Pembayaran disimulasikan dengan
+// mengirim callback bertanda tangan, misal lewat cmd/paysim.
+type FakePaymentProvider struct {
+	companyCode string
+}
+
+func NewFakePaymentProvider(companyCode string) *FakePaymentProvider {
+	log.Printf("✅ Payment provider: fake (BRIVA %s)", companyCode)
+	return &FakePaymentProvider{companyCode: companyCode}
+}
+
+func (p *FakePaymentProvider) Name() string {
+	return PaymentProviderFake
+}
+
+func (p *FakePaymentProvider) CreateVirtualAccount(requestCtx ctx.Context, payment *models.Payment) (*VirtualAccount, error) {
+	customer, err := rand.Int(rand.Reader, big.NewInt(1e11))
+	if err != nil {
+		return nil, err
+	}
+	return &VirtualAccount{
+		Bank:      "BRI",
+		Number:    fmt.Sprintf("%s%011d", p.companyCode, customer),
+		Reference: "FAKE-" + payment.ID,
+	}, nil
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"police-assistant-backend/models"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var paymentsBucket = []byte("payments")
+
+// PaymentStore menyimpan tagihan denda di file BoltDB. Tagihan selalu persisten (tidak
+// mengikuti SESSION_BACKEND) karena status pembayaran tidak boleh hilang saat restart.
+type PaymentStore struct {
+	db *bolt.DB
+}
+
+func NewPaymentStore(path string) (*PaymentStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create payment directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(paymentsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	log.Printf("✅ Payment Store initialized (%s)", path)
+	return &PaymentStore{db: db}, nil
+}
+
+// Reserve menyimpan tagihan baru jika tidak ada pelanggarannya yang sudah dibayar atau
+// sedang menunggu pembayaran di tagihan lain. Pengecekan dan penyimpanan berada dalam satu
+// transaksi sehingga dua request bersamaan tidak bisa menagih pelanggaran yang sama.
+func (s *PaymentStore) Reserve(payment *models.Payment, now time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		selected := make(map[string]bool, len(payment.ViolationIDs))
+		for _, id := range payment.ViolationIDs {
+			selected[id] = true
+		}
+
+		bucket := tx.Bucket(paymentsBucket)
+		err := bucket.ForEach(func(_, data []byte) error {
+			var existing models.Payment
+			if err := json.Unmarshal(data, &existing); err != nil {
+				return err
+			}
+			refreshPaymentStatus(&existing, now)
+			if existing.PlateNumber != payment.PlateNumber || !paymentCoversViolations(existing.Status) {
+				return nil
+			}
+			for _, id := range existing.ViolationIDs {
+				if selected[id] {
+					return fmt.Errorf("%w: %s (tagihan %s, status %s)", ErrViolationNotPayable, id, existing.ID, existing.Status)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return putPayment(bucket, payment)
+	})
+}
+
+// Save memperbarui tagihan yang sudah ada
+func (s *PaymentStore) Save(payment *models.Payment) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putPayment(tx.Bucket(paymentsBucket), payment)
+	})
+}
+
+// Update menjalankan fn terhadap tagihan dalam satu transaksi; perubahan disimpan jika fn tidak error.
+// billedElsewhere berisi pelanggaran tagihan ini yang sudah dibayar atau sedang menunggu
+// pembayaran di tagihan lain, misalnya karena tagihan ini expired lalu ditagih ulang.
+func (s *PaymentStore) Update(paymentID string, now time.Time, fn func(payment *models.Payment, billedElsewhere []string) error) (*models.Payment, error) {
+	var payment *models.Payment
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(paymentsBucket)
+		data := bucket.Get([]byte(paymentID))
+		if data == nil {
+			return ErrPaymentNotFound
+		}
+		payment = &models.Payment{}
+		if err := json.Unmarshal(data, payment); err != nil {
+			return err
+		}
+
+		selected := make(map[string]bool, len(payment.ViolationIDs))
+		for _, id := range payment.ViolationIDs {
+			selected[id] = true
+		}
+		var billedElsewhere []string
+		err := bucket.ForEach(func(_, data []byte) error {
+			var other models.Payment
+			if err := json.Unmarshal(data, &other); err != nil {
+				return err
+			}
+			refreshPaymentStatus(&other, now)
+			if other.ID == payment.ID || other.PlateNumber != payment.PlateNumber || !paymentCoversViolations(other.Status) {
+				return nil
+			}
+			for _, id := range other.ViolationIDs {
+				if selected[id] {
+					billedElsewhere = append(billedElsewhere, id)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := fn(payment, billedElsewhere); err != nil {
+			return err
+		}
+		return putPayment(bucket, payment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// Get mengambil tagihan berdasarkan ID
+func (s *PaymentStore) Get(paymentID string) (*models.Payment, error) {
+	var payment *models.Payment
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(paymentsBucket).Get([]byte(paymentID))
+		if data == nil {
+			return ErrPaymentNotFound
+		}
+		payment = &models.Payment{}
+		return json.Unmarshal(data, payment)
+	})
+	return payment, err
+}
+
+// FindByVirtualAccount mencari tagihan berdasarkan nomor virtual account
+func (s *PaymentStore) FindByVirtualAccount(number string) (*models.Payment, error) {
+	var found *models.Payment
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(paymentsBucket).ForEach(func(_, data []byte) error {
+			var payment models.Payment
+			if err := json.Unmarshal(data, &payment); err != nil {
+				return err
+			}
+			if found == nil && payment.VirtualAccount == number {
+				found = &payment
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrPaymentNotFound
+	}
+	return found, nil
+}
+
+// PendingPayments mengembalikan tagihan pending sebuah nomor polisi yang virtual account-nya
+// sudah dibuat dan belum lewat batas waktu, terbaru lebih dulu
+func (s *PaymentStore) PendingPayments(plateNumber string, now time.Time) []models.Payment {
+	var pending []models.Payment
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(paymentsBucket).ForEach(func(_, data []byte) error {
+			var payment models.Payment
+			if err := json.Unmarshal(data, &payment); err != nil {
+				return err
+			}
+			refreshPaymentStatus(&payment, now)
+			if payment.PlateNumber == plateNumber && payment.Status == PaymentStatusPending && payment.VirtualAccount != "" {
+				pending = append(pending, payment)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		log.Printf("❌ Failed to read payments for %s: %v", plateNumber, err)
+		return nil
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].CreatedAt.After(pending[j].CreatedAt)
+	})
+	return pending
+}
+
+// ViolationStatuses mengembalikan status pelanggaran sebuah nomor polisi menurut tagihan:
+// "paid" jika sudah dibayar, "processed" jika menunggu pembayaran virtual account
+func (s *PaymentStore) ViolationStatuses(plateNumber string, now time.Time) map[string]string {
+	statuses := make(map[string]string)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(paymentsBucket).ForEach(func(_, data []byte) error {
+			var payment models.Payment
+			if err := json.Unmarshal(data, &payment); err != nil {
+				return err
+			}
+			refreshPaymentStatus(&payment, now)
+			if payment.PlateNumber != plateNumber {
+				return nil
+			}
+			for _, id := range payment.ViolationIDs {
+				switch {
+				case payment.Status == PaymentStatusPaid:
+					statuses[id] = ViolationStatusPaid
+				case payment.Status == PaymentStatusPending && statuses[id] != ViolationStatusPaid:
+					statuses[id] = ViolationStatusProcessed
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		log.Printf("❌ Failed to read payments for %s: %v", plateNumber, err)
+	}
+	return statuses
+}
+
+func putPayment(bucket *bolt.Bucket, payment *models.Payment) error {
+	data, err := json.Marshal(payment)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(payment.ID), data)
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	ctx "context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"police-assistant-backend/models"
+	"testing"
+	"time"
+)
+
+const testWebhookSecret = "rahasia"
+
+func newTestPaymentService(t *testing.T) *PaymentService {
+	t.Helper()
+	etilangService := newTestETilangService(t)
+	return &PaymentService{
+		etilangService: etilangService,
+		store:          etilangService.payments,
+		provider:       NewFakePaymentProvider("77777"),
+		audit:          NewAuditLog(filepath.Join(t.TempDir(), "audit.jsonl")),
+		webhookSecret:  testWebhookSecret,
+		expiry:         time.Hour,
+	}
+}
+
+// verifiedSession membuat session yang sudah membuktikan kepemilikan B 1234 SV
+func verifiedSession(t *testing.T, service *PaymentService) string {
+	t.Helper()
+	sessionID, _, err := GetSessionStore().CreateSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	requester := OwnershipRequester{SessionID: sessionID, ClientIP: "198.51.100.20"}
+	if _, err := service.etilangService.VerifyOwnership(ctx.Background(), requester, "B 1234 SV", OwnershipEvidence{Digits: "23456"}, AuditSourceTool); err != nil {
+		t.Fatal(err)
+	}
+	return sessionID
+}
+
+func sendCallback(t *testing.T, service *PaymentService, payment *models.Payment, status string) *models.Payment {
+	t.Helper()
+	body, err := json.Marshal(models.PaymentCallback{
+		PaymentID:      payment.ID,
+		VirtualAccount: payment.VirtualAccount,
+		Status:         status,
+		Amount:         payment.Amount,
+		Reference:      "TEST",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := service.HandleCallback(body, SignPaymentCallback(testWebhookSecret, body))
+	if err != nil {
+		t.Fatalf("callback %s for %s: %v", status, payment.ID, err)
+	}
+	return updated
+}
+
+// TestCreatePaymentRequiresOwnership: tagihan hanya dibuat untuk session yang sudah verifikasi
+func TestCreatePaymentRequiresOwnership(t *testing.T) {
+	service := newTestPaymentService(t)
+	sessionID, _, err := GetSessionStore().CreateSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := service.CreatePayment(ctx.Background(), sessionID, "B 1234 SV", nil); !errors.Is(err, ErrOwnershipRequired) {
+		t.Fatalf("unverified session: err = %v, want ErrOwnershipRequired", err)
+	}
+	if pending := service.store.PendingPayments("B 1234 SV", time.Now()); len(pending) != 0 {
+		t.Fatalf("unverified session reserved %d payment(s)", len(pending))
+	}
+}
+
+// TestCreatePaymentReusesPending: permintaan ulang mengembalikan virtual account yang sama
+func TestCreatePaymentReusesPending(t *testing.T) {
+	service := newTestPaymentService(t)
+	sessionID := verifiedSession(t, service)
+	background := ctx.Background()
+
+	first, created, err := service.CreatePayment(background, sessionID, "B 1234 SV", nil)
+	if err != nil || !created {
+		t.Fatalf("first payment: created = %v, err = %v", created, err)
+	}
+
+	for _, violationIDs := range [][]string{nil, {"ETLE-B1234SV-02", "ETLE-B1234SV-01"}} {
+		again, created, err := service.CreatePayment(background, sessionID, "B 1234 SV", violationIDs)
+		if err != nil {
+			t.Fatalf("repeat %v: %v", violationIDs, err)
+		}
+		if created || again.ID != first.ID || again.VirtualAccount != first.VirtualAccount {
+			t.Fatalf("repeat %v: got %s (created %v), want pending %s", violationIDs, again.ID, created, first.ID)
+		}
+	}
+
+	if _, _, err := service.CreatePayment(background, sessionID, "B 1234 SV", []string{"ETLE-B1234SV-01"}); !errors.Is(err, ErrViolationNotPayable) {
+		t.Fatalf("subset of a pending payment: err = %v, want ErrViolationNotPayable", err)
+	}
+}
+
+// TestLatePaidCallbackAfterRebill: uang yang masuk untuk tagihan expired yang pelanggarannya
+// sudah ditagih ulang tidak boleh melunasi pelanggaran
+func TestLatePaidCallbackAfterRebill(t *testing.T) {
+	service := newTestPaymentService(t)
+	sessionID := verifiedSession(t, service)
+	background := ctx.Background()
+
+	expired, _, err := service.CreatePayment(background, sessionID, "B 1234 SV", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sendCallback(t, service, expired, PaymentStatusExpired)
+
+	rebilled, created, err := service.CreatePayment(background, sessionID, "B 1234 SV", nil)
+	if err != nil || !created {
+		t.Fatalf("rebill: created = %v, err = %v", created, err)
+	}
+
+	late := sendCallback(t, service, expired, PaymentStatusPaid)
+	if late.Status != PaymentStatusRefundRequired {
+		t.Fatalf("late callback status = %s, want %s", late.Status, PaymentStatusRefundRequired)
+	}
+	info, err := service.etilangService.lookup(background, mustParsePlate(t, "B 1234 SV"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, violation := range info.Violations {
+		if violation.Status != ViolationStatusProcessed {
+			t.Errorf("%s status = %s, want %s by %s", violation.ID, violation.Status, ViolationStatusProcessed, rebilled.ID)
+		}
+	}
+	if !info.HasViolation || info.TotalFine != 750000 {
+		t.Errorf("HasViolation = %v, TotalFine = %d, want true and 750000", info.HasViolation, info.TotalFine)
+	}
+
+	sendCallback(t, service, rebilled, PaymentStatusPaid)
+	info, err = service.etilangService.lookup(background, mustParsePlate(t, "B 1234 SV"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.HasViolation || info.TotalFine != 0 {
+		t.Errorf("after paying: HasViolation = %v, TotalFine = %d, want false and 0", info.HasViolation, info.TotalFine)
+	}
+}
+
+func mustParsePlate(t *testing.T, plateNumber string) Plate {
+	t.Helper()
+	plate, ok := ParsePlateNumber(plateNumber)
+	if !ok {
+		t.Fatalf("invalid plate %q", plateNumber)
+	}
+	return plate
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Plate adalah nomor polisi (TNKB): kode wilayah 1-2 huruf, nomor 1-4 angka, dan
+// huruf belakang 0-3 huruf. Contoh: B 1234 SV, AB 12 C, DK 1 A.
+type Plate struct {
+	Prefix string // Kode wilayah, misal B atau AB
+	Number string // Nomor registrasi tanpa nol di depan
+	Suffix string // Huruf belakang, kosong untuk sebagian plat dinas
+}
+
+// PlateRegion adalah wilayah registrasi sebuah kode wilayah beserta Polda yang membawahinya
+type PlateRegion struct {
+	Region string
+	Polda  string
+}
+
+// plateRegions memetakan kode wilayah TNKB ke wilayah registrasi dan Polda
+var plateRegions = map[string]PlateRegion{
+	// Sumatera
+	"BL": {"Aceh", "Polda Aceh"},
+	"BB": {"Sumatera Utara bagian barat (Tapanuli)", "Polda Sumatera Utara"},
+	"BK": {"Sumatera Utara bagian timur (Medan)", "Polda Sumatera Utara"},
+	"BA": {"Sumatera Barat", "Polda Sumatera Barat"},
+	"BM": {"Riau", "Polda Riau"},
+	"BP": {"Kepulauan Riau", "Polda Kepulauan Riau"},
+	"BH": {"Jambi", "Polda Jambi"},
+	"BG": {"Sumatera Selatan", "Polda Sumatera Selatan"},
+	"BN": {"Bangka Belitung", "Polda Kepulauan Bangka Belitung"},
+	"BD": {"Bengkulu", "Polda Bengkulu"},
+	"BE": {"Lampung", "Polda Lampung"},
+
+	// Jawa
+	"A":  {"Banten (Serang, Cilegon, Pandeglang, Lebak)", "Polda Banten"},
+	"B":  {"Jakarta, Depok, Tangerang, Bekasi", "Polda Metro Jaya"},
+	"D":  {"Bandung Raya, Cimahi", "Polda Jawa Barat"},
+	"E":  {"Cirebon, Indramayu, Majalengka, Kuningan", "Polda Jawa Barat"},
+	"F":  {"Bogor, Sukabumi, Cianjur", "Polda Jawa Barat"},
+	"T":  {"Purwakarta, Karawang, Subang", "Polda Jawa Barat"},
+	"Z":  {"Garut, Tasikmalaya, Sumedang, Ciamis, Banjar", "Polda Jawa Barat"},
+	"G":  {"Pekalongan, Tegal, Brebes, Batang, Pemalang", "Polda Jawa Tengah"},
+	"H":  {"Semarang, Salatiga, Kendal, Demak", "Polda Jawa Tengah"},
+	"K":  {"Pati, Kudus, Jepara, Rembang, Blora, Grobogan", "Polda Jawa Tengah"},
+	"R":  {"Banyumas, Cilacap, Purbalingga, Banjarnegara", "Polda Jawa Tengah"},
+	"AA": {"Magelang, Purworejo, Kebumen, Temanggung, Wonosobo", "Polda Jawa Tengah"},
+	"AD": {"Surakarta, Sukoharjo, Boyolali, Klaten, Sragen, Karanganyar, Wonogiri", "Polda Jawa Tengah"},
+	"AB": {"Daerah Istimewa Yogyakarta", "Polda DIY"},
+	"L":  {"Surabaya", "Polda Jawa Timur"},
+	"M":  {"Madura", "Polda Jawa Timur"},
+	"N":  {"Malang, Pasuruan, Probolinggo, Lumajang, Batu", "Polda Jawa Timur"},
+	"P":  {"Jember, Banyuwangi, Bondowoso, Situbondo", "Polda Jawa Timur"},
+	"S":  {"Bojonegoro, Tuban, Lamongan, Mojokerto, Jombang", "Polda Jawa Timur"},
+	"W":  {"Sidoarjo, Gresik", "Polda Jawa Timur"},
+	"AE": {"Madiun, Ngawi, Magetan, Ponorogo, Pacitan", "Polda Jawa Timur"},
+	"AG": {"Kediri, Blitar, Tulungagung, Nganjuk, Trenggalek", "Polda Jawa Timur"},
+
+	// Bali dan Nusa Tenggara
+	"DK": {"Bali", "Polda Bali"},
+	"DR": {"Lombok", "Polda Nusa Tenggara Barat"},
+	"EA": {"Sumbawa", "Polda Nusa Tenggara Barat"},
+	"DH": {"Timor", "Polda Nusa Tenggara Timur"},
+	"EB": {"Flores", "Polda Nusa Tenggara Timur"},
+	"ED": {"Sumba", "Polda Nusa Tenggara Timur"},
+
+	// Kalimantan
+	"KB": {"Kalimantan Barat", "Polda Kalimantan Barat"},
+	"DA": {"Kalimantan Selatan", "Polda Kalimantan Selatan"},
+	"KH": {"Kalimantan Tengah", "Polda Kalimantan Tengah"},
+	"KT": {"Kalimantan Timur", "Polda Kalimantan Timur"},
+	"KU": {"Kalimantan Utara", "Polda Kalimantan Utara"},
+
+	// Sulawesi
+	"DB": {"Sulawesi Utara daratan (Manado)", "Polda Sulawesi Utara"},
+	"DL": {"Sulawesi Utara kepulauan (Sangihe, Talaud)", "Polda Sulawesi Utara"},
+	"DM": {"Gorontalo", "Polda Gorontalo"},
+	"DN": {"Sulawesi Tengah", "Polda Sulawesi Tengah"},
+	"DT": {"Sulawesi Tenggara", "Polda Sulawesi Tenggara"},
+	"DD": {"Sulawesi Selatan bagian selatan (Makassar)", "Polda Sulawesi Selatan"},
+	"DP": {"Sulawesi Selatan bagian utara (Parepare)", "Polda Sulawesi Selatan"},
+	"DW": {"Sulawesi Selatan bagian timur (Bone, Wajo)", "Polda Sulawesi Selatan"},
+	"DC": {"Sulawesi Barat", "Polda Sulawesi Barat"},
+
+	// Maluku dan Papua
+	"DE": {"Maluku", "Polda Maluku"},
+	"DG": {"Maluku Utara", "Polda Maluku Utara"},
+	"PA": {"Papua", "Polda Papua"},
+	"PB": {"Papua Barat", "Polda Papua Barat"},
+}
+
+// plateStopwords adalah kata pendek dan satuan yang sering muncul setelah angka dalam chat
+// ("B 1234 ya", "H 2 jam"); ditulis huruf kecil, kata ini bukan huruf belakang plat.
+var plateStopwords = map[string]bool{
+	"DI": true, "KE": true, "YA": true, "YG": true, "DAN": true, "GA": true, "GAK": true,
+	"NYA": true, "ITU": true, "INI": true, "APA": true, "SIH": true, "KOK": true, "AJA": true,
+	"NIH": true, "TUH": true, "LHO": true, "KAH": true, "DGN": true, "SDH": true, "BLM": true,
+	"UDH": true, "MAS": true, "KAK": true, "PAK": true, "BU": true, "YAH": true, "DEH": true,
+	"JAM": true, "MNT": true, "DTK": true, "HR": true, "BLN": true, "THN": true, "KM": true,
+	"RB": true, "JT": true, "KG": true, "CC": true, "X": true,
+}
+
+// Kode wilayah, nomor, lalu huruf belakang; bagian boleh dipisah spasi atau tanda hubung
+var platePattern = regexp.MustCompile(`(?i)\b([a-z]{1,2})[\s-]*([0-9]{1,4})(?:[\s-]*([a-z]{1,3}))?\b`)
+
+// ParsePlateNumber mengurai satu nomor polisi, misal "b1234sv", "B-1234-SV", atau "AB 12 C".
+// Mengembalikan false jika format tidak valid atau kode wilayah tidak dikenal.
+func ParsePlateNumber(input string) (Plate, bool) {
+	input = strings.TrimSpace(input)
+	match := platePattern.FindStringSubmatchIndex(input)
+	if match == nil || match[0] != 0 || match[1] != len(input) {
+		return Plate{}, false
+	}
+	return newPlate(input, match)
+}
+
+// FindPlateNumbers mengembalikan semua nomor polisi valid di sebuah pesan, tanpa duplikat,
+// sesuai urutan kemunculan. Dalam teks bebas nomor tanpa huruf belakang diabaikan karena
+// terlalu mirip kode biasa ("A1", "B2").
+func FindPlateNumbers(message string) []Plate {
+	var plates []Plate
+	seen := make(map[string]bool)
+
+	for offset := 0; offset < len(message); {
+		match := platePattern.FindStringSubmatchIndex(message[offset:])
+		if match == nil {
+			break
+		}
+		for i := range match {
+			if match[i] >= 0 {
+				match[i] += offset
+			}
+		}
+
+		plate, ok := newPlate(message, match)
+		if !ok || plate.Suffix == "" {
+			// A rejected match ("di 2 B 1234 SV") may have swallowed the start of a real plate
+			offset = match[3]
+			continue
+		}
+		offset = match[1]
+		if !seen[plate.Normalized()] {
+			seen[plate.Normalized()] = true
+			plates = append(plates, plate)
+		}
+	}
+	return plates
+}
+
+// newPlate memvalidasi hasil platePattern: kode wilayah harus dikenal dan nomor tidak diawali nol
+func newPlate(input string, match []int) (Plate, bool) {
+	plate := Plate{
+		Prefix: strings.ToUpper(input[match[2]:match[3]]),
+		Number: input[match[4]:match[5]],
+	}
+	if _, known := plateRegions[plate.Prefix]; !known || plate.Number[0] == '0' {
+		return Plate{}, false
+	}
+
+	if match[6] >= 0 {
+		suffix := input[match[6]:match[7]]
+		separated := match[6] > match[5]
+		// A lowercase word after the number is usually the sentence continuing, not a suffix
+		if separated && suffix != strings.ToUpper(suffix) && plateStopwords[strings.ToUpper(suffix)] {
+			suffix = ""
+		}
+		plate.Suffix = strings.ToUpper(suffix)
+	}
+	return plate, true
+}
+
+// String mengembalikan nomor polisi dengan format standar: "B 1234 SV"
+func (p Plate) String() string {
+	if p.Suffix == "" {
+		return p.Prefix + " " + p.Number
+	}
+	return p.Prefix + " " + p.Number + " " + p.Suffix
+}
+
+// Normalized mengembalikan nomor polisi tanpa spasi, format yang dipakai provider e-tilang: "B1234SV"
+func (p Plate) Normalized() string {
+	return p.Prefix + p.Number + p.Suffix
+}
+
+// Region mengembalikan wilayah registrasi dan Polda dari kode wilayah
+func (p Plate) Region() PlateRegion {
+	return plateRegions[p.Prefix]
+}
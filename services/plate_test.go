@@ -0,0 +1,100 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePlateNumber(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // "" = invalid
+	}{
+		// Spacing, separators and case
+		{"B 1234 SV", "B 1234 SV"},
+		{"b1234sv", "B 1234 SV"},
+		{"B-1234-SV", "B 1234 SV"},
+		{"  b 1234   sv  ", "B 1234 SV"},
+		{"AB 12 C", "AB 12 C"},
+		{"dk1a", "DK 1 A"},
+		{"B 1234", "B 1234"},
+
+		// Region prefixes must be known
+		{"XX 1234 AB", ""},
+		{"Q 1234 AB", ""},
+		{"ABC 1234 D", ""},
+
+		// Invalid numbers and shapes
+		{"B 0123 SV", ""},
+		{"B 12345 SV", ""},
+		{"B SV", ""},
+		{"1234", ""},
+		{"B 1234 SVXX", ""},
+		{"", ""},
+		{"cek B 1234 SV", ""},
+	}
+	for _, tt := range tests {
+		plate, ok := ParsePlateNumber(tt.input)
+		got := ""
+		if ok {
+			got = plate.String()
+		}
+		if got != tt.want {
+			t.Errorf("ParsePlateNumber(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestPlateParts(t *testing.T) {
+	tests := []struct {
+		input      string
+		want       Plate
+		normalized string
+		polda      string
+	}{
+		{"B 1234 SV", Plate{"B", "1234", "SV"}, "B1234SV", "Polda Metro Jaya"},
+		{"ab-12-c", Plate{"AB", "12", "C"}, "AB12C", "Polda DIY"},
+		{"DK 1", Plate{"DK", "1", ""}, "DK1", "Polda Bali"},
+		{"PA 999 XYZ", Plate{"PA", "999", "XYZ"}, "PA999XYZ", "Polda Papua"},
+	}
+	for _, tt := range tests {
+		plate, ok := ParsePlateNumber(tt.input)
+		if !ok {
+			t.Errorf("ParsePlateNumber(%q) failed", tt.input)
+			continue
+		}
+		if plate != tt.want || plate.Normalized() != tt.normalized || plate.Region().Polda != tt.polda {
+			t.Errorf("%q = %+v (%s, %s), want %+v (%s, %s)", tt.input, plate, plate.Normalized(), plate.Region().Polda, tt.want, tt.normalized, tt.polda)
+		}
+	}
+}
+
+func TestFindPlateNumbers(t *testing.T) {
+	tests := []struct {
+		message string
+		want    []string
+	}{
+		{"cek tilang B 1234 SV dong", []string{"B 1234 SV"}},
+		{"mobil saya b1234sv sama ab 12 c", []string{"B 1234 SV", "AB 12 C"}},
+		{"B 1234 SV, b-1234-sv lagi", []string{"B 1234 SV"}},
+		// Lowercase stopwords after the number are not a suffix, so there is no plate
+		{"parkir di B 1234 ya", nil},
+		{"antre H 2 jam", nil},
+		// Uppercase is taken as written
+		{"plat H 2 JAM", []string{"H 2 JAM"}},
+		// A rejected match must not swallow the start of a real plate
+		{"di 2 B 1234 SV", []string{"B 1234 SV"}},
+		{"kode A1 dan B2", nil},
+		{"XX 1234 AB", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, plate := range FindPlateNumbers(tt.message) {
+			got = append(got, plate.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FindPlateNumbers(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
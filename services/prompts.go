@@ -0,0 +1,306 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"police-assistant-backend/models"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Template prompt yang wajib ada di PROMPTS_DIR
+const (
+	PromptSystem = "system.tmpl" // System prompt utama, memanggil template bagian lain
+	PromptMemory = "memory.tmpl" // Fakta session dan ringkasan percakapan
+	PromptTools  = "tools.tmpl"  // Instruksi tool calling
+
+	PromptChoiceClassifier = "choice_classifier.tmpl" // Classifier jawaban bebas ke pilihan node flow
+	PromptSummarize        = "summarize.tmpl"         // Peringkas history lama menjadi ringkasan berjalan
+)
+
+// promptVersionFile berisi label versi prompt yang dinaikkan manual saat isi prompt berubah
+const promptVersionFile = "VERSION"
+
+// PromptFixturesDir adalah subfolder PROMPTS_DIR berisi contoh SystemPromptData (*.json)
+const PromptFixturesDir = "fixtures"
+
+var requiredPrompts = []string{PromptSystem, PromptMemory, PromptTools, PromptChoiceClassifier, PromptSummarize}
+
+// SystemPromptData adalah data untuk semua template prompt. Setiap template menerima
+// struct yang sama sehingga fixture bisa dirender ke semua template.
+type SystemPromptData struct {
+	FirstMessage bool      `json:"first_message"` // Belum ada history maupun ringkasan
+	UserName     string    `json:"user_name"`     // Nama untuk sapaan, "Sobat Lantas" jika belum diketahui
+	KnownName    string    `json:"known_name"`    // Nama yang diberikan user, kosong jika belum diketahui
+	Now          time.Time `json:"now"`
+
+	Location              string  `json:"location"`
+	Latitude              float64 `json:"latitude"`
+	Longitude             float64 `json:"longitude"`
+	Speed                 float64 `json:"speed"`
+	Traffic               string  `json:"traffic"`
+	HasUploadedDocuments  bool    `json:"has_uploaded_documents"`
+	UploadedDocumentCount int     `json:"uploaded_document_count"`
+
+	ETilang         *models.ETilangInfo     `json:"etilang,omitempty"`
+	Regulations     []models.Regulation     `json:"regulations,omitempty"` // Pasal yang relevan dengan pertanyaan denda/sanksi
+	Pelayanan       *PelayananPromptData    `json:"pelayanan,omitempty"`
+	Flow            *models.FlowInfo        `json:"flow,omitempty"`
+	UploadRejection *models.UploadRejection `json:"upload_rejection,omitempty"`
+
+	PinnedFacts         map[string]string `json:"pinned_facts,omitempty"`
+	ConversationSummary string            `json:"conversation_summary,omitempty"`
+
+	Classifier *ChoicePromptData    `json:"classifier,omitempty"` // Hanya diisi untuk choice_classifier.tmpl
+	Summarize  *SummarizePromptData `json:"summarize,omitempty"`  // Hanya diisi untuk summarize.tmpl
+}
+
+// ChoicePromptData adalah pertanyaan node flow dan pilihan yang dicocokkan classifier
+type ChoicePromptData struct {
+	Question string       `json:"question"`
+	Choices  []FlowChoice `json:"choices"`
+}
+
+// SummarizePromptData adalah ringkasan berjalan dan pesan lama yang akan digabungkan ke dalamnya
+type SummarizePromptData struct {
+	PreviousSummary string                 `json:"previous_summary"`
+	Messages        []models.OpenAIMessage `json:"messages"`
+}
+
+// PelayananPromptData adalah pelayanan yang ditanyakan user beserta rule yang sudah diformat
+type PelayananPromptData struct {
+	Title        string                       `json:"title"`
+	FlowID       string                       `json:"flow_id"`
+	Documents    []string                     `json:"documents"`
+	ResponseRule string                       `json:"response_rule,omitempty"` // Dari RulesService.FormatResponseRuleForPrompt
+	LocationRule string                       `json:"location_rule,omitempty"` // Dari RulesService.FormatLocationRuleForPrompt
+	Script       []models.PelayananScriptTurn `json:"script,omitempty"`        // Dipakai jika tidak ada response rule
+}
+
+var promptFuncs = template.FuncMap{
+	"rupiah": formatRupiah,
+	"upper":  strings.ToUpper,
+	"inc":    func(i int) int { return i + 1 },
+}
+
+// PromptSet adalah satu versi template prompt yang sudah di-parse. Tidak berubah setelah
+// dimuat, jadi aman dipakai selama satu request walaupun template di-reload.
+type PromptSet struct {
+	Version   string // Label VERSION + hash isi template, dicatat di setiap balasan
+	templates *template.Template
+}
+
+// LoadPromptSet memuat semua file *.tmpl di dir. Nama template adalah nama file-nya.
+func LoadPromptSet(dir string) (*PromptSet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no *.tmpl files in %s", dir)
+	}
+	sort.Strings(paths)
+
+	// The hash makes an edited template visible in the version even if VERSION was not bumped
+	hash := sha256.New()
+	templates := template.New("").Funcs(promptFuncs).Option("missingkey=error")
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		name := filepath.Base(path)
+		if _, err := templates.New(name).Parse(string(content)); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(hash, "%s\x00%s\x00", name, content)
+	}
+
+	for _, name := range requiredPrompts {
+		if templates.Lookup(name) == nil {
+			return nil, fmt.Errorf("required template %s not found in %s", name, dir)
+		}
+	}
+
+	label := "unversioned"
+	if content, err := os.ReadFile(filepath.Join(dir, promptVersionFile)); err == nil && strings.TrimSpace(string(content)) != "" {
+		label = strings.TrimSpace(string(content))
+	}
+
+	return &PromptSet{
+		Version:   label + "+" + hex.EncodeToString(hash.Sum(nil))[:8],
+		templates: templates,
+	}, nil
+}
+
+// Render menjalankan satu template dengan data prompt
+func (p *PromptSet) Render(name string, data SystemPromptData) (string, error) {
+	var out strings.Builder
+	if err := p.templates.ExecuteTemplate(&out, name, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s (%s): %w", name, p.Version, err)
+	}
+	return out.String(), nil
+}
+
+// Names mengembalikan nama semua template di set ini, terurut
+func (p *PromptSet) Names() []string {
+	var names []string
+	for _, tmpl := range p.templates.Templates() {
+		if tmpl.Name() != "" {
+			names = append(names, tmpl.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Tanda hasil render yang salah: field tidak terisi ("<no value>") atau verb fmt yang tidak cocok ("%!").
+// Kurung kurawal sengaja tidak dicek karena teks user dan contoh JSON di prompt boleh memuatnya.
+var promptLeftoverMarkers = []string{"<no value>", "%!"}
+
+// CheckPromptOutput memastikan system prompt tidak kosong dan tidak ada placeholder tersisa
+func CheckPromptOutput(name string, output string) error {
+	if name == PromptSystem && strings.TrimSpace(output) == "" {
+		return fmt.Errorf("rendered system prompt is empty")
+	}
+	for _, marker := range promptLeftoverMarkers {
+		if strings.Contains(output, marker) {
+			return fmt.Errorf("output of %s contains %q", name, marker)
+		}
+	}
+	return nil
+}
+
+// Check merender setiap template terhadap setiap fixture di fixturesDir. Error parse
+// sudah tertangkap saat load; Check menangkap field yang salah, fungsi yang gagal,
+// dan placeholder yang tersisa di hasil render.
+func (p *PromptSet) Check(fixturesDir string) error {
+	paths, err := filepath.Glob(filepath.Join(fixturesDir, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		data, err := LoadPromptFixture(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		for _, name := range p.Names() {
+			output, err := p.Render(name, data)
+			if err == nil {
+				err = CheckPromptOutput(name, output)
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", filepath.Base(path), err)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadPromptFixture membaca satu fixture SystemPromptData; field yang tidak dikenal dianggap salah ketik
+func LoadPromptFixture(path string) (SystemPromptData, error) {
+	var data SystemPromptData
+
+	file, err := os.Open(path)
+	if err != nil {
+		return data, err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&data); err != nil {
+		return data, fmt.Errorf("invalid fixture: %w", err)
+	}
+	return data, nil
+}
+
+// PromptService menyimpan template prompt yang aktif. Dengan hot reload (dev), perubahan
+// file di PROMPTS_DIR langsung dipakai tanpa restart, setelah lolos render fixture.
+type PromptService struct {
+	dir       string
+	hotReload bool
+
+	mu      sync.Mutex
+	current *PromptSet
+	stamp   string // Nama, ukuran, dan waktu ubah file template saat terakhir dimuat
+}
+
+func NewPromptService(dir string, hotReload bool) (*PromptService, error) {
+	service := &PromptService{dir: dir, hotReload: hotReload}
+
+	set, err := service.load()
+	if err != nil {
+		return nil, err
+	}
+	service.current = set
+	service.stamp = service.fileStamp()
+
+	log.Printf("✅ Prompt Service initialized (%s, %d templates, hot reload: %v)", set.Version, len(set.Names()), hotReload)
+	return service, nil
+}
+
+// Current mengembalikan template yang aktif. Jika hot reload aktif dan file berubah,
+// template dimuat ulang; template lama tetap dipakai jika versi baru gagal di-parse atau dirender.
+func (s *PromptService) Current() *PromptSet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.hotReload {
+		return s.current
+	}
+
+	stamp := s.fileStamp()
+	if stamp == s.stamp {
+		return s.current
+	}
+	// Remember the stamp even on failure so a broken file is reported once, not per request
+	s.stamp = stamp
+
+	set, err := s.load()
+	if err != nil {
+		log.Printf("⚠️  Prompt reload failed, keeping %s: %v", s.current.Version, err)
+		return s.current
+	}
+	log.Printf("🔄 Prompts reloaded: %s -> %s", s.current.Version, set.Version)
+	s.current = set
+	return s.current
+}
+
+// load memuat template dari dir dan memastikan semua fixture bisa dirender
+func (s *PromptService) load() (*PromptSet, error) {
+	set, err := LoadPromptSet(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := set.Check(filepath.Join(s.dir, PromptFixturesDir)); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+func (s *PromptService) fileStamp() string {
+	paths, _ := filepath.Glob(filepath.Join(s.dir, "*.tmpl"))
+	paths = append(paths, filepath.Join(s.dir, promptVersionFile))
+	sort.Strings(paths)
+
+	var stamp strings.Builder
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&stamp, "%s:%d:%d;", filepath.Base(path), info.Size(), info.ModTime().UnixNano())
+	}
+	return stamp.String()
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testPromptsDir = "../prompts"
+
+// TestPromptFixturesRender merender setiap template terhadap setiap fixture di prompts/fixtures,
+// sama seperti cmd/promptlint, agar go test menangkap template atau fixture yang rusak
+func TestPromptFixturesRender(t *testing.T) {
+	prompts, err := LoadPromptSet(testPromptsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixturesDir := filepath.Join(testPromptsDir, PromptFixturesDir)
+	fixtures, err := filepath.Glob(filepath.Join(fixturesDir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatalf("no fixtures in %s", fixturesDir)
+	}
+
+	if err := prompts.Check(fixturesDir); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadPromptSetRequiresTemplates(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, PromptSystem), []byte("halo {{.UserName}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPromptSet(dir); err == nil {
+		t.Fatal("LoadPromptSet accepted a directory without the required templates")
+	}
+}
+
+func TestPromptSetCheckRejectsUnknownField(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range requiredPrompts {
+		content := ""
+		if name == PromptSystem {
+			content = "halo {{.UserName}} {{.Unknown}}"
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Unknown struct fields only fail at render time, which is what Check is for
+	prompts, err := LoadPromptSet(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prompts.Check(filepath.Join(testPromptsDir, PromptFixturesDir)); err == nil {
+		t.Fatal("Check accepted a template that uses an unknown field")
+	}
+}
+
+func TestCheckPromptOutput(t *testing.T) {
+	tests := []struct {
+		output string
+		ok     bool
+	}{
+		{"Halo Sobat Lantas", true},
+		{`Balas dengan JSON {"choice_id": "..."}`, true},
+		{"User menulis {{ .Message }}", true},
+		{"Nama: <no value>", false},
+		{"Denda: Rp %!d(string=satu)", false},
+	}
+	for _, tt := range tests {
+		if err := CheckPromptOutput(PromptSystem, tt.output); (err == nil) != tt.ok {
+			t.Errorf("CheckPromptOutput(%q) = %v, want ok %v", tt.output, err, tt.ok)
+		}
+	}
+}
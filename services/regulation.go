@@ -0,0 +1,229 @@
+package services
+
+import (
+	"fmt"
+	"police-assistant-backend/models"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const regulationLaw = "UU 22/2009"
+
+// Jumlah pasal maksimal yang dikembalikan SearchRegulations ke chat
+const RegulationChatLimit = 3
+
+// regulationEntry adalah satu pasal beserta kata kunci yang dicocokkan dengan pertanyaan
+// user atau uraian pelanggaran. Kata kunci ditulis huruf kecil dan dicocokkan per kata utuh;
+// frasa yang lebih panjang bernilai lebih tinggi.
+type regulationEntry struct {
+	code        string
+	title       string
+	description string
+	detention   string
+	maxFine     int
+	keywords    []string
+}
+
+// regulationCatalog berisi ketentuan pidana pelanggaran lalu lintas UU 22/2009 (Bab XX)
+// yang paling sering ditindak, terurut per pasal
+var regulationCatalog = []regulationEntry{
+	{"278", "Mobil tanpa perlengkapan keselamatan",
+		"Mengemudikan kendaraan bermotor beroda empat atau lebih tanpa ban cadangan, segitiga pengaman, dongkrak, pembuka roda, dan peralatan pertolongan pertama pada kecelakaan.",
+		"1 bulan", 250000, []string{"ban cadangan", "segitiga pengaman", "segitiga", "dongkrak", "p3k", "kotak p3k", "perlengkapan mobil"}},
+	{"280", "Tidak memasang pelat nomor (TNKB)",
+		"Mengemudikan kendaraan bermotor yang tidak dipasangi Tanda Nomor Kendaraan Bermotor yang ditetapkan Kepolisian.",
+		"2 bulan", 500000, []string{"tnkb", "plat nomor", "pelat nomor", "plat", "pelat", "nomor polisi", "tanpa plat", "plat palsu", "plat copotan"}},
+	{"281", "Tidak memiliki SIM",
+		"Mengemudikan kendaraan bermotor di jalan tanpa memiliki Surat Izin Mengemudi, termasuk SIM yang sudah tidak berlaku.",
+		"4 bulan", 1000000, []string{"sim", "punya sim", "tanpa sim", "belum punya sim", "memiliki sim", "sim mati", "sim habis", "sim kedaluwarsa", "sim kadaluarsa", "di bawah umur"}},
+	{"282", "Tidak mematuhi perintah petugas",
+		"Tidak mematuhi perintah yang diberikan petugas Kepolisian saat mengatur lalu lintas.",
+		"1 bulan", 250000, []string{"perintah petugas", "perintah polisi", "aba aba", "kabur", "tidak berhenti", "petugas"}},
+	{"283", "Mengemudi tidak wajar atau menggunakan HP",
+		"Mengemudikan kendaraan bermotor secara tidak wajar, melakukan kegiatan lain (misal menggunakan telepon), atau dipengaruhi keadaan yang mengganggu konsentrasi.",
+		"3 bulan", 750000, []string{"hp", "handphone", "ponsel", "telepon", "telpon", "menelepon", "nelpon", "main hp", "chat", "sms", "merokok", "rokok", "konsentrasi", "tidak wajar", "mengantuk"}},
+	{"284", "Tidak mengutamakan pejalan kaki atau pesepeda",
+		"Mengemudikan kendaraan bermotor tanpa mengutamakan keselamatan pejalan kaki atau pesepeda.",
+		"2 bulan", 500000, []string{"pejalan kaki", "penyeberang", "zebra cross", "penyeberangan", "pesepeda", "trotoar"}},
+	{"285(1)", "Sepeda motor tidak memenuhi persyaratan teknis",
+		"Mengemudikan sepeda motor yang tidak memenuhi persyaratan teknis dan laik jalan: kaca spion, klakson, lampu utama, lampu rem, lampu penunjuk arah, alat pemantul cahaya, pengukur kecepatan, knalpot, dan kedalaman alur ban.",
+		"1 bulan", 250000, []string{"motor", "sepeda motor", "spion", "kaca spion", "klakson", "knalpot", "knalpot racing", "knalpot brong", "lampu rem", "speedometer", "ban gundul", "tidak standar", "modifikasi"}},
+	{"285(2)", "Mobil tidak memenuhi persyaratan teknis",
+		"Mengemudikan kendaraan bermotor beroda empat atau lebih yang tidak memenuhi persyaratan teknis: kaca spion, klakson, lampu, penghapus kaca, kaca depan, bumper, dan sabuk keselamatan.",
+		"2 bulan", 500000, []string{"mobil", "spion", "kaca spion", "klakson", "wiper", "penghapus kaca", "kaca depan", "bumper", "tidak standar", "modifikasi"}},
+	{"286", "Mobil tidak laik jalan",
+		"Mengemudikan kendaraan bermotor beroda empat atau lebih yang tidak memenuhi persyaratan laik jalan.",
+		"2 bulan", 500000, []string{"laik jalan", "tidak laik", "rem blong", "mobil"}},
+	{"287(1)", "Melanggar rambu atau marka jalan",
+		"Melanggar aturan perintah atau larangan yang dinyatakan dengan rambu lalu lintas atau marka jalan, termasuk ganjil genap, jalur khusus bus, dan larangan parkir.",
+		"2 bulan", 500000, []string{"rambu", "marka", "marka jalan", "garis", "verboden", "dilarang masuk", "melawan arus", "lawan arah", "busway", "jalur busway", "ganjil genap", "dilarang parkir", "parkir di tempat terlarang", "putar balik", "u turn", "bahu jalan"}},
+	{"287(2)", "Menerobos lampu merah",
+		"Melanggar aturan perintah atau larangan yang dinyatakan dengan Alat Pemberi Isyarat Lalu Lintas (lampu lalu lintas).",
+		"2 bulan", 500000, []string{"lampu merah", "menerobos lampu merah", "melanggar lampu merah", "terobos", "menerobos", "nerobos", "traffic light", "lampu lalu lintas", "apill", "stop line"}},
+	{"287(3)", "Melanggar gerakan lalu lintas, berhenti, atau parkir",
+		"Melanggar ketentuan gerakan lalu lintas atau tata cara berhenti dan parkir.",
+		"1 bulan", 250000, []string{"parkir", "parkir sembarangan", "parkir liar", "berhenti", "berhenti sembarangan", "ngetem"}},
+	{"287(4)", "Sirene atau lampu isyarat tanpa hak",
+		"Menggunakan lampu isyarat (strobo/rotator) dan sirene tanpa hak, atau tidak memberi prioritas kepada kendaraan yang berhak didahulukan seperti ambulans dan pemadam kebakaran.",
+		"1 bulan", 250000, []string{"sirene", "strobo", "lampu strobo", "rotator", "lampu isyarat", "ambulans", "pemadam kebakaran", "pengawalan"}},
+	{"287(5)", "Melanggar batas kecepatan",
+		"Melanggar aturan batas kecepatan paling tinggi atau paling rendah.",
+		"2 bulan", 500000, []string{"kecepatan", "batas kecepatan", "melebihi batas", "ngebut", "kebut", "kebut kebutan", "terlalu cepat", "terlalu pelan", "km jam"}},
+	{"287(6)", "Melanggar tata cara penggandengan dan penempelan",
+		"Melanggar ketentuan tata cara penggandengan dan penempelan dengan kendaraan lain.",
+		"1 bulan", 250000, []string{"gandeng", "penggandengan", "menggandeng", "derek", "menempel", "nempel"}},
+	{"288(1)", "Tidak membawa atau STNK tidak sah",
+		"Mengemudikan kendaraan bermotor yang tidak dilengkapi Surat Tanda Nomor Kendaraan Bermotor atau STNK yang ditetapkan Kepolisian (termasuk STNK yang tidak disahkan).",
+		"2 bulan", 500000, []string{"stnk", "bawa stnk", "membawa stnk", "stnk mati", "stnk hilang", "pajak mati", "surat kendaraan"}},
+	{"288(2)", "Tidak dapat menunjukkan SIM",
+		"Mengemudikan kendaraan bermotor dan tidak dapat menunjukkan Surat Izin Mengemudi yang sah.",
+		"1 bulan", 250000, []string{"sim", "bawa sim", "membawa sim", "menunjukkan sim", "sim ketinggalan", "lupa sim", "lupa bawa sim"}},
+	{"288(3)", "Tidak memiliki bukti lulus uji berkala (KIR)",
+		"Mengemudikan mobil penumpang umum, bus, mobil barang, kereta gandengan, atau kereta tempelan tanpa surat keterangan uji berkala dan tanda lulus uji berkala.",
+		"2 bulan", 500000, []string{"kir", "uji kir", "uji berkala", "buku kir", "angkutan umum", "truk"}},
+	{"289", "Tidak memakai sabuk keselamatan",
+		"Pengemudi atau penumpang di samping pengemudi kendaraan bermotor beroda empat atau lebih tidak mengenakan sabuk keselamatan.",
+		"1 bulan", 250000, []string{"sabuk", "sabuk pengaman", "sabuk keselamatan", "seat belt", "seatbelt"}},
+	{"291(1)", "Tidak memakai helm SNI",
+		"Mengemudikan sepeda motor tanpa mengenakan helm Standar Nasional Indonesia.",
+		"1 bulan", 250000, []string{"helm", "helm sni", "tanpa helm", "pakai helm", "memakai helm", "menggunakan helm", "mengenakan helm"}},
+	{"291(2)", "Penumpang sepeda motor tidak memakai helm",
+		"Mengemudikan sepeda motor yang membiarkan penumpangnya tidak mengenakan helm.",
+		"1 bulan", 250000, []string{"helm", "penumpang", "boncengan", "dibonceng", "pembonceng", "penumpang helm"}},
+	{"292", "Sepeda motor berpenumpang lebih dari satu",
+		"Mengemudikan sepeda motor tanpa kereta samping yang mengangkut penumpang lebih dari satu orang.",
+		"1 bulan", 250000, []string{"bonceng tiga", "boncengan tiga", "cenglu", "bonceng bertiga", "lebih dari satu penumpang", "penumpang lebih dari satu", "bertiga"}},
+	{"293(1)", "Tidak menyalakan lampu utama pada malam hari",
+		"Mengemudikan kendaraan bermotor tanpa menyalakan lampu utama pada malam hari dan kondisi tertentu.",
+		"1 bulan", 250000, []string{"lampu utama", "lampu", "malam", "malam hari", "nyalakan lampu", "menyalakan lampu"}},
+	{"293(2)", "Sepeda motor tidak menyalakan lampu utama siang hari",
+		"Mengemudikan sepeda motor tanpa menyalakan lampu utama pada siang hari.",
+		"15 hari", 100000, []string{"lampu utama", "lampu", "siang", "siang hari", "nyalakan lampu", "menyalakan lampu", "lampu motor"}},
+	{"294", "Berbelok tanpa isyarat",
+		"Berbelok atau berbalik arah tanpa memberikan isyarat dengan lampu penunjuk arah atau isyarat tangan.",
+		"1 bulan", 250000, []string{"sein", "lampu sein", "isyarat", "belok", "berbelok", "balik arah", "lampu penunjuk arah"}},
+	{"295", "Pindah lajur tanpa isyarat",
+		"Berpindah lajur atau bergerak ke samping tanpa memberikan isyarat.",
+		"1 bulan", 250000, []string{"pindah lajur", "pindah jalur", "ganti lajur", "ganti jalur", "zig zag", "selap selip", "isyarat", "sein"}},
+	{"297", "Balapan di jalan",
+		"Mengemudikan kendaraan bermotor berbalapan di jalan.",
+		"1 tahun", 3000000, []string{"balap", "balapan", "balap liar", "trek trekan", "adu kecepatan"}},
+	{"307", "Melanggar ketentuan muatan dan dimensi (ODOL)",
+		"Mengemudikan kendaraan angkutan barang yang tidak mematuhi ketentuan tata cara pemuatan, daya angkut, dimensi, dan muatan sumbu.",
+		"2 bulan", 500000, []string{"muatan", "kelebihan muatan", "overload", "over dimensi", "odol", "daya angkut", "truk"}},
+}
+
+// Pertanyaan yang menyebut nomor pasal: "pasal 287", "pasal 287 ayat 2", "287(2)"
+var regulationCodePattern = regexp.MustCompile(`\b(?:pasal\s*)?(2[7-9][0-9]|3[01][0-9])\b\s*(?:ayat\s*\(?([0-9])\)?|\(([0-9])\))?`)
+
+var regulationWordPattern = regexp.MustCompile(`[a-z0-9]+`)
+
+// Regulations mengembalikan seluruh katalog pasal, terurut per pasal
+func Regulations() []models.Regulation {
+	regulations := make([]models.Regulation, len(regulationCatalog))
+	for i, entry := range regulationCatalog {
+		regulations[i] = entry.regulation()
+	}
+	return regulations
+}
+
+// FindRegulation mencari pasal berdasarkan kode ("287(2)", "289")
+func FindRegulation(code string) (models.Regulation, bool) {
+	code = strings.ReplaceAll(code, " ", "")
+	for _, entry := range regulationCatalog {
+		if entry.code == code {
+			return entry.regulation(), true
+		}
+	}
+	return models.Regulation{}, false
+}
+
+// SearchRegulations mencari pasal yang paling relevan dengan pertanyaan bebas
+// ("berapa denda tidak pakai helm") atau nomor pasal ("pasal 287 ayat 2"). Query
+// kosong mengembalikan seluruh katalog; limit <= 0 berarti tanpa batas.
+func SearchRegulations(query string, limit int) []models.Regulation {
+	if strings.TrimSpace(query) == "" {
+		return Regulations()
+	}
+
+	normalized := " " + strings.Join(regulationWordPattern.FindAllString(strings.ToLower(query), -1), " ") + " "
+	scores := make([]int, len(regulationCatalog))
+
+	for _, match := range regulationCodePattern.FindAllStringSubmatch(strings.ToLower(query), -1) {
+		article, paragraph := match[1], match[2]+match[3]
+		for i, entry := range regulationCatalog {
+			switch {
+			case paragraph != "" && entry.code == fmt.Sprintf("%s(%s)", article, paragraph):
+				scores[i] += 20
+			case entry.code == article || strings.HasPrefix(entry.code, article+"("):
+				scores[i] += 10
+			}
+		}
+	}
+
+	for i, entry := range regulationCatalog {
+		for _, keyword := range entry.keywords {
+			if strings.Contains(normalized, " "+keyword+" ") {
+				scores[i] += len(strings.Fields(keyword))
+			}
+		}
+	}
+
+	best := 0
+	for _, score := range scores {
+		best = max(best, score)
+	}
+	// Weak matches (a lone "lampu" next to "lampu merah") are dropped when a clearly better one exists
+	var order []int
+	for i, score := range scores {
+		if score > 0 && score*2 >= best {
+			order = append(order, i)
+		}
+	}
+	// Equal scores keep catalog order, so ayat (1) comes before ayat (2)
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+	if limit > 0 && len(order) > limit {
+		order = order[:limit]
+	}
+
+	regulations := make([]models.Regulation, 0, len(order))
+	for _, i := range order {
+		regulations = append(regulations, regulationCatalog[i].regulation())
+	}
+	return regulations
+}
+
+// attachRegulations melengkapi setiap pelanggaran dengan detail pasal. Pelanggaran tanpa
+// kode pasal dari provider dicocokkan dari uraiannya.
+func attachRegulations(info *models.ETilangInfo) {
+	for i := range info.Violations {
+		violation := &info.Violations[i]
+		if violation.ArticleCode == "" {
+			if matches := SearchRegulations(violation.Violation, 1); len(matches) > 0 {
+				violation.ArticleCode = matches[0].Code
+			}
+		}
+		if regulation, ok := FindRegulation(violation.ArticleCode); ok {
+			violation.Article = &regulation
+		}
+	}
+}
+
+func (e regulationEntry) regulation() models.Regulation {
+	article := "Pasal " + e.code
+	if open := strings.Index(e.code, "("); open > 0 {
+		article = fmt.Sprintf("Pasal %s ayat %s", e.code[:open], e.code[open:])
+	}
+	return models.Regulation{
+		Code:         e.code,
+		Law:          regulationLaw,
+		Article:      article,
+		Title:        e.title,
+		Description:  e.description,
+		MaxDetention: e.detention,
+		MaxFine:      e.maxFine,
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"police-assistant-backend/models"
+	"reflect"
+	"testing"
+)
+
+func TestFindRegulation(t *testing.T) {
+	tests := []struct {
+		code    string
+		article string // "" = not found
+		maxFine int
+	}{
+		{"289", "Pasal 289", 250000},
+		{"287(2)", "Pasal 287 ayat (2)", 500000},
+		{"287 (2)", "Pasal 287 ayat (2)", 500000},
+		{"297", "Pasal 297", 3000000},
+		{"287", "", 0}, // Only the ayat exist in the catalog
+		{"999", "", 0},
+		{"", "", 0},
+	}
+	for _, tt := range tests {
+		regulation, ok := FindRegulation(tt.code)
+		if got := regulation.Article; ok != (tt.article != "") || got != tt.article || regulation.MaxFine != tt.maxFine {
+			t.Errorf("FindRegulation(%q) = %q (fine %d, found %v), want %q (fine %d)", tt.code, got, regulation.MaxFine, ok, tt.article, tt.maxFine)
+		}
+		if ok && regulation.Law != regulationLaw {
+			t.Errorf("FindRegulation(%q).Law = %q", tt.code, regulation.Law)
+		}
+	}
+}
+
+func TestSearchRegulationsRanking(t *testing.T) {
+	tests := []struct {
+		query string
+		limit int
+		want  []string
+	}{
+		// Keywords, whole words only
+		{"berapa denda tidak pakai helm", 0, []string{"291(1)"}},
+		{"main hp sambil nyetir", 0, []string{"283"}},
+		{"tidak punya sim", 0, []string{"281"}},
+		{"similar", 0, nil},
+		{"apa itu fotosintesis", 0, nil},
+		// Longer phrases outrank shared single words, weak matches are dropped
+		{"nerobos lampu merah", 0, []string{"287(2)"}},
+		{"knalpot brong motor", 0, []string{"285(1)"}},
+		{"sim", 0, []string{"281", "288(2)"}},
+		// Article numbers: the named ayat first, then the rest in catalog order
+		{"pasal 287 ayat 2", 3, []string{"287(2)", "287(1)", "287(3)"}},
+		{"287(5)", 2, []string{"287(5)", "287(1)"}},
+		{"pasal 287", RegulationChatLimit, []string{"287(1)", "287(2)", "287(3)"}},
+		{"pasal 999", 0, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, regulation := range SearchRegulations(tt.query, tt.limit) {
+			got = append(got, regulation.Code)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SearchRegulations(%q, %d) = %q, want %q", tt.query, tt.limit, got, tt.want)
+		}
+	}
+
+	if all := SearchRegulations("  ", 1); len(all) != len(regulationCatalog) {
+		t.Errorf("empty query returned %d regulation(s), want the whole catalog (%d)", len(all), len(regulationCatalog))
+	}
+}
+
+func TestAttachRegulations(t *testing.T) {
+	info := &models.ETilangInfo{Violations: []models.ETilangViolation{
+		{Violation: "Menerobos lampu merah"},
+		{Violation: "Tidak menggunakan helm", ArticleCode: "291(1)"},
+		{Violation: "Pelanggaran lain"},
+	}}
+	attachRegulations(info)
+
+	want := []string{"287(2)", "291(1)", ""}
+	for i, violation := range info.Violations {
+		code := ""
+		if violation.Article != nil {
+			code = violation.Article.Code
+		}
+		if code != want[i] {
+			t.Errorf("violation %d (%q) article = %q, want %q", i, violation.Violation, code, want[i])
+		}
+	}
+}
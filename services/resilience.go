@@ -0,0 +1,287 @@
+package services
+
+import (
+	ctx "context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"police-assistant-backend/config"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// Jeda backoff: retryBaseDelay, 2x, 4x, ... dibatasi retryMaxDelay, dengan jitter
+const (
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 8 * time.Second
+)
+
+// Balasan saat dependency sedang gangguan (circuit breaker terbuka atau semua retry gagal)
+const (
+	LLMFallbackReply  = "Mohon maaf Sobat Lantas, asisten sedang mengalami gangguan sehingga belum bisa menjawab. Silakan coba lagi beberapa saat lagi ya 🙏 Untuk keadaan darurat, hubungi Call Center Polri 110."
+	MapsFallbackReply = "Mohon maaf Sobat Lantas, layanan peta dan lalu lintas sedang mengalami gangguan. Silakan coba lagi beberapa saat lagi ya 🙏"
+)
+
+// Status circuit breaker untuk health check
+const (
+	upstreamStateOK    = "closed"
+	upstreamStateOpen  = "open"
+	upstreamStateProbe = "half_open"
+)
+
+var (
+	upstreamsMu sync.Mutex
+	upstreams   = make(map[string]*Upstream)
+)
+
+// ErrUpstreamUnavailable menandakan dependency (OpenAI, ORS) sedang tidak bisa dipakai
+var ErrUpstreamUnavailable = errors.New("upstream unavailable")
+
+// StatusError adalah response HTTP gagal dari dependency
+type StatusError struct {
+	StatusCode int
+	RetryAfter time.Duration // Dari header Retry-After, 0 jika tidak ada
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("upstream returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// noRetryError menandai error yang tidak boleh diulang, misal stream yang sudah terkirim sebagian ke client
+type noRetryError struct {
+	err error
+}
+
+func (e *noRetryError) Error() string { return e.err.Error() }
+func (e *noRetryError) Unwrap() error { return e.err }
+
+func noRetry(err error) error {
+	return &noRetryError{err: err}
+}
+
+// RetryPolicy mengatur timeout per percobaan dan jumlah retry untuk satu dependency
+type RetryPolicy struct {
+	Timeout    time.Duration
+	MaxRetries int
+}
+
+// Upstream membungkus panggilan ke satu dependency dengan timeout, retry, dan circuit breaker
+type Upstream struct {
+	name   string
+	policy RetryPolicy
+
+	threshold int           // Kegagalan berturut-turut sebelum circuit terbuka
+	cooldown  time.Duration // Lama circuit terbuka sebelum panggilan percobaan
+
+	mu          sync.Mutex
+	failures    int       // Panggilan gagal berturut-turut (setelah semua retry)
+	openUntil   time.Time // Circuit terbuka sampai waktu ini
+	probing     bool      // Satu panggilan percobaan sedang berjalan setelah cooldown
+	lastFailure error
+}
+
+func NewUpstream(name string, policy RetryPolicy) *Upstream {
+	upstream := &Upstream{
+		name:      name,
+		policy:    policy,
+		threshold: config.AppConfig.CircuitBreakerFailures,
+		cooldown:  config.AppConfig.CircuitBreakerCooldown,
+	}
+
+	upstreamsMu.Lock()
+	upstreams[name] = upstream
+	upstreamsMu.Unlock()
+
+	return upstream
+}
+
+// UpstreamStates mengembalikan status circuit breaker setiap dependency untuk /health
+func UpstreamStates() map[string]string {
+	upstreamsMu.Lock()
+	defer upstreamsMu.Unlock()
+
+	states := make(map[string]string, len(upstreams))
+	for name, upstream := range upstreams {
+		states[name] = upstream.State()
+	}
+	return states
+}
+
+// Do menjalankan fn dengan timeout per percobaan. Error 429, 5xx, timeout, dan gangguan
+// jaringan diulang dengan exponential backoff + jitter. Jika circuit terbuka atau semua
+// percobaan gagal, error membungkus ErrUpstreamUnavailable.
+func (u *Upstream) Do(requestCtx ctx.Context, fn func(attemptCtx ctx.Context) error) error {
+	if err := u.allow(); err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := ctx.WithTimeout(requestCtx, u.policy.Timeout)
+		err := fn(attemptCtx)
+		cancel()
+
+		if err == nil {
+			u.recordSuccess()
+			return nil
+		}
+		// The caller gave up (client disconnected or server shutting down); that says nothing about the upstream
+		if requestCtx.Err() != nil {
+			u.releaseProbe()
+			return err
+		}
+		if !isRetryable(err) {
+			u.releaseProbe()
+			return err
+		}
+		if attempt >= u.policy.MaxRetries {
+			u.recordFailure(err)
+			return fmt.Errorf("%s: %w after %d attempt(s): %v", u.name, ErrUpstreamUnavailable, attempt+1, err)
+		}
+
+		delay := backoffDelay(attempt, retryAfter(err))
+		log.Printf("🔁 %s call failed (attempt %d/%d), retrying in %s: %v", u.name, attempt+1, u.policy.MaxRetries+1, delay.Round(time.Millisecond), err)
+		select {
+		case <-time.After(delay):
+		case <-requestCtx.Done():
+			u.releaseProbe()
+			return requestCtx.Err()
+		}
+	}
+}
+
+// State mengembalikan status circuit breaker: closed, open, atau half_open
+func (u *Upstream) State() string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	switch {
+	case u.probing:
+		return upstreamStateProbe
+	case time.Now().Before(u.openUntil):
+		return upstreamStateOpen
+	case !u.openUntil.IsZero():
+		return upstreamStateProbe
+	default:
+		return upstreamStateOK
+	}
+}
+
+// allow menolak panggilan selama circuit terbuka; setelah cooldown satu panggilan boleh lewat sebagai percobaan
+func (u *Upstream) allow() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.openUntil.IsZero() {
+		return nil
+	}
+	if time.Now().Before(u.openUntil) || u.probing {
+		return fmt.Errorf("%s: %w (circuit open: %v)", u.name, ErrUpstreamUnavailable, u.lastFailure)
+	}
+
+	u.probing = true
+	log.Printf("🟡 %s circuit half-open, sending probe request", u.name)
+	return nil
+}
+
+func (u *Upstream) recordSuccess() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if !u.openUntil.IsZero() {
+		log.Printf("🟢 %s circuit closed, upstream recovered", u.name)
+	}
+	u.failures = 0
+	u.openUntil = time.Time{}
+	u.probing = false
+	u.lastFailure = nil
+}
+
+func (u *Upstream) recordFailure(err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.failures++
+	u.lastFailure = err
+	if u.probing || u.failures >= u.threshold {
+		u.openUntil = time.Now().Add(u.cooldown)
+		log.Printf("🔴 %s circuit open for %s after %d failure(s): %v", u.name, u.cooldown, u.failures, err)
+	}
+	u.probing = false
+}
+
+// releaseProbe membuka kembali slot percobaan jika panggilan selesai tanpa hasil yang jelas
+func (u *Upstream) releaseProbe() {
+	u.mu.Lock()
+	u.probing = false
+	u.mu.Unlock()
+}
+
+// isRetryable: 429, 5xx, timeout percobaan, dan gangguan jaringan
+func isRetryable(err error) bool {
+	var stop *noRetryError
+	if errors.As(err, &stop) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return retryableStatus(statusErr.StatusCode)
+	}
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		return retryableStatus(apiErr.StatusCode)
+	}
+	if errors.Is(err, ctx.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	var urlErr *url.Error
+	return errors.As(err, &netErr) || errors.As(err, &urlErr)
+}
+
+func retryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// retryAfter membaca jeda yang diminta upstream (header Retry-After), 0 jika tidak ada
+func retryAfter(err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.RetryAfter
+	}
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) && apiErr.Response != nil {
+		return parseRetryAfter(apiErr.Response.Header.Get("Retry-After"))
+	}
+	return 0
+}
+
+// parseRetryAfter mendukung format detik ("3"); format tanggal HTTP diabaikan
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// backoffDelay menghitung jeda sebelum retry ke-(attempt+1) dengan equal jitter
+func backoffDelay(attempt int, requested time.Duration) time.Duration {
+	delay := retryBaseDelay << attempt
+	if delay > retryMaxDelay || delay <= 0 {
+		delay = retryMaxDelay
+	}
+	delay = delay/2 + rand.N(delay/2+1)
+
+	if requested > delay {
+		delay = min(requested, retryMaxDelay)
+	}
+	return delay
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	ctx "context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newTestUpstream(maxRetries int) *Upstream {
+	return &Upstream{
+		name:      "test",
+		policy:    RetryPolicy{Timeout: time.Second, MaxRetries: maxRetries},
+		threshold: 2,
+		cooldown:  time.Hour,
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	for attempt := 0; attempt < 8; attempt++ {
+		base := min(retryBaseDelay<<attempt, retryMaxDelay)
+		for i := 0; i < 50; i++ {
+			if delay := backoffDelay(attempt, 0); delay < base/2 || delay > base {
+				t.Fatalf("backoffDelay(%d) = %s, want within [%s, %s]", attempt, delay, base/2, base)
+			}
+		}
+	}
+
+	// Retry-After wins over a shorter backoff but is still capped
+	if delay := backoffDelay(0, 3*time.Second); delay != 3*time.Second {
+		t.Errorf("Retry-After 3s: delay = %s", delay)
+	}
+	if delay := backoffDelay(0, time.Minute); delay != retryMaxDelay {
+		t.Errorf("Retry-After 1m: delay = %s, want %s", delay, retryMaxDelay)
+	}
+	if delay := backoffDelay(0, time.Millisecond); delay > retryBaseDelay {
+		t.Errorf("short Retry-After: delay = %s, want the backoff", delay)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"3", 3 * time.Second},
+		{"0", 0},
+		{"-1", 0},
+		{"", 0},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.header); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.header, got, tt.want)
+		}
+	}
+
+	err := &StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 2 * time.Second}
+	if got := retryAfter(err); got != 2*time.Second {
+		t.Errorf("retryAfter(StatusError) = %s", got)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&StatusError{StatusCode: http.StatusTooManyRequests}, true},
+		{&StatusError{StatusCode: http.StatusBadGateway}, true},
+		{&StatusError{StatusCode: http.StatusNotFound}, false},
+		{&StatusError{StatusCode: http.StatusBadRequest}, false},
+		{ctx.DeadlineExceeded, true},
+		{noRetry(&StatusError{StatusCode: http.StatusBadGateway}), false},
+		{errors.New("malformed response"), false},
+	}
+	for _, tt := range tests {
+		if got := isRetryable(tt.err); got != tt.want {
+			t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// TestCircuitBreakerTransitions: closed -> open setelah threshold, half_open setelah cooldown,
+// probe gagal membuka lagi, probe sukses menutup
+func TestCircuitBreakerTransitions(t *testing.T) {
+	upstream := newTestUpstream(0)
+	background := ctx.Background()
+	calls := 0
+	fail := func(ctx.Context) error {
+		calls++
+		return &StatusError{StatusCode: http.StatusServiceUnavailable}
+	}
+	succeed := func(ctx.Context) error {
+		calls++
+		return nil
+	}
+
+	for i := 0; i < upstream.threshold; i++ {
+		if state := upstream.State(); state != upstreamStateOK {
+			t.Fatalf("failure %d: state = %s, want closed", i, state)
+		}
+		if err := upstream.Do(background, fail); !errors.Is(err, ErrUpstreamUnavailable) {
+			t.Fatalf("failure %d: err = %v", i, err)
+		}
+	}
+	if state := upstream.State(); state != upstreamStateOpen {
+		t.Fatalf("after %d failures: state = %s, want open", upstream.threshold, state)
+	}
+
+	calls = 0
+	if err := upstream.Do(background, succeed); !errors.Is(err, ErrUpstreamUnavailable) || calls != 0 {
+		t.Fatalf("open circuit: err = %v, calls = %d, want rejected without calling", err, calls)
+	}
+
+	// Cooldown over: one probe may pass, a failed probe opens the circuit again
+	upstream.openUntil = time.Now().Add(-time.Second)
+	if state := upstream.State(); state != upstreamStateProbe {
+		t.Fatalf("after cooldown: state = %s, want half_open", state)
+	}
+	if err := upstream.Do(background, fail); !errors.Is(err, ErrUpstreamUnavailable) || calls != 1 {
+		t.Fatalf("failed probe: err = %v, calls = %d", err, calls)
+	}
+	if state := upstream.State(); state != upstreamStateOpen {
+		t.Fatalf("after failed probe: state = %s, want open", state)
+	}
+
+	// Only one probe at a time while it is in flight
+	upstream.openUntil = time.Now().Add(-time.Second)
+	if err := upstream.allow(); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if err := upstream.allow(); !errors.Is(err, ErrUpstreamUnavailable) {
+		t.Fatalf("second probe: err = %v, want rejected", err)
+	}
+	upstream.releaseProbe()
+
+	if err := upstream.Do(background, succeed); err != nil {
+		t.Fatalf("successful probe: %v", err)
+	}
+	if state := upstream.State(); state != upstreamStateOK || upstream.failures != 0 {
+		t.Fatalf("after successful probe: state = %s, failures = %d, want closed", state, upstream.failures)
+	}
+}
+
+func TestUpstreamDoRetries(t *testing.T) {
+	upstream := newTestUpstream(2)
+	calls := 0
+	err := upstream.Do(ctx.Background(), func(ctx.Context) error {
+		calls++
+		if calls < 2 {
+			return &StatusError{StatusCode: http.StatusBadGateway}
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Fatalf("err = %v, calls = %d, want success on the second attempt", err, calls)
+	}
+
+	calls = 0
+	err = upstream.Do(ctx.Background(), func(ctx.Context) error {
+		calls++
+		return &StatusError{StatusCode: http.StatusNotFound}
+	})
+	if errors.Is(err, ErrUpstreamUnavailable) || calls != 1 || upstream.failures != 0 {
+		t.Fatalf("404: err = %v, calls = %d, failures = %d, want one call without tripping the breaker", err, calls, upstream.failures)
+	}
+}
+
+// TestUpstreamDoCancelled: client yang putus menghentikan retry tanpa dihitung sebagai kegagalan upstream
+func TestUpstreamDoCancelled(t *testing.T) {
+	upstream := newTestUpstream(5)
+	requestCtx, cancel := ctx.WithCancel(ctx.Background())
+	calls := 0
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	err := upstream.Do(requestCtx, func(ctx.Context) error {
+		calls++
+		return &StatusError{StatusCode: http.StatusServiceUnavailable}
+	})
+	if !errors.Is(err, ctx.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Do returned after %s, want it to stop waiting for the backoff", elapsed)
+	}
+	if calls != 1 || upstream.failures != 0 || upstream.State() != upstreamStateOK {
+		t.Fatalf("calls = %d, failures = %d, state = %s; want one call and a closed circuit", calls, upstream.failures, upstream.State())
+	}
+
+	// A call already in flight sees the cancellation through its attempt context
+	requestCtx, cancel = ctx.WithCancel(ctx.Background())
+	cancel()
+	err = upstream.Do(requestCtx, func(attemptCtx ctx.Context) error {
+		<-attemptCtx.Done()
+		return attemptCtx.Err()
+	})
+	if !errors.Is(err, ctx.Canceled) || upstream.failures != 0 {
+		t.Fatalf("in-flight: err = %v, failures = %d", err, upstream.failures)
+	}
+}
@@ -1,190 +1,443 @@
 package services
 
 import (
+	"errors"
+	"log"
+	"police-assistant-backend/config"
 	"police-assistant-backend/models"
 	"sync"
 	"time"
-
-	"github.com/google/uuid"
 )
 
-// SessionStore menyimpan chat history per session
-type SessionStore struct {
-	sessions map[string]*Session
-	mu       sync.RWMutex
+// SessionStore menyimpan chat history, data, dan state flow per session.
+// Backend dipilih lewat config (SESSION_BACKEND): memory (default) atau bolt.
+type SessionStore interface {
+	CreateSession() (string, string, error)
+	GetSession(sessionID string) (*Session, bool)
+	VerifyToken(sessionID string, token string) bool
+	DeleteSession(sessionID string)
+	GetSessionCount() int
+
+	AddMessage(sessionID string, role string, content string, meta *models.MessageMeta) error
+	GetHistory(sessionID string) []models.OpenAIMessage
+	ClearSession(sessionID string)
+	CompactHistory(sessionID string, count int, summary string)
+	GetSummary(sessionID string) string
+	PinFact(sessionID string, key string, value string)
+	GetPinnedFacts(sessionID string) map[string]string
+
+	GetData(sessionID string, key string) string
+	SetData(sessionID string, key string, value string)
+
+	StartFlow(sessionID string, flowID string, entryNode string)
+	GetActiveFlow(sessionID string) (string, string)
+	SetFlowNode(sessionID string, flowID string, nodeID string)
+	PopFlowNode(sessionID string, flowID string) (string, bool)
+	GetFlowStackDepth(sessionID string, flowID string) int
+	EndFlow(sessionID string)
+	PauseFlow(sessionID string)
+	ResumeFlow(sessionID string) (string, string)
+	GetFlowContext(sessionID string, flowID string) map[string]interface{}
+	SetFlowValue(sessionID string, flowID string, key string, value interface{})
 }
 
 // Session menyimpan history dan metadata per session
 type Session struct {
-	ID        string
-	History   []models.OpenAIMessage
-	Data      map[string]string // Generic data storage for flow states, etc.
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID         string                      `json:"id"`
+	TokenHash  string                      `json:"token_hash"` // SHA-256 dari token pemilik session; token asli tidak disimpan
+	History    []models.SessionMessage     `json:"history"`
+	Summarized int                         `json:"summarized,omitempty"`   // Jumlah pesan terlama yang sudah masuk Summary (tidak dikirim ke LLM)
+	Summary    string                      `json:"summary,omitempty"`      // Ringkasan berjalan dari pesan lama yang sudah dipadatkan
+	Pinned     map[string]string           `json:"pinned_facts,omitempty"` // Fakta penting (nama, nomor polisi, layanan) di luar window history
+	Data       map[string]string           `json:"data"`                   // Generic data storage for flow states, etc.
+	ActiveFlow string                      `json:"active_flow,omitempty"`  // flow_id yang sedang berjalan (kosong jika tidak ada)
+	PausedFlow string                      `json:"paused_flow,omitempty"`  // flow_id yang dibatalkan user dan masih bisa dilanjutkan
+	Flows      map[string]*FlowState       `json:"flows"`                  // State flow per flow_id
+	FlowPath   []models.TranscriptFlowStep `json:"flow_path,omitempty"`    // Node flow yang dikunjungi, untuk transcript
+	CreatedAt  time.Time                   `json:"created_at"`
+	UpdatedAt  time.Time                   `json:"updated_at"`
+}
+
+// FlowState menyimpan posisi dan context terstruktur satu flow dalam session
+type FlowState struct {
+	FlowID      string                 `json:"flow_id"`
+	CurrentNode string                 `json:"current_node"`
+	Stack       []string               `json:"stack,omitempty"` // Node yang sudah dilewati, untuk perintah "kembali"
+	Context     map[string]interface{} `json:"context"`         // Hasil on_select, upload, output action
+
+	// Context saat tiap node di Stack pertama kali dimasuki (sejajar dengan Stack), agar
+	// "kembali" juga membatalkan on_select, upload, dan output action setelah node tersebut
+	Snapshots    []map[string]interface{} `json:"snapshots,omitempty"`
+	EntryContext map[string]interface{}   `json:"entry_context,omitempty"` // Context saat CurrentNode dimasuki
+	StartedAt    time.Time                `json:"started_at"`
+	UpdatedAt    time.Time                `json:"updated_at"`
 }
 
+// Batas keras jumlah pesan history per session; dalam pemakaian normal history
+// sudah dipadatkan lebih dulu oleh HistoryService berdasarkan budget token
+const maxHistoryMessages = 200
+
+// Session yang tidak aktif lebih lama dari ini akan dihapus
+const sessionTTL = 24 * time.Hour
+
+// ErrSessionNotFound dikembalikan jika session tidak ada; session hanya dibuat lewat CreateSession
+var ErrSessionNotFound = errors.New("session not found")
+
 var (
-	sessionStore *SessionStore
+	sessionStore SessionStore
 	once         sync.Once
 )
 
 // GetSessionStore returns singleton instance of SessionStore
-func GetSessionStore() *SessionStore {
+func GetSessionStore() SessionStore {
 	once.Do(func() {
-		sessionStore = &SessionStore{
-			sessions: make(map[string]*Session),
+		backend := "memory"
+		if config.AppConfig != nil && config.AppConfig.SessionBackend != "" {
+			backend = config.AppConfig.SessionBackend
+		}
+
+		switch backend {
+		case "bolt":
+			store, err := NewBoltSessionStore(config.AppConfig.SessionDBPath)
+			if err != nil {
+				log.Fatalf("❌ Failed to open session database %s: %v", config.AppConfig.SessionDBPath, err)
+			}
+			sessionStore = store
+		default:
+			sessionStore = NewMemorySessionStore()
 		}
-		// Start cleanup goroutine
-		go sessionStore.cleanupExpiredSessions()
 	})
 	return sessionStore
 }
 
-// CreateSession membuat session baru
-func (s *SessionStore) CreateSession() string {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	sessionID := uuid.New().String()
-	s.sessions[sessionID] = &Session{
+func newSession(sessionID string, tokenHash string) *Session {
+	now := time.Now()
+	return &Session{
 		ID:        sessionID,
-		History:   []models.OpenAIMessage{},
+		TokenHash: tokenHash,
+		History:   []models.SessionMessage{},
+		Pinned:    make(map[string]string),
 		Data:      make(map[string]string),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		Flows:     make(map[string]*FlowState),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// clone membuat salinan dalam session agar pemanggil tidak mengubah state store
+func (s *Session) clone() *Session {
+	copied := *s
+	copied.History = append([]models.SessionMessage{}, s.History...)
+
+	copied.Pinned = make(map[string]string, len(s.Pinned))
+	for key, value := range s.Pinned {
+		copied.Pinned[key] = value
+	}
+
+	copied.FlowPath = append([]models.TranscriptFlowStep(nil), s.FlowPath...)
+
+	copied.Data = make(map[string]string, len(s.Data))
+	for key, value := range s.Data {
+		copied.Data[key] = value
 	}
 
-	return sessionID
+	copied.Flows = make(map[string]*FlowState, len(s.Flows))
+	for flowID, state := range s.Flows {
+		stateCopy := *state
+		stateCopy.Stack = append([]string(nil), state.Stack...)
+		stateCopy.Context = copyContext(state.Context)
+		stateCopy.EntryContext = copyContext(state.EntryContext)
+		stateCopy.Snapshots = make([]map[string]interface{}, len(state.Snapshots))
+		for i, snapshot := range state.Snapshots {
+			stateCopy.Snapshots[i] = copyContext(snapshot)
+		}
+		copied.Flows[flowID] = &stateCopy
+	}
+
+	return &copied
+}
+
+// recordFlowStep mencatat kunjungan node flow untuk transcript
+func (s *Session) recordFlowStep(flowID string, nodeID string) {
+	s.FlowPath = append(s.FlowPath, models.TranscriptFlowStep{
+		FlowID:    flowID,
+		NodeID:    nodeID,
+		Timestamp: time.Now(),
+	})
+	if overflow := len(s.FlowPath) - maxHistoryMessages; overflow > 0 {
+		s.FlowPath = s.FlowPath[overflow:]
+	}
 }
 
-// GetSession mengambil session berdasarkan ID
-func (s *SessionStore) GetSession(sessionID string) (*Session, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// sessionAccess adalah operasi dasar yang disediakan setiap backend;
+// fn dijalankan secara atomik terhadap satu session
+type sessionAccess interface {
+	viewSession(sessionID string, fn func(session *Session)) bool
+	updateSession(sessionID string, fn func(session *Session)) bool
+}
 
-	session, exists := s.sessions[sessionID]
-	return session, exists
+// sessionOps mengimplementasikan operasi history, data, dan flow di atas sessionAccess
+// sehingga logika session sama untuk semua backend
+type sessionOps struct {
+	access sessionAccess
 }
 
-// AddMessage menambahkan pesan ke history session
-func (s *SessionStore) AddMessage(sessionID string, role string, content string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// VerifyToken mengecek token pemilik session. Session tanpa token (data lama) hanya bisa
+// diakses admin.
+func (o sessionOps) VerifyToken(sessionID string, token string) bool {
+	valid := false
+	o.access.viewSession(sessionID, func(session *Session) {
+		valid = session.TokenHash != "" && tokenMatches(session.TokenHash, token)
+	})
+	return valid
+}
 
-	session, exists := s.sessions[sessionID]
-	if !exists {
-		// Jika session tidak ada, buat baru
-		session = &Session{
-			ID:        sessionID,
-			History:   []models.OpenAIMessage{},
-			Data:      make(map[string]string),
-			CreatedAt: time.Now(),
+// AddMessage menambahkan pesan beserta metadata giliran ke history session
+func (o sessionOps) AddMessage(sessionID string, role string, content string, meta *models.MessageMeta) error {
+	found := o.access.updateSession(sessionID, func(session *Session) {
+		session.History = append(session.History, models.SessionMessage{
+			Role:      role,
+			Content:   content,
+			Timestamp: time.Now(),
+			Meta:      meta,
+		})
+
+		if overflow := len(session.History) - maxHistoryMessages; overflow > 0 {
+			session.History = session.History[overflow:]
+			session.Summarized = max(session.Summarized-overflow, 0)
 		}
-		s.sessions[sessionID] = session
+	})
+	if !found {
+		return ErrSessionNotFound
 	}
+	return nil
+}
 
-	session.History = append(session.History, models.OpenAIMessage{
-		Role:    role,
-		Content: content,
+// GetHistory mengambil salinan history untuk LLM (tanpa pesan yang sudah diringkas)
+func (o sessionOps) GetHistory(sessionID string) []models.OpenAIMessage {
+	history := []models.OpenAIMessage{}
+	o.access.viewSession(sessionID, func(session *Session) {
+		for _, msg := range session.History[min(session.Summarized, len(session.History)):] {
+			history = append(history, models.OpenAIMessage{Role: msg.Role, Content: msg.Content})
+		}
 	})
-	session.UpdatedAt = time.Now()
+	return history
+}
 
-	// Batasi history max 30 pesan (15 exchange)
-	if len(session.History) > 30 {
-		session.History = session.History[len(session.History)-30:]
-	}
+// ClearSession menghapus history, ringkasan, dan fakta yang disematkan di session
+func (o sessionOps) ClearSession(sessionID string) {
+	o.access.updateSession(sessionID, func(session *Session) {
+		session.History = []models.SessionMessage{}
+		session.Summarized = 0
+		session.FlowPath = nil
+		session.Summary = ""
+		session.Pinned = make(map[string]string)
+	})
+}
 
-	return nil
+// CompactHistory menggantikan count pesan tertua di history LLM dengan ringkasan berjalan.
+// Pesan aslinya tetap disimpan untuk transcript.
+func (o sessionOps) CompactHistory(sessionID string, count int, summary string) {
+	o.access.updateSession(sessionID, func(session *Session) {
+		session.Summarized = min(session.Summarized+count, len(session.History))
+		session.Summary = summary
+	})
 }
 
-// GetHistory mengambil history dari session
-func (s *SessionStore) GetHistory(sessionID string) []models.OpenAIMessage {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// GetSummary mengambil ringkasan percakapan lama dari session
+func (o sessionOps) GetSummary(sessionID string) string {
+	summary := ""
+	o.access.viewSession(sessionID, func(session *Session) {
+		summary = session.Summary
+	})
+	return summary
+}
 
-	session, exists := s.sessions[sessionID]
-	if !exists {
-		return []models.OpenAIMessage{}
-	}
+// PinFact menyimpan fakta penting yang selalu disertakan ke prompt (nama, nomor polisi, layanan)
+func (o sessionOps) PinFact(sessionID string, key string, value string) {
+	o.access.updateSession(sessionID, func(session *Session) {
+		if session.Pinned == nil {
+			session.Pinned = make(map[string]string)
+		}
+		session.Pinned[key] = value
+	})
+}
 
-	return session.History
+// GetPinnedFacts mengambil salinan fakta yang disematkan di session
+func (o sessionOps) GetPinnedFacts(sessionID string) map[string]string {
+	facts := make(map[string]string)
+	o.access.viewSession(sessionID, func(session *Session) {
+		for key, value := range session.Pinned {
+			facts[key] = value
+		}
+	})
+	return facts
 }
 
-// ClearSession menghapus history session
-func (s *SessionStore) ClearSession(sessionID string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// GetData mengambil data arbitrary dari session
+func (o sessionOps) GetData(sessionID string, key string) string {
+	value := ""
+	o.access.viewSession(sessionID, func(session *Session) {
+		value = session.Data[key]
+	})
+	return value
+}
 
-	if session, exists := s.sessions[sessionID]; exists {
-		session.History = []models.OpenAIMessage{}
-		session.UpdatedAt = time.Now()
-	}
+// SetData menyimpan data arbitrary ke session yang sudah ada
+func (o sessionOps) SetData(sessionID string, key string, value string) {
+	o.access.updateSession(sessionID, func(session *Session) {
+		session.Data[key] = value
+	})
 }
 
-// DeleteSession menghapus session
-func (s *SessionStore) DeleteSession(sessionID string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// StartFlow mengaktifkan flow di session dan mereset state-nya ke entry node
+func (o sessionOps) StartFlow(sessionID string, flowID string, entryNode string) {
+	o.access.updateSession(sessionID, func(session *Session) {
+		now := time.Now()
+		session.ActiveFlow = flowID
+		if session.PausedFlow == flowID {
+			session.PausedFlow = ""
+		}
+		session.Flows[flowID] = &FlowState{
+			FlowID:       flowID,
+			CurrentNode:  entryNode,
+			Context:      make(map[string]interface{}),
+			EntryContext: make(map[string]interface{}),
+			StartedAt:    now,
+			UpdatedAt:    now,
+		}
+		session.recordFlowStep(flowID, entryNode)
+	})
+}
 
-	delete(s.sessions, sessionID)
+// GetActiveFlow mengembalikan flow_id dan node saat ini dari flow yang aktif
+func (o sessionOps) GetActiveFlow(sessionID string) (string, string) {
+	flowID, nodeID := "", ""
+	o.access.viewSession(sessionID, func(session *Session) {
+		if state, exists := session.Flows[session.ActiveFlow]; exists && session.ActiveFlow != "" {
+			flowID, nodeID = state.FlowID, state.CurrentNode
+		}
+	})
+	return flowID, nodeID
 }
 
-// GetSessionCount mengembalikan jumlah session aktif
-func (s *SessionStore) GetSessionCount() int {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// SetFlowNode memindahkan posisi flow ke node tertentu; node sebelumnya disimpan di stack
+func (o sessionOps) SetFlowNode(sessionID string, flowID string, nodeID string) {
+	o.access.updateSession(sessionID, func(session *Session) {
+		state := session.Flows[flowID]
+		if state == nil {
+			return
+		}
 
-	return len(s.sessions)
+		if state.CurrentNode != "" && state.CurrentNode != nodeID {
+			state.Stack = append(state.Stack, state.CurrentNode)
+			state.Snapshots = append(state.Snapshots, copyContext(state.EntryContext))
+			state.EntryContext = copyContext(state.Context)
+		}
+		state.CurrentNode = nodeID
+		state.UpdatedAt = time.Now()
+		session.recordFlowStep(flowID, nodeID)
+	})
 }
 
-// GetData mengambil data arbitrary dari session
-func (s *SessionStore) GetData(sessionID string, key string) string {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// PopFlowNode mengembalikan posisi flow ke node sebelumnya di stack. Context flow ikut
+// dikembalikan ke keadaan saat node tersebut dimasuki, sehingga efek on_select pilihan
+// sebelumnya (dan semua nilai setelahnya) dibatalkan.
+func (o sessionOps) PopFlowNode(sessionID string, flowID string) (string, bool) {
+	nodeID, ok := "", false
+	o.access.updateSession(sessionID, func(session *Session) {
+		state := session.Flows[flowID]
+		if state == nil || len(state.Stack) == 0 {
+			return
+		}
 
-	session, exists := s.sessions[sessionID]
-	if !exists {
-		return ""
-	}
+		// Sessions stored before snapshots existed keep their context as is
+		if last := len(state.Snapshots) - 1; last == len(state.Stack)-1 {
+			state.Context = state.Snapshots[last]
+			state.Snapshots = state.Snapshots[:last]
+			state.EntryContext = copyContext(state.Context)
+		}
+		state.CurrentNode = state.Stack[len(state.Stack)-1]
+		state.Stack = state.Stack[:len(state.Stack)-1]
+		state.UpdatedAt = time.Now()
+		nodeID, ok = state.CurrentNode, true
+		session.recordFlowStep(flowID, nodeID)
+	})
+	return nodeID, ok
+}
 
-	return session.Data[key]
+// GetFlowStackDepth mengembalikan jumlah node yang bisa dikunjungi kembali
+func (o sessionOps) GetFlowStackDepth(sessionID string, flowID string) int {
+	depth := 0
+	o.access.viewSession(sessionID, func(session *Session) {
+		if state := session.Flows[flowID]; state != nil {
+			depth = len(state.Stack)
+		}
+	})
+	return depth
 }
 
-// SetData menyimpan data arbitrary ke session
-func (s *SessionStore) SetData(sessionID string, key string, value string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// EndFlow menonaktifkan flow yang sedang berjalan; state terakhir tetap disimpan
+func (o sessionOps) EndFlow(sessionID string) {
+	o.access.updateSession(sessionID, func(session *Session) {
+		if session.PausedFlow == session.ActiveFlow {
+			session.PausedFlow = ""
+		}
+		session.ActiveFlow = ""
+	})
+}
 
-	session, exists := s.sessions[sessionID]
-	if !exists {
-		// Create session if not exists
-		session = &Session{
-			ID:        sessionID,
-			History:   []models.OpenAIMessage{},
-			Data:      make(map[string]string),
-			CreatedAt: time.Now(),
+// PauseFlow menonaktifkan flow atas permintaan user; posisi dan context disimpan
+// agar flow bisa dilanjutkan dengan ResumeFlow
+func (o sessionOps) PauseFlow(sessionID string) {
+	o.access.updateSession(sessionID, func(session *Session) {
+		if session.ActiveFlow != "" {
+			session.PausedFlow = session.ActiveFlow
+			session.ActiveFlow = ""
 		}
-		s.sessions[sessionID] = session
-	}
+	})
+}
 
-	session.Data[key] = value
-	session.UpdatedAt = time.Now()
+// ResumeFlow mengaktifkan kembali flow yang dibatalkan dan mengembalikan flow_id serta node-nya
+func (o sessionOps) ResumeFlow(sessionID string) (string, string) {
+	flowID, nodeID := "", ""
+	o.access.updateSession(sessionID, func(session *Session) {
+		if session.ActiveFlow != "" || session.PausedFlow == "" {
+			return
+		}
+
+		state, exists := session.Flows[session.PausedFlow]
+		session.PausedFlow = ""
+		if !exists {
+			return
+		}
+
+		session.ActiveFlow = state.FlowID
+		flowID, nodeID = state.FlowID, state.CurrentNode
+	})
+	return flowID, nodeID
 }
 
-// cleanupExpiredSessions membersihkan session yang sudah tidak aktif > 24 jam
-func (s *SessionStore) cleanupExpiredSessions() {
-	ticker := time.NewTicker(1 * time.Hour)
-	defer ticker.Stop()
+// GetFlowContext mengambil salinan context flow dari session
+func (o sessionOps) GetFlowContext(sessionID string, flowID string) map[string]interface{} {
+	flowContext := make(map[string]interface{})
+	o.access.viewSession(sessionID, func(session *Session) {
+		if state := session.Flows[flowID]; state != nil {
+			flowContext = copyContext(state.Context)
+		}
+	})
+	return flowContext
+}
 
-	for range ticker.C {
-		s.mu.Lock()
-		now := time.Now()
-		for id, session := range s.sessions {
-			// Hapus session yang tidak aktif > 24 jam
-			if now.Sub(session.UpdatedAt) > 24*time.Hour {
-				delete(s.sessions, id)
-			}
+// SetFlowValue menyimpan nilai ke context flow; key bertitik (uploads.ktp) disimpan bersarang
+func (o sessionOps) SetFlowValue(sessionID string, flowID string, key string, value interface{}) {
+	o.access.updateSession(sessionID, func(session *Session) {
+		state := session.Flows[flowID]
+		if state == nil {
+			return
 		}
-		s.mu.Unlock()
-	}
+
+		setPath(state.Context, key, value)
+		state.UpdatedAt = time.Now()
+	})
 }
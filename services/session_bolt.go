@@ -0,0 +1,209 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+var sessionsBucket = []byte("sessions")
+
+// BoltSessionStore menyimpan session di file BoltDB sehingga history, data,
+// dan posisi flow tetap ada setelah restart atau redeploy. File dikunci eksklusif oleh
+// satu proses; ini bukan penyimpanan bersama untuk beberapa replica.
+type BoltSessionStore struct {
+	sessionOps
+	db *bolt.DB
+}
+
+func NewBoltSessionStore(path string) (*BoltSessionStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	// Timeout so a second process holding the file lock fails fast instead of hanging
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(sessionsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	store := &BoltSessionStore{db: db}
+	store.sessionOps = sessionOps{access: store}
+
+	log.Printf("✅ Bolt Session Store initialized (%s, %d sessions)", path, store.GetSessionCount())
+
+	// Start cleanup goroutine
+	go store.cleanupExpiredSessions()
+	return store, nil
+}
+
+// CreateSession membuat session baru beserta token pemiliknya. Session hanya dikembalikan
+// jika sudah tersimpan, agar client tidak memegang ID yang tidak pernah ada.
+func (s *BoltSessionStore) CreateSession() (string, string, error) {
+	sessionID := uuid.New().String()
+	token, tokenHash := newSessionToken()
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return putSession(tx, newSession(sessionID, tokenHash))
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return sessionID, token, nil
+}
+
+// GetSession mengambil salinan session berdasarkan ID
+func (s *BoltSessionStore) GetSession(sessionID string) (*Session, bool) {
+	var session *Session
+	s.viewSession(sessionID, func(stored *Session) {
+		session = stored
+	})
+	return session, session != nil
+}
+
+// DeleteSession menghapus session
+func (s *BoltSessionStore) DeleteSession(sessionID string) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).Delete([]byte(sessionID))
+	})
+	if err != nil {
+		log.Printf("❌ Failed to delete session %s: %v", sessionID, err)
+	}
+}
+
+// GetSessionCount mengembalikan jumlah session tersimpan
+func (s *BoltSessionStore) GetSessionCount() int {
+	count := 0
+	s.db.View(func(tx *bolt.Tx) error {
+		count = tx.Bucket(sessionsBucket).Stats().KeyN
+		return nil
+	})
+	return count
+}
+
+func (s *BoltSessionStore) viewSession(sessionID string, fn func(session *Session)) bool {
+	var session *Session
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		session, err = getSession(tx, sessionID)
+		return err
+	})
+	if err != nil {
+		log.Printf("❌ Failed to read session %s: %v", sessionID, err)
+		return false
+	}
+	if session == nil {
+		return false
+	}
+
+	fn(session)
+	return true
+}
+
+func (s *BoltSessionStore) updateSession(sessionID string, fn func(session *Session)) bool {
+	found := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		session, err := getSession(tx, sessionID)
+		if err != nil {
+			return err
+		}
+		if session == nil {
+			return nil
+		}
+
+		fn(session)
+		session.UpdatedAt = time.Now()
+		found = true
+		return putSession(tx, session)
+	})
+	if err != nil {
+		log.Printf("❌ Failed to update session %s: %v", sessionID, err)
+		return false
+	}
+	return found
+}
+
+func getSession(tx *bolt.Tx, sessionID string) (*Session, error) {
+	data := tx.Bucket(sessionsBucket).Get([]byte(sessionID))
+	if data == nil {
+		return nil, nil
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("corrupt session record: %w", err)
+	}
+	if session.Data == nil {
+		session.Data = make(map[string]string)
+	}
+	if session.Flows == nil {
+		session.Flows = make(map[string]*FlowState)
+	}
+	return &session, nil
+}
+
+func putSession(tx *bolt.Tx, session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(sessionsBucket).Put([]byte(session.ID), data)
+}
+
+// cleanupExpiredSessions membersihkan session yang sudah tidak aktif > 24 jam
+func (s *BoltSessionStore) cleanupExpiredSessions() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		removed := 0
+
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(sessionsBucket)
+			var expired [][]byte
+
+			err := bucket.ForEach(func(key, value []byte) error {
+				var session struct {
+					UpdatedAt time.Time `json:"updated_at"`
+				}
+				if err := json.Unmarshal(value, &session); err != nil || now.Sub(session.UpdatedAt) > sessionTTL {
+					expired = append(expired, append([]byte(nil), key...))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, key := range expired {
+				if err := bucket.Delete(key); err != nil {
+					return err
+				}
+			}
+			removed = len(expired)
+			return nil
+		})
+		if err != nil {
+			log.Printf("❌ Session cleanup failed: %v", err)
+		} else if removed > 0 {
+			log.Printf("🧹 Removed %d expired session(s)", removed)
+		}
+	}
+}
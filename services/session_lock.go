@@ -0,0 +1,88 @@
+package services
+
+import (
+	"errors"
+	"police-assistant-backend/config"
+	"sync"
+	"time"
+)
+
+// ErrTurnTimeout dikembalikan jika giliran session tidak didapat dalam batas waktu tunggu
+var ErrTurnTimeout = errors.New("another message in this session is still being processed")
+
+// TurnLocker memastikan hanya satu giliran chat per session yang diproses pada satu waktu,
+// sehingga dua pesan cepat tidak membaca history yang sama atau memajukan flow dua kali.
+// Lock berlaku per proses, sehingga server hanya boleh dijalankan sebagai satu replica.
+type TurnLocker struct {
+	mu      sync.Mutex
+	locks   map[string]*turnLock
+	timeout time.Duration
+}
+
+type turnLock struct {
+	slot chan struct{} // Kapasitas 1: terisi selama giliran berjalan
+	refs int           // Jumlah pemegang + yang menunggu; entry dihapus saat 0
+}
+
+var (
+	turnLocker     *TurnLocker
+	turnLockerOnce sync.Once
+)
+
+// GetTurnLocker returns singleton instance of TurnLocker
+func GetTurnLocker() *TurnLocker {
+	turnLockerOnce.Do(func() {
+		timeout := 20 * time.Second
+		if config.AppConfig != nil && config.AppConfig.SessionTurnTimeout > 0 {
+			timeout = config.AppConfig.SessionTurnTimeout
+		}
+		turnLocker = NewTurnLocker(timeout)
+	})
+	return turnLocker
+}
+
+func NewTurnLocker(timeout time.Duration) *TurnLocker {
+	return &TurnLocker{
+		locks:   make(map[string]*turnLock),
+		timeout: timeout,
+	}
+}
+
+// Acquire menunggu giliran untuk session; release wajib dipanggil setelah giliran selesai
+func (l *TurnLocker) Acquire(sessionID string) (func(), error) {
+	l.mu.Lock()
+	lock, exists := l.locks[sessionID]
+	if !exists {
+		lock = &turnLock{slot: make(chan struct{}, 1)}
+		l.locks[sessionID] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+
+	select {
+	case lock.slot <- struct{}{}:
+		var once sync.Once
+		return func() {
+			once.Do(func() {
+				<-lock.slot
+				l.unref(sessionID, lock)
+			})
+		}, nil
+	case <-timer.C:
+		l.unref(sessionID, lock)
+		return nil, ErrTurnTimeout
+	}
+}
+
+func (l *TurnLocker) unref(sessionID string, lock *turnLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock.refs--
+	if lock.refs == 0 {
+		delete(l.locks, sessionID)
+	}
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemorySessionStore menyimpan session di memory proses (hilang saat restart)
+type MemorySessionStore struct {
+	sessionOps
+	sessions map[string]*Session
+	mu       sync.RWMutex
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	store := &MemorySessionStore{
+		sessions: make(map[string]*Session),
+	}
+	store.sessionOps = sessionOps{access: store}
+
+	// Start cleanup goroutine
+	go store.cleanupExpiredSessions()
+	return store
+}
+
+// CreateSession membuat session baru beserta token pemiliknya
+func (s *MemorySessionStore) CreateSession() (string, string, error) {
+	token, tokenHash := newSessionToken()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessionID := uuid.New().String()
+	s.sessions[sessionID] = newSession(sessionID, tokenHash)
+
+	return sessionID, token, nil
+}
+
+// GetSession mengambil salinan session berdasarkan ID
+func (s *MemorySessionStore) GetSession(sessionID string) (*Session, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return nil, false
+	}
+	return session.clone(), true
+}
+
+// DeleteSession menghapus session
+func (s *MemorySessionStore) DeleteSession(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, sessionID)
+}
+
+// GetSessionCount mengembalikan jumlah session aktif
+func (s *MemorySessionStore) GetSessionCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.sessions)
+}
+
+func (s *MemorySessionStore) viewSession(sessionID string, fn func(session *Session)) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return false
+	}
+	fn(session)
+	return true
+}
+
+func (s *MemorySessionStore) updateSession(sessionID string, fn func(session *Session)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return false
+	}
+
+	fn(session)
+	session.UpdatedAt = time.Now()
+	return true
+}
+
+// cleanupExpiredSessions membersihkan session yang sudah tidak aktif > 24 jam
+func (s *MemorySessionStore) cleanupExpiredSessions() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		now := time.Now()
+		for id, session := range s.sessions {
+			if now.Sub(session.UpdatedAt) > sessionTTL {
+				delete(s.sessions, id)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestSessionStoreConcurrentTurns menjalankan giliran paralel di bawah turn lock pada kedua
+// backend. Jalankan dengan go test -race.
+func TestSessionStoreConcurrentTurns(t *testing.T) {
+	backends := map[string]func(t *testing.T) SessionStore{
+		"memory": func(t *testing.T) SessionStore { return NewMemorySessionStore() },
+		"bolt": func(t *testing.T) SessionStore {
+			store, err := NewBoltSessionStore(filepath.Join(t.TempDir(), "sessions.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return store
+		},
+	}
+
+	for name, newStore := range backends {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			locker := NewTurnLocker(time.Minute)
+
+			sessionID, token, err := store.CreateSession()
+			if err != nil {
+				t.Fatal(err)
+			}
+			store.StartFlow(sessionID, "stress", "node_0")
+
+			const workers, turns = 8, 10
+			var wg sync.WaitGroup
+			errs := make(chan error, workers*turns)
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func(worker int) {
+					defer wg.Done()
+					for turn := 0; turn < turns; turn++ {
+						release, err := locker.Acquire(sessionID)
+						if err != nil {
+							errs <- err
+							continue
+						}
+
+						// Same shape as a chat turn: read history, advance the flow, store a user/assistant pair
+						history := store.GetHistory(sessionID)
+						if len(history)%2 != 0 {
+							errs <- fmt.Errorf("worker %d: interleaved turn, history length %d", worker, len(history))
+						}
+						_, nodeID := store.GetActiveFlow(sessionID)
+						store.SetFlowNode(sessionID, "stress", nodeID+"+")
+						store.SetFlowValue(sessionID, "stress", "last_worker", worker)
+						store.AddMessage(sessionID, "user", fmt.Sprintf("w%d t%d", worker, turn), nil)
+						store.AddMessage(sessionID, "assistant", fmt.Sprintf("reply w%d t%d", worker, turn), nil)
+						release()
+					}
+				}(w)
+			}
+
+			// Readers outside the turn lock must only ever see copies
+			done := make(chan struct{})
+			var readers sync.WaitGroup
+			readers.Add(1)
+			go func() {
+				defer readers.Done()
+				for {
+					select {
+					case <-done:
+						return
+					default:
+						if session, ok := store.GetSession(sessionID); ok {
+							session.History = nil
+							session.Data["tampered"] = "yes"
+						}
+						store.GetFlowContext(sessionID, "stress")
+						store.VerifyToken(sessionID, token)
+					}
+				}
+			}()
+
+			wg.Wait()
+			close(done)
+			readers.Wait()
+			close(errs)
+			for err := range errs {
+				t.Error(err)
+			}
+
+			if _, nodeID := store.GetActiveFlow(sessionID); nodeID != "node_0"+strings.Repeat("+", workers*turns) {
+				t.Errorf("flow advanced %d times, want %d", len(nodeID)-len("node_0"), workers*turns)
+			}
+			if depth := store.GetFlowStackDepth(sessionID, "stress"); depth != workers*turns {
+				t.Errorf("flow stack depth %d, want %d", depth, workers*turns)
+			}
+			if store.GetData(sessionID, "tampered") != "" {
+				t.Error("GetSession returned the live session instead of a copy")
+			}
+			if !store.VerifyToken(sessionID, token) || store.VerifyToken(sessionID, token+"x") {
+				t.Error("session token no longer verifies correctly")
+			}
+		})
+	}
+}
+
+// TestPopFlowNodeRestoresContext memastikan "kembali" membatalkan on_select node yang ditinggalkan
+func TestPopFlowNodeRestoresContext(t *testing.T) {
+	store := NewMemorySessionStore()
+	sessionID, _, err := store.CreateSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store.StartFlow(sessionID, "sim", "sim_type")
+	store.SetFlowValue(sessionID, "sim", "sim_type", "sim_c")
+	store.SetFlowNode(sessionID, "sim", "ever_had_sim")
+	store.SetFlowValue(sessionID, "sim", "ever_had_sim", true)
+	store.SetFlowNode(sessionID, "sim", "upload_ktp")
+
+	if nodeID, ok := store.PopFlowNode(sessionID, "sim"); !ok || nodeID != "ever_had_sim" {
+		t.Fatalf("PopFlowNode = %q, %v, want ever_had_sim", nodeID, ok)
+	}
+	context := store.GetFlowContext(sessionID, "sim")
+	if _, exists := context["ever_had_sim"]; exists {
+		t.Errorf("context still has ever_had_sim after back: %v", context)
+	}
+	if context["sim_type"] != "sim_c" {
+		t.Errorf("sim_type = %v, want sim_c kept from the earlier node", context["sim_type"])
+	}
+
+	if nodeID, ok := store.PopFlowNode(sessionID, "sim"); !ok || nodeID != "sim_type" {
+		t.Fatalf("PopFlowNode = %q, %v, want sim_type", nodeID, ok)
+	}
+	if context := store.GetFlowContext(sessionID, "sim"); len(context) != 0 {
+		t.Errorf("context after going back to the entry node = %v, want empty", context)
+	}
+}
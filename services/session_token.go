@@ -0,0 +1,39 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+)
+
+// Panjang token session dalam byte (sebelum di-encode)
+const sessionTokenBytes = 32
+
+// newSessionToken membuat token acak untuk pemilik session beserta hash yang disimpan di store
+func newSessionToken() (string, string) {
+	raw := make([]byte, sessionTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		// crypto/rand only fails if the OS entropy source is broken; never hand out a guessable token
+		log.Fatalf("❌ Failed to generate session token: %v", err)
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashSessionToken(token)
+}
+
+// hashSessionToken menghasilkan SHA-256 (hex) dari token
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenMatches membandingkan token dengan hash tersimpan dalam waktu konstan
+func tokenMatches(tokenHash string, token string) bool {
+	if token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(tokenHash), []byte(hashSessionToken(token))) == 1
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"police-assistant-backend/models"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Format ekspor transcript yang didukung
+const (
+	TranscriptJSON     = "json"
+	TranscriptMarkdown = "md"
+	TranscriptPDF      = "pdf"
+)
+
+const transcriptTimeFormat = "2006-01-02 15:04:05 MST"
+
+// BuildTranscript menyusun transcript dari salinan session (lihat SessionStore.GetSession)
+func BuildTranscript(session *Session) *models.Transcript {
+	transcript := &models.Transcript{
+		SessionID:  session.ID,
+		CreatedAt:  session.CreatedAt,
+		UpdatedAt:  session.UpdatedAt,
+		ExportedAt: time.Now(),
+		Models:     []string{},
+		Summary:    session.Summary,
+		FlowPath:   append([]models.TranscriptFlowStep{}, session.FlowPath...),
+		Messages:   append([]models.SessionMessage{}, session.History...),
+	}
+
+	seen := make(map[string]bool)
+	for _, msg := range session.History {
+		if msg.Meta != nil && msg.Meta.Model != "" && !seen[msg.Meta.Model] {
+			seen[msg.Meta.Model] = true
+			transcript.Models = append(transcript.Models, msg.Meta.Model)
+		}
+	}
+
+	return transcript
+}
+
+// RenderTranscriptMarkdown menulis transcript sebagai dokumen Markdown
+func RenderTranscriptMarkdown(transcript *models.Transcript) string {
+	var b strings.Builder
+
+	b.WriteString("# Transcript Percakapan\n\n")
+	for _, line := range transcriptHeader(transcript) {
+		fmt.Fprintf(&b, "- **%s**: %s\n", line[0], line[1])
+	}
+
+	if transcript.Summary != "" {
+		b.WriteString("\n## Ringkasan Percakapan Lama\n\n")
+		b.WriteString(transcript.Summary)
+		b.WriteString("\n")
+	}
+
+	if len(transcript.FlowPath) > 0 {
+		b.WriteString("\n## Alur Flow\n\n| Waktu | Flow | Node |\n|-------|------|------|\n")
+		for _, step := range transcript.FlowPath {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", formatTranscriptTime(step.Timestamp), step.FlowID, step.NodeID)
+		}
+	}
+
+	b.WriteString("\n## Percakapan\n")
+	for _, msg := range transcript.Messages {
+		fmt.Fprintf(&b, "\n### [%s] %s\n\n", formatTranscriptTime(msg.Timestamp), transcriptSpeaker(msg))
+		b.WriteString(msg.Content)
+		b.WriteString("\n")
+		if details := transcriptDetails(msg.Meta); len(details) > 0 {
+			b.WriteString("\n")
+			for _, detail := range details {
+				fmt.Fprintf(&b, "> %s  \n", detail)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// RenderTranscriptPDF menulis transcript sebagai PDF A4 sederhana
+func RenderTranscriptPDF(transcript *models.Transcript) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Transcript "+transcript.SessionID, true)
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(0, 5, fmt.Sprintf("Halaman %d/{nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	// Core PDF fonts only cover cp1252; emoji and box-drawing characters are stripped first
+	translate := pdf.UnicodeTranslatorFromDescriptor("")
+	text := func(s string) string {
+		return translate(stripPDFSymbols(s))
+	}
+
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, "Transcript Percakapan", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	for _, line := range transcriptHeader(transcript) {
+		pdf.MultiCell(0, 5, text(line[0]+": "+line[1]), "", "L", false)
+	}
+
+	section := func(title string) {
+		pdf.Ln(4)
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.CellFormat(0, 7, title, "B", 1, "L", false, 0, "")
+		pdf.Ln(1)
+	}
+
+	if transcript.Summary != "" {
+		section("Ringkasan Percakapan Lama")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.MultiCell(0, 5, text(transcript.Summary), "", "L", false)
+	}
+
+	if len(transcript.FlowPath) > 0 {
+		section("Alur Flow")
+		pdf.SetFont("Helvetica", "", 9)
+		for _, step := range transcript.FlowPath {
+			pdf.MultiCell(0, 5, text(fmt.Sprintf("%s  %s / %s", formatTranscriptTime(step.Timestamp), step.FlowID, step.NodeID)), "", "L", false)
+		}
+	}
+
+	section("Percakapan")
+	for _, msg := range transcript.Messages {
+		pdf.Ln(2)
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.MultiCell(0, 5, text(fmt.Sprintf("[%s] %s", formatTranscriptTime(msg.Timestamp), transcriptSpeaker(msg))), "", "L", false)
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.MultiCell(0, 5, text(msg.Content), "", "L", false)
+
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(90, 90, 90)
+		for _, detail := range transcriptDetails(msg.Meta) {
+			pdf.MultiCell(0, 4, text(detail), "", "L", false)
+		}
+		pdf.SetTextColor(0, 0, 0)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render transcript PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// transcriptHeader mengembalikan pasangan label/nilai untuk bagian atas transcript
+func transcriptHeader(transcript *models.Transcript) [][2]string {
+	modelsUsed := "-"
+	if len(transcript.Models) > 0 {
+		modelsUsed = strings.Join(transcript.Models, ", ")
+	}
+
+	return [][2]string{
+		{"Session ID", transcript.SessionID},
+		{"Dibuat", formatTranscriptTime(transcript.CreatedAt)},
+		{"Terakhir aktif", formatTranscriptTime(transcript.UpdatedAt)},
+		{"Diekspor", formatTranscriptTime(transcript.ExportedAt)},
+		{"Model", modelsUsed},
+		{"Jumlah pesan", fmt.Sprintf("%d", len(transcript.Messages))},
+	}
+}
+
+// transcriptSpeaker memberi label pengirim pesan beserta sumber balasan asisten
+func transcriptSpeaker(msg models.SessionMessage) string {
+	if msg.Role == "user" {
+		return "User"
+	}
+
+	speaker := "Asisten"
+	if msg.Meta == nil {
+		return speaker
+	}
+	switch {
+	case msg.Meta.Model != "":
+		speaker += " (" + msg.Meta.Model + ")"
+	case msg.Meta.ReplySource == FlowReplyDeterministic:
+		speaker += " (flow)"
+	}
+	return speaker
+}
+
+// transcriptDetails meringkas metadata pesan (pilihan, node flow, data e-tilang, pelayanan)
+func transcriptDetails(meta *models.MessageMeta) []string {
+	if meta == nil {
+		return nil
+	}
+
+	var details []string
+	if meta.ChoiceID != "" {
+		details = append(details, "Pilihan: "+meta.ChoiceID)
+	}
+	if meta.FlowID != "" {
+		details = append(details, fmt.Sprintf("Flow: %s / %s", meta.FlowID, meta.FlowNode))
+	}
+	if len(meta.ToolCalls) > 0 {
+		details = append(details, "Tool: "+strings.Join(meta.ToolCalls, ", "))
+	}
+	if meta.PromptVersion != "" {
+		details = append(details, "Prompt: "+meta.PromptVersion)
+	}
+	if info := meta.ETilangInfo; info != nil {
+		detail := fmt.Sprintf("E-Tilang %s: %d pelanggaran, total denda Rp %d", info.PlateNumber, len(info.Violations), info.TotalFine)
+		switch {
+		case !info.Found && info.ChassisNumber == "":
+			// Records saved before lookups reported "found" always carry a chassis number
+			detail = fmt.Sprintf("E-Tilang %s: nomor polisi tidak ditemukan", info.PlateNumber)
+		case !info.HasViolation:
+			detail = fmt.Sprintf("E-Tilang %s: tidak ada pelanggaran", info.PlateNumber)
+		}
+		details = append(details, detail)
+	}
+	if info := meta.PelayananInfo; info != nil && info.Found {
+		details = append(details, "Pelayanan: "+info.Flow.Title)
+	}
+	return details
+}
+
+func formatTranscriptTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(transcriptTimeFormat)
+}
+
+// stripPDFSymbols membuang emoji dan simbol yang tidak ada di font PDF standar
+func stripPDFSymbols(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\u200d' || unicode.Is(unicode.Variation_Selector, r) || (r > unicode.MaxLatin1 && unicode.IsSymbol(r)) {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"police-assistant-backend/config"
+	"police-assistant-backend/models"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Kode alasan penolakan upload
+const (
+	UploadRejectNoData          = "no_data"
+	UploadRejectInvalidBase64   = "invalid_base64"
+	UploadRejectFetchFailed     = "fetch_failed"
+	UploadRejectURLNotAllowed   = "url_not_allowed"
+	UploadRejectEmptyFile       = "empty_file"
+	UploadRejectTooLarge        = "file_too_large"
+	UploadRejectMimeNotAllowed  = "mime_not_allowed"
+	UploadRejectContentMismatch = "content_mismatch"
+	UploadRejectStorageFailed   = "storage_failed"
+)
+
+// Jumlah redirect maksimal saat mengunduh upload dari url
+const uploadMaxRedirects = 3
+
+var (
+	errUploadTooLarge      = errors.New("file exceeds upload limit")
+	errUploadURLNotAllowed = errors.New("upload url is not allowed")
+)
+
+// Rentang alamat yang tidak boleh dihubungi saat mengunduh upload (SSRF): selain loopback,
+// private, dan link-local (termasuk metadata cloud 169.254.169.254) yang dicek lewat netip
+var uploadBlockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 bisa menembus ke alamat IPv4 internal
+}
+
+// UploadService memvalidasi dan menyimpan dokumen yang diupload user
+type UploadService struct {
+	fileStore    *FileStore
+	httpClient   *http.Client
+	maxSize      int64
+	allowedHosts map[string]bool
+}
+
+func NewUploadService(fileStore *FileStore) *UploadService {
+	service := &UploadService{
+		fileStore:    fileStore,
+		maxSize:      config.AppConfig.MaxUploadSize,
+		allowedHosts: make(map[string]bool),
+	}
+	for _, host := range config.AppConfig.UploadURLHosts {
+		service.allowedHosts[host] = true
+	}
+	service.httpClient = newUploadHTTPClient(service.checkUploadURL)
+
+	if len(service.allowedHosts) == 0 {
+		log.Printf("✅ Upload Service initialized (max size: %d bytes, url uploads disabled)", service.maxSize)
+	} else {
+		log.Printf("✅ Upload Service initialized (max size: %d bytes, url hosts: %s)", service.maxSize, strings.Join(config.AppConfig.UploadURLHosts, ", "))
+	}
+	return service
+}
+
+// newUploadHTTPClient membuat client yang hanya terhubung ke alamat publik. Alamat dicek
+// saat koneksi dibuat (setelah DNS di-resolve), sehingga DNS rebinding tidak bisa mengarah
+// ke jaringan internal. Setiap redirect dicek ulang dengan checkURL.
+func newUploadHTTPClient(checkURL func(*url.URL) error) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !uploadAddrAllowed(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s resolves to a non-public address", errUploadURLNotAllowed, address)
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: 15 * time.Second,
+		Transport: &http.Transport{
+			Proxy:               nil, // Dial the target directly so the address check applies
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= uploadMaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", uploadMaxRedirects)
+			}
+			return checkURL(req.URL)
+		},
+	}
+}
+
+// uploadAddrAllowed menolak alamat loopback, private, link-local, multicast, dan rentang khusus
+func uploadAddrAllowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range uploadBlockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkUploadURL memastikan url memakai http/https dan host-nya ada di UPLOAD_URL_HOSTS
+func (s *UploadService) checkUploadURL(target *url.URL) error {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported scheme %q", errUploadURLNotAllowed, target.Scheme)
+	}
+	if !s.allowedHosts[strings.ToLower(target.Hostname())] {
+		return fmt.Errorf("%w: host %q is not in UPLOAD_URL_HOSTS", errUploadURLNotAllowed, target.Hostname())
+	}
+	return nil
+}
+
+// Store memvalidasi dokumen terhadap daftar MIME yang diizinkan lalu menyimpannya sebagai milik session
+func (s *UploadService) Store(sessionID string, doc models.UploadedDocument, allowedMime []string) (*StoredFile, *models.UploadRejection) {
+	reject := func(code string, reason string) (*StoredFile, *models.UploadRejection) {
+		log.Printf("🚫 Upload rejected: %s (%s) - %s", doc.FileName, code, reason)
+		return nil, &models.UploadRejection{
+			FileName:     doc.FileName,
+			Code:         code,
+			Reason:       reason,
+			AllowedTypes: allowedMime,
+			MaxSizeBytes: s.maxSize,
+		}
+	}
+
+	var data []byte
+	contentType := strings.ToLower(strings.TrimSpace(doc.FileType))
+
+	switch {
+	case doc.Base64Data != "":
+		encoded := doc.Base64Data
+		// Data URL: data:image/png;base64,xxxx
+		if strings.HasPrefix(encoded, "data:") {
+			if comma := strings.Index(encoded, ","); comma != -1 {
+				if contentType == "" {
+					contentType = strings.TrimSuffix(strings.TrimPrefix(encoded[:comma], "data:"), ";base64")
+				}
+				encoded = encoded[comma+1:]
+			}
+		}
+
+		if int64(base64.StdEncoding.DecodedLen(len(encoded))) > s.maxSize+2 {
+			return reject(UploadRejectTooLarge, fmt.Sprintf("Ukuran file melebihi batas %s.", formatBytes(s.maxSize)))
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			decoded, err = base64.RawStdEncoding.DecodeString(encoded)
+		}
+		if err != nil {
+			return reject(UploadRejectInvalidBase64, "Data file tidak dapat dibaca (format base64 tidak valid).")
+		}
+		data = decoded
+
+	case doc.URL != "":
+		fetched, fetchedType, err := s.fetch(doc.URL)
+		if err != nil {
+			log.Printf("❌ Failed to fetch upload from %s: %v", doc.URL, err)
+			if err == errUploadTooLarge {
+				return reject(UploadRejectTooLarge, fmt.Sprintf("Ukuran file melebihi batas %s.", formatBytes(s.maxSize)))
+			}
+			if errors.Is(err, errUploadURLNotAllowed) {
+				return reject(UploadRejectURLNotAllowed, "File dari URL ini tidak diterima. Kirim file sebagai base64.")
+			}
+			return reject(UploadRejectFetchFailed, "File dari URL tidak dapat diambil.")
+		}
+		data = fetched
+		if contentType == "" {
+			contentType = fetchedType
+		}
+
+	default:
+		return reject(UploadRejectNoData, "Dokumen tidak berisi data file maupun URL.")
+	}
+
+	if len(data) == 0 {
+		return reject(UploadRejectEmptyFile, "File yang diupload kosong.")
+	}
+	if int64(len(data)) > s.maxSize {
+		return reject(UploadRejectTooLarge, fmt.Sprintf("Ukuran file melebihi batas %s.", formatBytes(s.maxSize)))
+	}
+
+	// Deteksi tipe dari isi file untuk mencegah tipe yang dipalsukan
+	sniffed := strings.Split(http.DetectContentType(data), ";")[0]
+	if contentType == "" {
+		contentType = sniffed
+	}
+	contentType = strings.Split(contentType, ";")[0]
+
+	if !mimeAllowed(contentType, allowedMime) {
+		return reject(UploadRejectMimeNotAllowed, fmt.Sprintf("Tipe file %s tidak diterima. Tipe yang diterima: %s.", contentType, strings.Join(allowedMime, ", ")))
+	}
+	if sniffed != "application/octet-stream" && !sameMajorType(sniffed, contentType) {
+		return reject(UploadRejectContentMismatch, "Isi file tidak sesuai dengan tipe file yang dikirim.")
+	}
+
+	stored, err := s.fileStore.Save(sessionID, doc.FileName, contentType, data)
+	if err != nil {
+		log.Printf("❌ Failed to store upload %s: %v", doc.FileName, err)
+		return reject(UploadRejectStorageFailed, "File gagal disimpan, silakan coba lagi.")
+	}
+
+	log.Printf("📥 Upload stored: %s (%s, %d bytes) -> %s", doc.FileName, contentType, len(data), stored.ID)
+	return stored, nil
+}
+
+// fetch mengunduh file dari URL dengan batas ukuran. Hanya host di UPLOAD_URL_HOSTS yang
+// dihubungi, dan hanya jika alamatnya publik.
+func (s *UploadService) fetch(rawURL string) ([]byte, string, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", errUploadURLNotAllowed, err)
+	}
+	if err := s.checkUploadURL(target); err != nil {
+		return nil, "", err
+	}
+
+	resp, err := s.httpClient.Get(target.String())
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > s.maxSize {
+		return nil, "", errUploadTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, s.maxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > s.maxSize {
+		return nil, "", errUploadTooLarge
+	}
+
+	return data, strings.ToLower(resp.Header.Get("Content-Type")), nil
+}
+
+// mimeAllowed mencocokkan tipe file dengan daftar MIME (mendukung wildcard image/*)
+func mimeAllowed(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if pattern == "*/*" || pattern == contentType {
+			return true
+		}
+		if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameMajorType(a, b string) bool {
+	return strings.SplitN(a, "/", 2)[0] == strings.SplitN(b, "/", 2)[0]
+}
+
+func formatBytes(size int64) string {
+	if size >= 1024*1024 {
+		return fmt.Sprintf("%.0f MB", float64(size)/(1024*1024))
+	}
+	return fmt.Sprintf("%d KB", size/1024)
+}
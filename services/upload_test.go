@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+func TestUploadAddrAllowed(t *testing.T) {
+	cases := []struct {
+		addr    string
+		allowed bool
+	}{
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false}, // Cloud metadata
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"fd00:ec2::254", false},
+		{"fe80::1", false},
+		{"64:ff9b::a9fe:a9fe", false},
+	}
+	for _, tc := range cases {
+		if got := uploadAddrAllowed(netip.MustParseAddr(tc.addr)); got != tc.allowed {
+			t.Errorf("uploadAddrAllowed(%s) = %v, want %v", tc.addr, got, tc.allowed)
+		}
+	}
+}
+
+func newTestUploadService(hosts ...string) *UploadService {
+	service := &UploadService{maxSize: 1 << 20, allowedHosts: make(map[string]bool)}
+	for _, host := range hosts {
+		service.allowedHosts[host] = true
+	}
+	service.httpClient = newUploadHTTPClient(service.checkUploadURL)
+	return service
+}
+
+func TestUploadFetchRejectsHostNotAllowed(t *testing.T) {
+	service := newTestUploadService("files.example.com")
+	for _, rawURL := range []string{
+		"http://169.254.169.254/latest/meta-data/",
+		"http://other.example.com/ktp.png",
+		"file:///etc/passwd",
+	} {
+		if _, _, err := service.fetch(rawURL); !errors.Is(err, errUploadURLNotAllowed) {
+			t.Errorf("fetch(%s) error = %v, want errUploadURLNotAllowed", rawURL, err)
+		}
+	}
+}
+
+// Even an allowlisted host must not reach an internal address (e.g. after DNS rebinding)
+func TestUploadFetchRejectsInternalAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret"))
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	service := newTestUploadService(target.Hostname())
+	if _, _, err := service.fetch(server.URL); !errors.Is(err, errUploadURLNotAllowed) {
+		t.Fatalf("fetch(%s) error = %v, want errUploadURLNotAllowed", server.URL, err)
+	}
+}
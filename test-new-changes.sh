@@ -9,6 +9,22 @@
 
 BASE_URL="http://localhost:8080/api/v1"
 
+# Session harus dibuat lewat API; session_id buatan sendiri ditolak server.
+# new_session mencetak "<session_id> <session_token>"
+new_session() {
+  curl -s -X POST "$BASE_URL/session" | jq -r '"\(.session_id) \(.session_token)"'
+}
+
+read SID_GREETING_001 TOK_GREETING_001 < <(new_session)
+read SID_GREETING_002 TOK_GREETING_002 < <(new_session)
+read SID_ETILANG_LINK_001 TOK_ETILANG_LINK_001 < <(new_session)
+read SID_SIM_HILANG_001 TOK_SIM_HILANG_001 < <(new_session)
+read SID_SIM_RUSAK_001 TOK_SIM_RUSAK_001 < <(new_session)
+read SID_SIM_INTL_001 TOK_SIM_INTL_001 < <(new_session)
+read SID_MUTASI_001 TOK_MUTASI_001 < <(new_session)
+read SID_BALIKNAMA_001 TOK_BALIKNAMA_001 < <(new_session)
+read SID_NO_GREETING_001 TOK_NO_GREETING_001 < <(new_session)
+
 # Colors for output
 RED='\033[0;31m'
 GREEN='\033[0;32m'
@@ -33,7 +49,8 @@ echo -e "${YELLOW}Expected: Harus menyapa 'Halo Taufan!' bukan 'Halo Sobat Lanta
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-greeting-001",
+    "session_id": "'"$SID_GREETING_001"'",
+    "session_token": "'"$TOK_GREETING_001"'",
     "message": "halo",
     "name": "Taufan",
     "location": "Jakarta Selatan"
@@ -56,7 +73,8 @@ echo -e "${YELLOW}Expected: Harus menyapa 'Halo Sobat Lantas!' karena nama tidak
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-greeting-002",
+    "session_id": "'"$SID_GREETING_002"'",
+    "session_token": "'"$TOK_GREETING_002"'",
     "message": "halo",
     "location": "Jakarta Pusat"
   }' | jq '.'
@@ -78,7 +96,8 @@ echo -e "${YELLOW}Expected: Response harus ada link 'https://etle-pmj.id/' untuk
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-etilang-link-001",
+    "session_id": "'"$SID_ETILANG_LINK_001"'",
+    "session_token": "'"$TOK_ETILANG_LINK_001"'",
     "message": "cek tilang B1234SV",
     "name": "Andi",
     "location": "Jakarta"
@@ -101,7 +120,8 @@ echo -e "${YELLOW}Expected: Harus ke flow 'SIM Hilang / Rusak', BUKAN 'Buat SIM'
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-sim-hilang-001",
+    "session_id": "'"$SID_SIM_HILANG_001"'",
+    "session_token": "'"$TOK_SIM_HILANG_001"'",
     "message": "SIM saya hilang bisa dibantu?",
     "name": "Budi",
     "location": "Bandung"
@@ -124,7 +144,8 @@ echo -e "${YELLOW}Expected: Harus ke flow 'SIM Hilang / Rusak', BUKAN 'Buat SIM'
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-sim-rusak-001",
+    "session_id": "'"$SID_SIM_RUSAK_001"'",
+    "session_token": "'"$TOK_SIM_RUSAK_001"'",
     "message": "SIM saya rusak mau ganti",
     "name": "Citra",
     "location": "Surabaya"
@@ -147,7 +168,8 @@ echo -e "${YELLOW}Expected: Harus ke flow 'SIM Internasional', BUKAN 'Buat SIM'$
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-sim-intl-001",
+    "session_id": "'"$SID_SIM_INTL_001"'",
+    "session_token": "'"$TOK_SIM_INTL_001"'",
     "message": "mau bikin SIM internasional",
     "name": "Deni",
     "location": "Jakarta"
@@ -170,7 +192,8 @@ echo -e "${YELLOW}Expected: Harus ke flow 'Mutasi Kendaraan', BUKAN 'Balik Nama'
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-mutasi-001",
+    "session_id": "'"$SID_MUTASI_001"'",
+    "session_token": "'"$TOK_MUTASI_001"'",
     "message": "saya mau mutasi kendaraan",
     "name": "Eko",
     "location": "Semarang"
@@ -193,7 +216,8 @@ echo -e "${YELLOW}Expected: Harus ke flow 'Balik Nama Kendaraan', BUKAN 'Mutasi'
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-balikNama-001",
+    "session_id": "'"$SID_BALIKNAMA_001"'",
+    "session_token": "'"$TOK_BALIKNAMA_001"'",
     "message": "mau balik nama motor bisa dibantu?",
     "name": "Fitri",
     "location": "Yogyakarta"
@@ -216,7 +240,8 @@ echo -e "${YELLOW}Step 1: Pesan pertama (ada greeting)${NC}"
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-no-greeting-001",
+    "session_id": "'"$SID_NO_GREETING_001"'",
+    "session_token": "'"$TOK_NO_GREETING_001"'",
     "message": "halo",
     "name": "Gita",
     "location": "Malang"
@@ -230,7 +255,8 @@ echo -e "${YELLOW}Step 2: Pesan lanjutan (TIDAK ada greeting)${NC}"
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-no-greeting-001",
+    "session_id": "'"$SID_NO_GREETING_001"'",
+    "session_token": "'"$TOK_NO_GREETING_001"'",
     "message": "mau tanya cara perpanjang SIM",
     "name": "Gita",
     "location": "Malang"
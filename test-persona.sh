@@ -26,6 +26,7 @@ RESPONSE1=$(curl -s -X POST http://localhost:8080/api/v1/chat \
 
 echo "$RESPONSE1" | jq '.'
 SESSION_ID=$(echo "$RESPONSE1" | jq -r '.session_id')
+SESSION_TOKEN=$(echo "$RESPONSE1" | jq -r '.session_token')
 echo ""
 echo "Session ID: $SESSION_ID"
 echo ""
@@ -46,6 +47,7 @@ curl -s -X POST http://localhost:8080/api/v1/chat \
       \"latitude\": -6.2088,
       \"longitude\": 106.8456
     },
+    \"session_token\": \"$SESSION_TOKEN\",
     \"session_id\": \"$SESSION_ID\"
   }" | jq '.'
 
@@ -67,6 +69,7 @@ curl -s -X POST http://localhost:8080/api/v1/chat \
       \"latitude\": -6.2345,
       \"longitude\": 106.8765
     },
+    \"session_token\": \"$SESSION_TOKEN\",
     \"session_id\": \"$SESSION_ID\"
   }" | jq '.'
 
@@ -5,6 +5,29 @@
 
 BASE_URL="http://localhost:8080/api/v1"
 
+# Session harus dibuat lewat API; session_id buatan sendiri ditolak server.
+# new_session mencetak "<session_id> <session_token>"
+new_session() {
+  curl -s -X POST "$BASE_URL/session" | jq -r '"\(.session_id) \(.session_token)"'
+}
+
+read SID_SIM_BARU_001 TOK_SIM_BARU_001 < <(new_session)
+read SID_PERPANJANG_SIM_002 TOK_PERPANJANG_SIM_002 < <(new_session)
+read SID_PAJAK_003 TOK_PAJAK_003 < <(new_session)
+read SID_STNK_004 TOK_STNK_004 < <(new_session)
+read SID_MUTASI_005 TOK_MUTASI_005 < <(new_session)
+read SID_BALIK_NAMA_006 TOK_BALIK_NAMA_006 < <(new_session)
+read SID_PLAT_007 TOK_PLAT_007 < <(new_session)
+read SID_COMBO_008 TOK_COMBO_008 < <(new_session)
+read SID_NO_NAME_009 TOK_NO_NAME_009 < <(new_session)
+read SID_UPGRADE_010 TOK_UPGRADE_010 < <(new_session)
+read SID_ETILANG_011 TOK_ETILANG_011 < <(new_session)
+read SID_ETILANG_012 TOK_ETILANG_012 < <(new_session)
+read SID_ETILANG_013 TOK_ETILANG_013 < <(new_session)
+read SID_ETILANG_014 TOK_ETILANG_014 < <(new_session)
+read SID_ETILANG_015 TOK_ETILANG_015 < <(new_session)
+read SID_ETILANG_016 TOK_ETILANG_016 < <(new_session)
+
 echo "════════════════════════════════════════════════════════════════"
 echo "🧪 TEST RULES-BASED CONVERSATION FLOW"
 echo "════════════════════════════════════════════════════════════════"
@@ -28,7 +51,8 @@ echo -e "${YELLOW}Step 1: User bertanya tentang pembuatan SIM${NC}"
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-sim-baru-001",
+    "session_id": "'"$SID_SIM_BARU_001"'",
+    "session_token": "'"$TOK_SIM_BARU_001"'",
     "message": "mau bikin sim baru",
     "name": "Budi",
     "location": "Jakarta Selatan",
@@ -46,7 +70,8 @@ echo -e "${YELLOW}Step 2: User mau dibantu${NC}"
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-sim-baru-001",
+    "session_id": "'"$SID_SIM_BARU_001"'",
+    "session_token": "'"$TOK_SIM_BARU_001"'",
     "message": "iya mau dibantu",
     "name": "Budi",
     "location": "Jakarta Selatan",
@@ -71,7 +96,8 @@ echo -e "${YELLOW}Step 1: User bertanya tentang perpanjangan SIM${NC}"
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-perpanjang-sim-002",
+    "session_id": "'"$SID_PERPANJANG_SIM_002"'",
+    "session_token": "'"$TOK_PERPANJANG_SIM_002"'",
     "message": "bagaimana cara perpanjang SIM?",
     "name": "Siti",
     "location": "Tangerang Selatan",
@@ -86,7 +112,8 @@ sleep 3
 echo -e "${YELLOW}Step 2: User upload dokumen${NC}"
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: multipart/form-data" \
-  -F "session_id=test-perpanjang-sim-002" \
+  -H "X-Session-Token: $TOK_PERPANJANG_SIM_002" \
+  -F "session_id=$SID_PERPANJANG_SIM_002" \
   -F "message=ini dokumen saya" \
   -F "name=Siti" \
   -F "location=Tangerang Selatan" \
@@ -112,7 +139,8 @@ echo -e "${YELLOW}Step 1: User bertanya tentang pajak kendaraan${NC}"
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-pajak-003",
+    "session_id": "'"$SID_PAJAK_003"'",
+    "session_token": "'"$TOK_PAJAK_003"'",
     "message": "cara bayar pajak motor gimana ya?",
     "name": "Ahmad",
     "location": "Bekasi",
@@ -128,7 +156,8 @@ echo -e "${YELLOW}Step 2: User minta bantuan${NC}"
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-pajak-003",
+    "session_id": "'"$SID_PAJAK_003"'",
+    "session_token": "'"$TOK_PAJAK_003"'",
     "message": "iya tolong bantu saya",
     "name": "Ahmad",
     "location": "Bekasi"
@@ -151,7 +180,8 @@ echo -e "${YELLOW}Step 1: User bertanya tentang pengesahan STNK${NC}"
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-stnk-004",
+    "session_id": "'"$SID_STNK_004"'",
+    "session_token": "'"$TOK_STNK_004"'",
     "message": "STNK saya mau habis masa berlakunya, harus diapakan?",
     "name": "Rina",
     "location": "Bogor",
@@ -176,7 +206,8 @@ echo -e "${YELLOW}User bertanya tentang mutasi kendaraan${NC}"
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-mutasi-005",
+    "session_id": "'"$SID_MUTASI_005"'",
+    "session_token": "'"$TOK_MUTASI_005"'",
     "message": "saya mau mutasi motor saya dari Jakarta ke Bandung",
     "name": "Deni",
     "location": "Jakarta Pusat",
@@ -201,7 +232,8 @@ echo -e "${YELLOW}User bertanya tentang balik nama${NC}"
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-balik-nama-006",
+    "session_id": "'"$SID_BALIK_NAMA_006"'",
+    "session_token": "'"$TOK_BALIK_NAMA_006"'",
     "message": "cara balik nama mobil bekas gimana?",
     "name": "Fitri",
     "location": "Depok",
@@ -226,7 +258,8 @@ echo -e "${YELLOW}User bertanya tentang ganti plat nomor${NC}"
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-plat-007",
+    "session_id": "'"$SID_PLAT_007"'",
+    "session_token": "'"$TOK_PLAT_007"'",
     "message": "plat nomor motor saya mau habis masa berlakunya 5 tahun",
     "name": "Eko",
     "location": "Tangerang",
@@ -251,7 +284,8 @@ echo -e "${YELLOW}Step 1: User cek e-tilang${NC}"
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-combo-008",
+    "session_id": "'"$SID_COMBO_008"'",
+    "session_token": "'"$TOK_COMBO_008"'",
     "message": "cek tilang B1234XYZ",
     "name": "Rudi",
     "location": "Jakarta Barat"
@@ -265,7 +299,8 @@ echo -e "${YELLOW}Step 2: User mau bayar pajak${NC}"
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-combo-008",
+    "session_id": "'"$SID_COMBO_008"'",
+    "session_token": "'"$TOK_COMBO_008"'",
     "message": "bagaimana cara bayar pajak tahunan motor?",
     "name": "Rudi",
     "location": "Jakarta Barat"
@@ -288,7 +323,8 @@ echo -e "${YELLOW}User bertanya tanpa menyebutkan nama${NC}"
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-no-name-009",
+    "session_id": "'"$SID_NO_NAME_009"'",
+    "session_token": "'"$TOK_NO_NAME_009"'",
     "message": "cara perpanjang SIM gimana?",
     "location": "Bandung"
   }' | jq '.'
@@ -310,7 +346,8 @@ echo -e "${YELLOW}User bertanya tentang upgrade SIM${NC}"
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-upgrade-010",
+    "session_id": "'"$SID_UPGRADE_010"'",
+    "session_token": "'"$TOK_UPGRADE_010"'",
     "message": "saya punya SIM C, mau upgrade jadi SIM A",
     "name": "Hendra",
     "location": "Semarang",
@@ -335,7 +372,8 @@ echo -e "${YELLOW}User cek tilang dengan plat B1234SV (data dummy: ada 2 pelangg
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-etilang-011",
+    "session_id": "'"$SID_ETILANG_011"'",
+    "session_token": "'"$TOK_ETILANG_011"'",
     "message": "cek tilang B1234SV",
     "name": "Budi Santoso",
     "location": "Jakarta Pusat"
@@ -358,7 +396,8 @@ echo -e "${YELLOW}User cek tilang dengan plat B9999ZZ (data dummy: bersih)${NC}"
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-etilang-012",
+    "session_id": "'"$SID_ETILANG_012"'",
+    "session_token": "'"$TOK_ETILANG_012"'",
     "message": "tolong cek e-tilang untuk B9999ZZ",
     "name": "Ahmad Fauzi",
     "location": "Jakarta Selatan"
@@ -381,7 +420,8 @@ echo -e "${YELLOW}User cek tilang dengan plat B5678XY (data dummy: parkir ilegal
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-etilang-013",
+    "session_id": "'"$SID_ETILANG_013"'",
+    "session_token": "'"$TOK_ETILANG_013"'",
     "message": "cek tilang mobil B5678XY dong",
     "name": "Siti Rahayu",
     "location": "Jakarta Pusat"
@@ -404,7 +444,8 @@ echo -e "${YELLOW}User cek tilang dengan plat D1111AA (data dummy: sudah dibayar
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-etilang-014",
+    "session_id": "'"$SID_ETILANG_014"'",
+    "session_token": "'"$TOK_ETILANG_014"'",
     "message": "cek e-tilang D1111AA",
     "name": "Rina Kartika",
     "location": "Bandung"
@@ -427,7 +468,8 @@ echo -e "${YELLOW}User cek tilang dengan plat E7777BB (data dummy: pakai HP)${NC
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-etilang-015",
+    "session_id": "'"$SID_ETILANG_015"'",
+    "session_token": "'"$TOK_ETILANG_015"'",
     "message": "cek tilang motor E7777BB",
     "name": "Dedi Gunawan",
     "location": "Bandung"
@@ -450,7 +492,8 @@ echo -e "${YELLOW}User cek tilang dengan plat yang tidak ada di database${NC}"
 curl -X POST "$BASE_URL/chat" \
   -H "Content-Type: application/json" \
   -d '{
-    "session_id": "test-etilang-016",
+    "session_id": "'"$SID_ETILANG_016"'",
+    "session_token": "'"$TOK_ETILANG_016"'",
     "message": "cek tilang B8888XX",
     "name": "Tono",
     "location": "Jakarta"
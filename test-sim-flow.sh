@@ -29,6 +29,7 @@ RESPONSE=$(curl -s -X POST "$API_URL" \
 
 echo "$RESPONSE" | jq '.'
 SESSION_ID=$(echo "$RESPONSE" | jq -r '.session_id')
+SESSION_TOKEN=$(echo "$RESPONSE" | jq -r '.session_token')
 echo -e "\n${BLUE}Session ID: $SESSION_ID${NC}\n"
 sleep 2
 
@@ -39,6 +40,7 @@ RESPONSE=$(curl -s -X POST "$API_URL" \
   -H "Content-Type: application/json" \
   -d "{
     \"message\": \"SIM A\",
+    \"session_token\": \"$SESSION_TOKEN\",
     \"session_id\": \"$SESSION_ID\",
     \"context\": {
       \"location\": \"Jakarta\",
@@ -57,6 +59,7 @@ RESPONSE=$(curl -s -X POST "$API_URL" \
   -H "Content-Type: application/json" \
   -d "{
     \"message\": \"Ya, pernah\",
+    \"session_token\": \"$SESSION_TOKEN\",
     \"session_id\": \"$SESSION_ID\",
     \"context\": {
       \"location\": \"Jakarta\",
@@ -75,6 +78,7 @@ RESPONSE=$(curl -s -X POST "$API_URL" \
   -H "Content-Type: application/json" \
   -d "{
     \"message\": \"Masih berlaku\",
+    \"session_token\": \"$SESSION_TOKEN\",
     \"session_id\": \"$SESSION_ID\",
     \"context\": {
       \"location\": \"Jakarta\",
@@ -93,6 +97,7 @@ RESPONSE=$(curl -s -X POST "$API_URL" \
   -H "Content-Type: application/json" \
   -d "{
     \"message\": \"Ya, tolong bantu\",
+    \"session_token\": \"$SESSION_TOKEN\",
     \"session_id\": \"$SESSION_ID\",
     \"context\": {
       \"location\": \"Jakarta\",
@@ -111,6 +116,7 @@ RESPONSE=$(curl -s -X POST "$API_URL" \
   -H "Content-Type: application/json" \
   -d "{
     \"message\": \"Ini KTP saya\",
+    \"session_token\": \"$SESSION_TOKEN\",
     \"session_id\": \"$SESSION_ID\",
     \"context\": {
       \"location\": \"Jakarta\",
@@ -137,6 +143,7 @@ RESPONSE=$(curl -s -X POST "$API_URL" \
   -H "Content-Type: application/json" \
   -d "{
     \"message\": \"Ini SIM lama saya\",
+    \"session_token\": \"$SESSION_TOKEN\",
     \"session_id\": \"$SESSION_ID\",
     \"context\": {
       \"location\": \"Jakarta\",
@@ -163,6 +170,7 @@ RESPONSE=$(curl -s -X POST "$API_URL" \
   -H "Content-Type: application/json" \
   -d "{
     \"message\": \"Ini surat sehat saya\",
+    \"session_token\": \"$SESSION_TOKEN\",
     \"session_id\": \"$SESSION_ID\",
     \"context\": {
       \"location\": \"Jakarta\",
@@ -189,6 +197,7 @@ RESPONSE=$(curl -s -X POST "$API_URL" \
   -H "Content-Type: application/json" \
   -d "{
     \"message\": \"Ini hasil tes psikologi saya\",
+    \"session_token\": \"$SESSION_TOKEN\",
     \"session_id\": \"$SESSION_ID\",
     \"context\": {
       \"location\": \"Jakarta\",
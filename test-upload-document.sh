@@ -97,13 +97,14 @@ sleep 2
 # Test 4: Upload dengan session_id (lanjutan percakapan)
 echo -e "${GREEN}Test 4: Upload dokumen dengan session_id${NC}"
 echo -e "${YELLOW}Request: Upload file dalam session yang sudah ada${NC}"
-echo -e "${YELLOW}Note: Ganti <SESSION_ID> dengan session_id dari response sebelumnya${NC}"
+echo -e "${YELLOW}Note: Ganti <SESSION_ID> dan <SESSION_TOKEN> dengan session_id dan session_token dari response sebelumnya${NC}"
 echo ""
 echo 'curl -X POST "'"$API_URL"'" \'
 echo '  -H "Content-Type: application/json" \'
 echo '  -d '"'"'{
     "message": "Ini dokumen tambahannya",
     "session_id": "<SESSION_ID>",
+    "session_token": "<SESSION_TOKEN>",
     "context": {
       "location": "Jakarta Selatan"
     },
@@ -156,8 +157,8 @@ echo -e "${GREEN}✅ All tests completed!${NC}"
 echo -e "${BLUE}━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━${NC}"
 echo ""
 echo -e "${YELLOW}Tips:${NC}"
-echo "- Response akan include: success, response, session_id"
+echo "- Response akan include: success, response, session_id (dan session_token untuk session baru)"
 echo "- Jika upload dokumen, context.HasUploadedDocuments akan true"
 echo "- AI akan memberikan konfirmasi dokumen diterima dengan emoji ✅"
-echo "- Gunakan session_id untuk melanjutkan percakapan"
+echo "- Gunakan session_id + session_token untuk melanjutkan percakapan"
 echo ""